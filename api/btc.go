@@ -0,0 +1,166 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+)
+
+// EstimateBTCFee, fee rate (sat/vB) for ?conf_target=N blocks
+func (h *WalletHandler) EstimateBTCFee(c *gin.Context) {
+	target, _ := strconv.Atoi(c.Query("conf_target"))
+	if target <= 0 {
+		target = h.walletService.BtcChain.ConfTarget
+	}
+
+	feeRate := h.walletService.BtcChain.EstimateFeeRate(c.Request.Context(), target)
+	c.JSON(200, gin.H{
+		"conf_target": target,
+		"fee_rate":    feeRate, // sat/vB
+	})
+}
+
+// BumpBTCFee, speed up an unconfirmed btc tx by rbf or cpfp
+func (h *WalletHandler) BumpBTCFee(c *gin.Context) {
+	userID := c.Param("userID")
+	txid := c.Param("txid")
+
+	var req request.BumpBTCFeeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	newTxID, err := h.walletService.BumpBTCFee(c.Request.Context(), userID, txid, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"method":  req.Method,
+		"txid":    txid,
+		"tx_hash": newTxID,
+	})
+}
+
+// ListBTCUTXOs, ?chain=ltc|doge (default btc), ?include_spent=true to include spent outputs
+func (h *WalletHandler) ListBTCUTXOs(c *gin.Context) {
+	userID := c.Param("userID")
+	includeSpent, _ := strconv.ParseBool(c.Query("include_spent"))
+
+	utxos, err := h.walletService.ListBTCUTXOs(c.Request.Context(), userID, c.Query("chain"), includeSpent)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, utxos)
+}
+
+// FreezeBTCUTXO, freeze txid:vout on ?chain= (default btc) so coin selection never spends it
+func (h *WalletHandler) FreezeBTCUTXO(c *gin.Context) {
+	h.setBTCUTXOFrozen(c, true)
+}
+
+// UnfreezeBTCUTXO, make txid:vout on ?chain= (default btc) spendable again
+func (h *WalletHandler) UnfreezeBTCUTXO(c *gin.Context) {
+	h.setBTCUTXOFrozen(c, false)
+}
+
+func (h *WalletHandler) setBTCUTXOFrozen(c *gin.Context, frozen bool) {
+	userID := c.Param("userID")
+	txid := c.Param("txid")
+	vout, err := strconv.ParseUint(c.Param("vout"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid vout"})
+		return
+	}
+
+	if frozen {
+		var req request.FreezeUTXOReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		err = h.walletService.FreezeBTCUTXO(c.Request.Context(), userID, c.Query("chain"), txid, uint32(vout), req.Reason)
+	} else {
+		err = h.walletService.UnfreezeBTCUTXO(c.Request.Context(), userID, c.Query("chain"), txid, uint32(vout))
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"txid":   txid,
+		"vout":   vout,
+		"frozen": frozen,
+	})
+}
+
+// ExportBTCAccounts, output descriptors and xpub/ypub/zpub for watch-only wallets
+func (h *WalletHandler) ExportBTCAccounts(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.ExportBTCReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	accounts, err := h.walletService.ExportBTCAccounts(c.Request.Context(), userID, req.Passphrase)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"accounts": accounts})
+}
+
+// SignBTCMessage, prove control of a managed address (legacy signmessage / bip322)
+func (h *WalletHandler) SignBTCMessage(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.SignMessageReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	signature, format, err := h.walletService.SignBTCMessage(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"address":   req.Address,
+		"message":   req.Message,
+		"signature": signature,
+		"format":    format,
+	})
+}
+
+// VerifyBTCMessage, check a message signature for any address
+func (h *WalletHandler) VerifyBTCMessage(c *gin.Context) {
+	var req request.VerifyMessageReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, err := h.walletService.VerifyBTCMessage(&req)
+	if err != nil && !errors.Is(err, chain.ErrInvalidMessageSignature) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"valid":  err == nil,
+		"format": format,
+	})
+}
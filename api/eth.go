@@ -0,0 +1,397 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// endpointHeader 响应头里带上实际处理请求的 ETH RPC 节点，方便排查
+const endpointHeader = "X-ETH-Endpoint"
+
+// ETHEndpoints, health and block height of each configured eth rpc endpoint
+func (h *WalletHandler) ETHEndpoints(c *gin.Context) {
+	c.JSON(200, gin.H{"endpoints": h.walletService.ETHEndpoints()})
+}
+
+// setEndpointHeader writes the eth endpoints recorded on ctx, if any
+func setEndpointHeader(c *gin.Context, ctx context.Context) {
+	if endpoints := chain.TracedEndpoints(ctx); len(endpoints) > 0 {
+		c.Header(endpointHeader, strings.Join(endpoints, ", "))
+	}
+}
+
+// ETHNonceStatus, local nonce allocation vs node, with gaps
+func (h *WalletHandler) ETHNonceStatus(c *gin.Context) {
+	userID := c.Param("userID")
+	address, err := utils.NormalizeETHAddress(c.Param("address"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.walletService.ETHNonceStatus(c.Request.Context(), userID, address)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, status)
+}
+
+// FillETHNonceGaps, send a 0 eth self transfer at every gap nonce
+func (h *WalletHandler) FillETHNonceGaps(c *gin.Context) {
+	userID := c.Param("userID")
+	address, err := utils.NormalizeETHAddress(c.Param("address"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req request.FillNonceGapReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, hashes, err := h.walletService.FillETHNonceGaps(c.Request.Context(), userID, address, req.Passphrase)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error(), "tx_hashes": hashes})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"nonce":     status,
+		"tx_hashes": hashes,
+	})
+}
+
+// CancelETHNonceGaps, roll the next nonce back to the first gap
+func (h *WalletHandler) CancelETHNonceGaps(c *gin.Context) {
+	userID := c.Param("userID")
+	address, err := utils.NormalizeETHAddress(c.Param("address"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.walletService.CancelETHNonceGaps(c.Request.Context(), userID, address)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, status)
+}
+
+// GetTransaction, outgoing eth tx with fee params, receipt and confirmations
+func (h *WalletHandler) GetTransaction(c *gin.Context) {
+	userID := c.Param("userID")
+	hash := c.Param("hash")
+
+	tx, err := h.walletService.GetTransaction(c.Request.Context(), userID, hash)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, tx)
+}
+
+// ETHTxEvents, watchdog events (rebroadcast, fee bumps, stuck) on the nonce of an eth tx
+func (h *WalletHandler) ETHTxEvents(c *gin.Context) {
+	userID := c.Param("userID")
+	hash := c.Param("hash")
+
+	events, err := h.walletService.ETHTxEvents(c.Request.Context(), userID, hash)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"events": events})
+}
+
+// GetETHFeePolicy, auto fee bump policy of a wallet
+func (h *WalletHandler) GetETHFeePolicy(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	policy, err := h.walletService.GetETHFeePolicy(c.Request.Context(), userID, walletID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"fee_policy": policy})
+}
+
+// SetETHFeePolicy, set the fee ceiling the watchdog may escalate stuck eth txs to
+func (h *WalletHandler) SetETHFeePolicy(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	var req request.ETHFeePolicyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.walletService.SetETHFeePolicy(c.Request.Context(), userID, walletID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"fee_policy": policy})
+}
+
+// SpeedUpETHTx, re-sign a pending eth tx with the same nonce and bumped fees
+func (h *WalletHandler) SpeedUpETHTx(c *gin.Context) {
+	h.replaceETHTx(c, false)
+}
+
+// CancelETHTx, replace a pending eth tx with a 0 eth self transfer
+func (h *WalletHandler) CancelETHTx(c *gin.Context) {
+	h.replaceETHTx(c, true)
+}
+
+func (h *WalletHandler) replaceETHTx(c *gin.Context, cancel bool) {
+	userID := c.Param("userID")
+	hash := c.Param("hash")
+
+	var req request.ReplaceETHTxReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	replace := h.walletService.SpeedUpETHTx
+	if cancel {
+		replace = h.walletService.CancelETHTx
+	}
+	tx, err := replace(c.Request.Context(), userID, hash, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, tx)
+}
+
+// ListETHTokens, erc-20 tokens registered for the current network
+func (h *WalletHandler) ListETHTokens(c *gin.Context) {
+	tokens, err := h.walletService.ListETHTokens(c.Request.Context())
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"tokens": tokens})
+}
+
+// AddETHToken, register an erc-20 token by contract address, reading decimals / symbol / name from chain
+func (h *WalletHandler) AddETHToken(c *gin.Context) {
+	var req request.AddTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.walletService.AddETHToken(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, token)
+}
+
+// ListNFTs, erc-721 / erc-1155 tokens held by the user's eth addresses in the configured collections
+func (h *WalletHandler) ListNFTs(c *gin.Context) {
+	userID := c.Param("userID")
+
+	nfts, err := h.walletService.ListNFTs(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"nfts": nfts})
+}
+
+// GetNFTMetadata, tokenURI / uri of a token and its metadata
+func (h *WalletHandler) GetNFTMetadata(c *gin.Context) {
+	metadata, err := h.walletService.GetNFTMetadata(c.Request.Context(), c.Param("collection"), c.Param("tokenID"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, metadata)
+}
+
+// SendNFT, transfer an erc-721 / erc-1155 token with safeTransferFrom
+func (h *WalletHandler) SendNFT(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.SendNFTReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := chain.WithEndpointTrace(c.Request.Context())
+	txHash, err := h.walletService.SendNFT(ctx, userID, &req)
+	setEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"tx_hash": txHash})
+}
+
+// CallContract, read-only eth_call of a contract method with decoded outputs
+func (h *WalletHandler) CallContract(c *gin.Context) {
+	var req request.ContractCallReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := chain.WithEndpointTrace(c.Request.Context())
+	outputs, err := h.walletService.CallContract(ctx, &req)
+	setEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"outputs": outputs})
+}
+
+// WriteContract, sign and send a state-changing contract call from a managed address
+func (h *WalletHandler) WriteContract(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.ContractWriteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := chain.WithEndpointTrace(c.Request.Context())
+	txHash, err := h.walletService.WriteContract(ctx, userID, &req)
+	setEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"tx_hash": txHash})
+}
+
+// DeployContract, deploy bytecode with encoded constructor args, returns the created contract address
+func (h *WalletHandler) DeployContract(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.ContractDeployReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := chain.WithEndpointTrace(c.Request.Context())
+	txHash, contract, err := h.walletService.DeployContract(ctx, userID, &req)
+	setEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"tx_hash":          txHash,
+		"contract_address": contract,
+	})
+}
+
+// SignETHMessage, personal_sign with a managed address, returns the signature and the decoded message
+func (h *WalletHandler) SignETHMessage(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.SignETHMessageReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.walletService.SignETHMessage(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+// SignETHTypedData, eth_signTypedData_v4 (EIP-712) with a managed address, returns the signature and the decoded domain / message
+func (h *WalletHandler) SignETHTypedData(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.SignTypedDataReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.walletService.SignETHTypedData(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+// VerifyETHMessage, recover the signer of a personal_sign or EIP-712 signature
+func (h *WalletHandler) VerifyETHMessage(c *gin.Context) {
+	var req request.VerifyETHMessageReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.walletService.VerifyETHMessage(&req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+// BroadcastETHTx, validate, record and broadcast a raw signed transaction (from broadcast=false or signed elsewhere)
+func (h *WalletHandler) BroadcastETHTx(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.BroadcastETHTxReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := chain.WithEndpointTrace(c.Request.Context())
+	tx, err := h.walletService.BroadcastETHTx(ctx, userID, req.RawTx)
+	setEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, tx)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+)
+
+// CreateMultisigWallet, m-of-n p2wsh wallet from our hd account + cosigner xpubs
+func (h *WalletHandler) CreateMultisigWallet(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.CreateMultisigReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	wallet, addr, err := h.walletService.CreateMultisigWallet(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"wallet_id": wallet.ID,
+		"multisig":  wallet.Multisig,
+		"address":   addr.Address,
+	})
+}
+
+// GetMultisigWallet, policy and wsh(sortedmulti) descriptors
+func (h *WalletHandler) GetMultisigWallet(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	wallet, descriptors, err := h.walletService.MultisigDescriptors(c.Request.Context(), userID, walletID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"wallet_id":   wallet.ID,
+		"wallet_name": wallet.WalletName,
+		"multisig":    wallet.Multisig,
+		"descriptors": descriptors,
+	})
+}
+
+// DeriveMultisigAddress, next receive address of a multisig wallet
+func (h *WalletHandler) DeriveMultisigAddress(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	addr, err := h.walletService.DeriveMultisigAddress(c.Request.Context(), userID, walletID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, addr)
+}
+
+// CreateMultisigSpend, build a psbt signed by our cosigner key
+func (h *WalletHandler) CreateMultisigSpend(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	var req request.MultisigSpendReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := h.walletService.CreateMultisigSpend(c.Request.Context(), userID, walletID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, record)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/offline"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+)
+
+// ExportOfflineWallet, encrypted wallet export for the offline signer
+func (h *WalletHandler) ExportOfflineWallet(c *gin.Context) {
+	var req request.ExportOfflineWalletReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, err := h.walletService.ExportOfflineWallet(c.Request.Context(), c.Param("userID"), c.Param("walletID"), req.Passphrase)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, export)
+}
+
+// CreateOfflineETHRequest, unsigned eth tx as a json / ur sign request
+func (h *WalletHandler) CreateOfflineETHRequest(c *gin.Context) {
+	var req request.OfflineETHReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := chain.WithEndpointTrace(c.Request.Context())
+	payload, err := h.walletService.CreateOfflineETHRequest(ctx, c.Param("userID"), &req)
+	setEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	writeOfflinePayload(c, payload, req.Fragment)
+}
+
+// CreateOfflineBTCRequest, unsigned psbt as a json / ur sign request
+func (h *WalletHandler) CreateOfflineBTCRequest(c *gin.Context) {
+	var req request.OfflineBTCReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	payload, err := h.walletService.CreateOfflineBTCRequest(c.Request.Context(), c.Param("userID"), &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	writeOfflinePayload(c, payload, req.Fragment)
+}
+
+// ImportOfflinePayload, signed payload from the offline signer, broadcast when fully signed
+func (h *WalletHandler) ImportOfflinePayload(c *gin.Context) {
+	var req request.ImportOfflineReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	text := req.Payload
+	if len(req.UR) > 0 {
+		text = strings.Join(req.UR, " ")
+	}
+	if strings.TrimSpace(text) == "" || (req.Payload != "" && len(req.UR) > 0) {
+		c.JSON(400, gin.H{"error": errors.New("one of payload or ur is required").Error()})
+		return
+	}
+
+	ctx := chain.WithEndpointTrace(c.Request.Context())
+	result, err := h.walletService.ImportOfflinePayload(ctx, c.Param("userID"), text)
+	setEndpointHeader(c, ctx)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, result)
+}
+
+// writeOfflinePayload 返回 JSON payload 和 UR 分段，每段 UR 生成一个 QR 码
+func writeOfflinePayload(c *gin.Context, payload *offline.Payload, fragment int) {
+	parts, err := payload.UR(fragment)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"payload": payload, "ur": parts})
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+)
+
+// CreateBTCPSBT, unsigned psbt from the hd wallet for offline / third-party signing
+func (h *WalletHandler) CreateBTCPSBT(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.CreatePSBTReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := h.walletService.CreateBTCPSBT(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "file" {
+		h.exportBTCPSBT(c, userID, record.ID)
+		return
+	}
+	c.JSON(200, gin.H{"psbt": record})
+}
+
+// ExportBTCPSBT, base64 by default, ?format=file downloads a binary .psbt
+func (h *WalletHandler) ExportBTCPSBT(c *gin.Context) {
+	h.exportBTCPSBT(c, c.Param("userID"), c.Param("id"))
+}
+
+func (h *WalletHandler) exportBTCPSBT(c *gin.Context, userID, id string) {
+	record, raw, err := h.walletService.ExportBTCPSBT(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "file" {
+		c.Header("Content-Disposition", "attachment; filename="+record.ID+".psbt")
+		c.Data(200, "application/octet-stream", raw)
+		return
+	}
+	c.JSON(200, gin.H{
+		"id":   record.ID,
+		"psbt": record.PSBT,
+	})
+}
+
+// GetBTCPSBT, psbt and its signature progress
+func (h *WalletHandler) GetBTCPSBT(c *gin.Context) {
+	userID := c.Param("userID")
+	id := c.Param("id")
+
+	record, status, err := h.walletService.GetBTCPSBT(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"psbt":   record,
+		"status": status,
+	})
+}
+
+// ImportBTCPSBT, merge signatures from another signer after checking the tx is unchanged
+func (h *WalletHandler) ImportBTCPSBT(c *gin.Context) {
+	userID := c.Param("userID")
+	id := c.Param("id")
+
+	data, err := readPSBTUpload(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, status, err := h.walletService.ImportBTCPSBT(c.Request.Context(), userID, id, data)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"psbt":   record,
+		"status": status,
+	})
+}
+
+// FinalizeBTCPSBT, finalize and broadcast a fully signed psbt
+func (h *WalletHandler) FinalizeBTCPSBT(c *gin.Context) {
+	userID := c.Param("userID")
+	id := c.Param("id")
+
+	record, err := h.walletService.FinalizeBTCPSBT(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"psbt": record,
+		"txid": record.TxID,
+		"sent": record.Status == entity.BTCPSBTBroadcast,
+	})
+}
+
+// CancelBTCPSBT, abandon a psbt that is still collecting signatures and unfreeze its inputs
+func (h *WalletHandler) CancelBTCPSBT(c *gin.Context) {
+	record, err := h.walletService.CancelBTCPSBT(c.Request.Context(), c.Param("userID"), c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"psbt": record})
+}
+
+// readPSBTUpload multipart 上传的 .psbt 文件或 JSON 里的 base64
+func readPSBTUpload(c *gin.Context) ([]byte, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+
+	var req request.ImportPSBTReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, err
+	}
+	return []byte(req.PSBT), nil
+}
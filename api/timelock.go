@@ -0,0 +1,103 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+)
+
+// CreateTimelockWallet, our hd key spends any time, recovery key after `older` blocks
+func (h *WalletHandler) CreateTimelockWallet(c *gin.Context) {
+	userID := c.Param("userID")
+
+	var req request.CreateTimelockReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	wallet, addr, err := h.walletService.CreateTimelockWallet(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"wallet_id": wallet.ID,
+		"timelock":  wallet.Timelock,
+		"address":   addr.Address,
+	})
+}
+
+// GetTimelockWallet, policy, miniscript and descriptors
+func (h *WalletHandler) GetTimelockWallet(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	wallet, miniscript, descriptors, err := h.walletService.TimelockDescriptors(c.Request.Context(), userID, walletID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"wallet_id":   wallet.ID,
+		"wallet_name": wallet.WalletName,
+		"timelock":    wallet.Timelock,
+		"miniscript":  miniscript,
+		"descriptors": descriptors,
+	})
+}
+
+// DeriveTimelockAddress, next receive address of a timelock wallet
+func (h *WalletHandler) DeriveTimelockAddress(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	addr, err := h.walletService.DeriveTimelockAddress(c.Request.Context(), userID, walletID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, addr)
+}
+
+// CreateTimelockSpend, spend through the primary key and broadcast
+func (h *WalletHandler) CreateTimelockSpend(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	var req request.TimelockSpendReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := h.walletService.CreateTimelockSpend(c.Request.Context(), userID, walletID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, record)
+}
+
+// CreateTimelockRecovery, sweep matured outputs through the recovery key
+func (h *WalletHandler) CreateTimelockRecovery(c *gin.Context) {
+	userID := c.Param("userID")
+	walletID := c.Param("walletID")
+
+	var req request.TimelockRecoveryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := h.walletService.CreateTimelockRecovery(c.Request.Context(), userID, walletID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, record)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
 	"github.com/linlinbupt123-crypto/wallet_service/request"
 	"github.com/linlinbupt123-crypto/wallet_service/service"
 )
@@ -28,7 +29,7 @@ func (h *WalletHandler) CreateWallet(c *gin.Context) {
 	req.UserID = userID
 
 	wallet, addrs, err := h.walletService.CreateWalletAndAddresses(
-		c.Request.Context(), req.UserID, req.Passphrase,
+		c.Request.Context(), req.UserID, req.Passphrase, req.AddressType,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -69,6 +70,7 @@ func (h *WalletHandler) DeriveAddress(c *gin.Context) {
 		req.UserID,
 		req.Passphrase,
 		req.ChainName,
+		req.AddressType,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -78,6 +80,7 @@ func (h *WalletHandler) DeriveAddress(c *gin.Context) {
 	c.JSON(http.StatusOK, addr)
 }
 
+// SendTransaction, broadcast=false (eth) signs only and reserves the next managed nonce unless an explicit nonce is given
 func (h *WalletHandler) SendTransaction(c *gin.Context) {
 	var req request.SendTxReq
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -85,20 +88,31 @@ func (h *WalletHandler) SendTransaction(c *gin.Context) {
 		return
 	}
 
-	txHash, err := h.walletService.SendTransaction(
-		c.Request.Context(),
+	ctx := chain.WithEndpointTrace(c.Request.Context())
+	txHash, rawTx, err := h.walletService.SendTransaction(
+		ctx,
 		&req,
 	)
+	setEndpointHeader(c, ctx)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
+	if rawTx != "" {
+		c.JSON(200, gin.H{
+			"tx_hash":   txHash,
+			"raw_tx":    rawTx,
+			"broadcast": false,
+		})
+		return
+	}
 	c.JSON(200, gin.H{
 		"tx_hash": txHash,
 	})
 }
 
+// GetBalance, utxo chains are summed from the synced utxos, ?refresh=true syncs the user's addresses first
 func (h *WalletHandler) GetBalance(c *gin.Context) {
 	userID := c.Param("userID")
 
@@ -108,11 +122,15 @@ func (h *WalletHandler) GetBalance(c *gin.Context) {
 		return
 	}
 
+	ctx := chain.WithEndpointTrace(c.Request.Context())
 	balance, err := h.walletService.GetBalance(
-		c.Request.Context(),
+		ctx,
 		userID,
 		req.Chain,
+		req.Asset,
+		c.Query("refresh") == "true",
 	)
+	setEndpointHeader(c, ctx)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
@@ -120,7 +138,8 @@ func (h *WalletHandler) GetBalance(c *gin.Context) {
 
 	c.JSON(200, gin.H{
 		"chain":   req.Chain,
-		"balance": balance, // Wei
+		"asset":   req.Asset,
+		"balance": balance,
 	})
 }
 
@@ -0,0 +1,278 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	wrapErrors "github.com/linlinbupt123-crypto/wallet_service/errors"
+)
+
+// BitcoindBackend 通过 bitcoind JSON-RPC 查询链上数据
+// 不依赖节点钱包：UTXO 用 scantxoutset 扫描，只能看到已确认的输出，
+// regtest 下可以直接用 bitcoind -regtest 测试
+type BitcoindBackend struct {
+	url    string
+	user   string
+	pass   string
+	client *http.Client
+
+	// scantxoutset 在一个节点上同时只能跑一个，后台同步和查余额的扫描在这里排队
+	scanMu sync.Mutex
+}
+
+func NewBitcoindBackend(url, user, pass string) *BitcoindBackend {
+	return &BitcoindBackend{
+		url:    url,
+		user:   user,
+		pass:   pass,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("bitcoind rpc error %d: %s", e.Code, e.Message)
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// call 发起一次 JSON-RPC 调用，out 为 nil 时忽略结果
+func (b *BitcoindBackend) call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(rpcRequest{JSONRPC: "1.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.user != "" {
+		req.SetBasicAuth(b.user, b.pass)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, method, err)
+	}
+	defer resp.Body.Close()
+
+	// bitcoind 出错时 HTTP 状态码是 500，但 body 里仍然是 JSON-RPC 错误
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, method, fmt.Errorf("http %d: %w", resp.StatusCode, err))
+	}
+	if rpcResp.Error != nil {
+		return wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, method, rpcResp.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, out)
+}
+
+func (b *BitcoindBackend) BlockHeight(ctx context.Context) (int64, error) {
+	var height int64
+	if err := b.call(ctx, "getblockcount", nil, &height); err != nil {
+		return 0, err
+	}
+	return height, nil
+}
+
+type scanTxOutSetResult struct {
+	Success  bool `json:"success"`
+	Unspents []struct {
+		TxID   string  `json:"txid"`
+		Vout   uint32  `json:"vout"`
+		Desc   string  `json:"desc"`
+		Amount float64 `json:"amount"`
+		Height int64   `json:"height"`
+	} `json:"unspents"`
+}
+
+func (b *BitcoindBackend) ListUnspent(ctx context.Context, addresses []string) ([]BTCUnspent, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+	descs := make([]interface{}, 0, len(addresses))
+	for _, a := range addresses {
+		descs = append(descs, map[string]string{"desc": "addr(" + a + ")"})
+	}
+
+	b.scanMu.Lock()
+	defer b.scanMu.Unlock()
+	var res scanTxOutSetResult
+	if err := b.call(ctx, "scantxoutset", []interface{}{"start", descs}, &res); err != nil {
+		return nil, err
+	}
+	if !res.Success {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, "scantxoutset", fmt.Errorf("scan aborted"))
+	}
+
+	out := make([]BTCUnspent, 0, len(res.Unspents))
+	for _, u := range res.Unspents {
+		amount, err := btcutil.NewAmount(u.Amount)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, BTCUnspent{
+			TxID:    u.TxID,
+			Vout:    u.Vout,
+			Address: addressFromDescriptor(u.Desc),
+			Value:   int64(amount),
+			Height:  u.Height,
+		})
+	}
+	return out, nil
+}
+
+func (b *BitcoindBackend) IsSpent(ctx context.Context, txid string, vout uint32) (bool, error) {
+	// gettxout 对已花费（含 mempool）的输出返回 null
+	var res json.RawMessage
+	if err := b.call(ctx, "gettxout", []interface{}{txid, vout, true}, &res); err != nil {
+		return false, err
+	}
+	return len(res) == 0 || string(res) == "null", nil
+}
+
+// rpcMethodNotFound 节点版本过低，不支持该 RPC
+const rpcMethodNotFound = -32601
+
+func (b *BitcoindBackend) SpendingTxID(ctx context.Context, txid string, vout uint32) (string, error) {
+	// gettxspendingprevout 只查 mempool，已上链的花费查不到，按无法确定处理
+	var res []struct {
+		SpendingTxID string `json:"spendingtxid"`
+	}
+	prevouts := []interface{}{map[string]interface{}{"txid": txid, "vout": vout}}
+	if err := b.call(ctx, "gettxspendingprevout", []interface{}{prevouts}, &res); err != nil {
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) && rpcErr.Code == rpcMethodNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	if len(res) == 0 {
+		return "", nil
+	}
+	return res[0].SpendingTxID, nil
+}
+
+// rpcTxNotFound getrawtransaction 找不到交易的错误码 (RPC_INVALID_ADDRESS_OR_KEY)
+const rpcTxNotFound = -5
+
+func (b *BitcoindBackend) TxHeight(ctx context.Context, txid string, outputs int) (int64, bool, error) {
+	var res struct {
+		Confirmations int64 `json:"confirmations"`
+	}
+	err := b.call(ctx, "getrawtransaction", []interface{}{txid, true}, &res)
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != rpcTxNotFound {
+			return 0, false, err
+		}
+		// 没有 -txindex 时已确认交易查不到，从它还没花掉的输出上取确认数
+		res.Confirmations = -1
+		for i := 0; i < outputs && res.Confirmations < 0; i++ {
+			var out json.RawMessage
+			if err := b.call(ctx, "gettxout", []interface{}{txid, i, true}, &out); err != nil {
+				return 0, false, err
+			}
+			if len(out) > 0 && string(out) != "null" {
+				if err := json.Unmarshal(out, &res); err != nil {
+					return 0, false, err
+				}
+			}
+		}
+		if res.Confirmations < 0 {
+			return 0, false, nil
+		}
+	}
+	if res.Confirmations == 0 {
+		return 0, true, nil
+	}
+	tip, err := b.BlockHeight(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	return tip - res.Confirmations + 1, true, nil
+}
+
+func (b *BitcoindBackend) GetRawTransaction(ctx context.Context, txid string, height int64) ([]byte, error) {
+	params := []interface{}{txid, false}
+	// 没有 -txindex 时，已确认交易必须指定所在区块
+	if height > 0 {
+		var blockHash string
+		if err := b.call(ctx, "getblockhash", []interface{}{height}, &blockHash); err != nil {
+			return nil, err
+		}
+		params = append(params, blockHash)
+	}
+	var rawHex string
+	if err := b.call(ctx, "getrawtransaction", params, &rawHex); err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(rawHex)
+}
+
+func (b *BitcoindBackend) Broadcast(ctx context.Context, rawTx []byte) (string, error) {
+	var txid string
+	if err := b.call(ctx, "sendrawtransaction", []interface{}{hex.EncodeToString(rawTx)}, &txid); err != nil {
+		return "", err
+	}
+	return txid, nil
+}
+
+type estimateSmartFeeResult struct {
+	FeeRate float64  `json:"feerate"` // BTC/kvB
+	Errors  []string `json:"errors"`
+	Blocks  int      `json:"blocks"`
+}
+
+func (b *BitcoindBackend) EstimateFeeRate(ctx context.Context, targetBlocks int) (float64, error) {
+	var res estimateSmartFeeResult
+	if err := b.call(ctx, "estimatesmartfee", []interface{}{targetBlocks}, &res); err != nil {
+		return 0, err
+	}
+	// 节点数据不足 (例如 regtest 刚启动) 时没有 feerate 字段
+	if res.FeeRate <= 0 {
+		return 0, nil
+	}
+	// BTC/kvB -> sat/vB
+	return res.FeeRate * 1e8 / 1000, nil
+}
+
+// addressFromDescriptor "addr(bc1q...)#checksum" -> "bc1q..."
+func addressFromDescriptor(desc string) string {
+	if i := strings.IndexByte(desc, '#'); i >= 0 {
+		desc = desc[:i]
+	}
+	desc = strings.TrimPrefix(desc, "addr(")
+	return strings.TrimSuffix(desc, ")")
+}
@@ -1,48 +1,382 @@
 package chain
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/linlinbupt123-crypto/wallet_service/config"
+)
+
+// BTCAddressType 比特币地址（脚本）类型，每种类型对应一个 BIP purpose
+type BTCAddressType string
+
+const (
+	P2PKH      BTCAddressType = "p2pkh"       // BIP44 legacy, 1...
+	P2SHP2WPKH BTCAddressType = "p2sh-p2wpkh" // BIP49 nested segwit, 3...
+	P2WPKH     BTCAddressType = "p2wpkh"      // BIP84 native segwit, bc1q...
+	P2TR       BTCAddressType = "p2tr"        // BIP86 taproot, bc1p...
+
+	P2WSH BTCAddressType = "p2wsh" // BIP48 sorted multisig，只用于 multisig 钱包
 )
 
+// ParseBTCAddressType 空字符串返回 def，未知类型返回错误
+func ParseBTCAddressType(s string, def BTCAddressType) (BTCAddressType, error) {
+	if s == "" {
+		return def, nil
+	}
+	t := BTCAddressType(s)
+	switch t {
+	case P2PKH, P2SHP2WPKH, P2WPKH, P2TR:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unsupported btc address type: %s", s)
+	}
+}
+
+// Purpose 返回该地址类型对应的 BIP purpose
+func (t BTCAddressType) Purpose() int {
+	switch t {
+	case P2SHP2WPKH:
+		return 49
+	case P2WPKH:
+		return 84
+	case P2TR:
+		return 86
+	case P2WSH:
+		return 48
+	default:
+		return 44
+	}
+}
+
+// addressTypeForPurpose 由 path 中的 purpose 反推地址类型
+func addressTypeForPurpose(purpose uint32) (BTCAddressType, error) {
+	switch purpose {
+	case 44:
+		return P2PKH, nil
+	case 49:
+		return P2SHP2WPKH, nil
+	case 84:
+		return P2WPKH, nil
+	case 86:
+		return P2TR, nil
+	default:
+		return "", fmt.Errorf("unsupported purpose: %d", purpose)
+	}
+}
+
+// BTCChain 比特币及复用同一套 UTXO 流程的链 (LTC / DOGE)，Name 区分具体的链
 type BTCChain struct {
-	MainNet bool
+	Name        ChainType
+	MainNet     bool
+	Network     string         // testnet / regtest / signet，只对 btc 生效
+	AddressType BTCAddressType // 新钱包的默认地址类型
+	FeeRate     int64          // 默认费率 sat/vB
+	Backend     BTCBackend     // 未配置时为 nil
+
+	CoinSelection string // 默认选币策略
+	DustLimit     int64  // 找零 dust 阈值 (satoshi)
+	ConfTarget    int    // 估算费率时的默认确认目标 (区块数)
+
+	FreezeDustBelow int64 // 收到的输出低于该值时自动冻结，0 表示不冻结
+
+	coin   utxoCoin
+	params *chaincfg.Params
 }
 
-func NewBTCChain(mainnet bool) *BTCChain {
-	return &BTCChain{MainNet: mainnet}
+// defaultBTCFeeRate 配置里没有 fee_rate 时使用 (sat/vB)
+const defaultBTCFeeRate int64 = 5
+
+// defaultBTCConfTarget 配置里没有 conf_target 时使用
+const defaultBTCConfTarget = 6
+
+// NewUTXOChain 按链名创建 BTC / LTC / DOGE，未配置的默认值取各链自己的
+func NewUTXOChain(name ChainType, cfg config.BtcConfig) (*BTCChain, error) {
+	coin, ok := utxoCoins[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported utxo chain: %s", name)
+	}
+	b := &BTCChain{
+		Name:    name,
+		MainNet: cfg.MainNet,
+		Network: cfg.Network,
+		coin:    coin,
+	}
+	b.params = b.resolveParams()
+
+	addrType, err := b.ParseAddressType(cfg.AddressType, coin.defaultAddressType)
+	// 地址类型配错了同样启动时报错，不要悄悄换成默认类型派生出另一套地址
+	if err != nil {
+		return nil, fmt.Errorf("%s address_type: %w", name, err)
+	}
+	// 选币策略配错了启动时就报错，不要等到发送时才发现
+	if _, err := NewCoinSelector(cfg.CoinSelection); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if coin.esploraOnly && (cfg.Backend == "bitcoind" || cfg.Backend == "" && cfg.RPC != "") {
+		return nil, fmt.Errorf("%s: the bitcoind backend is not supported, use esplora", name)
+	}
+	// 后端未配置时只能派生地址，查询/发送会返回 ErrBTCBackendNotConfigured
+	backend, err := newBTCBackend(cfg)
+	if err != nil {
+		log.Printf("%s backend disabled: %v", name, err)
+	}
+	feeRate := cfg.FeeRate
+	if feeRate <= 0 {
+		feeRate = coin.feeRate
+	}
+	dustLimit := cfg.DustLimit
+	if dustLimit <= 0 {
+		dustLimit = coin.dustLimit
+	}
+	confTarget := cfg.ConfTarget
+	if confTarget <= 0 {
+		confTarget = defaultBTCConfTarget
+	}
+
+	b.AddressType = addrType
+	b.FeeRate = feeRate
+	b.Backend = backend
+	b.CoinSelection = cfg.CoinSelection
+	b.DustLimit = dustLimit
+	b.ConfTarget = confTarget
+	b.FreezeDustBelow = cfg.FreezeDustBelow
+	return b, nil
 }
 
-func (b *BTCChain) netParams() *chaincfg.Params {
+func (b *BTCChain) resolveParams() *chaincfg.Params {
 	if b.MainNet {
-		return &chaincfg.MainNetParams
+		return b.coin.mainNet
+	}
+	if b.Name != BTC {
+		return b.coin.testNet
+	}
+	switch b.Network {
+	case "regtest":
+		return &chaincfg.RegressionNetParams
+	case "signet":
+		return &chaincfg.SigNetParams
+	default:
+		return &chaincfg.TestNet3Params
 	}
-	return &chaincfg.TestNet3Params
 }
 
-func (b *BTCChain) DeriveAddress(seed []byte, path string) (string, error) {
-	master, err := hdkeychain.NewMaster(seed, b.netParams())
+func (b *BTCChain) netParams() *chaincfg.Params {
+	return b.params
+}
+
+// ParseAddressType 解析并检查该链是否支持这种单签地址类型，空字符串返回 def
+func (b *BTCChain) ParseAddressType(s string, def BTCAddressType) (BTCAddressType, error) {
+	t, err := ParseBTCAddressType(s, def)
 	if err != nil {
 		return "", err
 	}
+	for _, supported := range b.coin.addressTypes {
+		if t == supported {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("%s does not support address type %s", b.Name, t)
+}
 
-	indices, err := parseDerivationPath(path)
+// backend 返回已配置的后端，未配置时返回 ErrBTCBackendNotConfigured
+func (b *BTCChain) backend() (BTCBackend, error) {
+	if b.Backend == nil {
+		return nil, ErrBTCBackendNotConfigured
+	}
+	return b.Backend, nil
+}
+
+// BlockHeight 当前链高度
+func (b *BTCChain) BlockHeight(ctx context.Context) (int64, error) {
+	backend, err := b.backend()
+	if err != nil {
+		return 0, err
+	}
+	return backend.BlockHeight(ctx)
+}
+
+// ListUnspent 查询一组地址的未花费输出
+func (b *BTCChain) ListUnspent(ctx context.Context, addresses []string) ([]BTCUnspent, error) {
+	backend, err := b.backend()
+	if err != nil {
+		return nil, err
+	}
+	return backend.ListUnspent(ctx, addresses)
+}
+
+// IsSpent 判断某个输出是否已被花费
+func (b *BTCChain) IsSpent(ctx context.Context, txid string, vout uint32) (bool, error) {
+	backend, err := b.backend()
+	if err != nil {
+		return false, err
+	}
+	return backend.IsSpent(ctx, txid, vout)
+}
+
+// SpendingTxID 花掉某个输出的交易，未花费或无法确定时返回空串
+func (b *BTCChain) SpendingTxID(ctx context.Context, txid string, vout uint32) (string, error) {
+	backend, err := b.backend()
 	if err != nil {
 		return "", err
 	}
+	return backend.SpendingTxID(ctx, txid, vout)
+}
 
-	key := master
-	for _, idx := range indices {
-		key, err = key.Derive(idx)
+// TxHeight 交易所在区块高度，0 表示在 mempool，found 为 false 表示节点上找不到
+func (b *BTCChain) TxHeight(ctx context.Context, txid string, outputs int) (int64, bool, error) {
+	backend, err := b.backend()
+	if err != nil {
+		return 0, false, err
+	}
+	return backend.TxHeight(ctx, txid, outputs)
+}
+
+// EstimateFeeRate 估算 targetBlocks 内确认的费率 (sat/vB，向上取整)；
+// targetBlocks <= 0 时使用配置的 conf_target。后端不可用或没有估算数据时
+// 退回配置的 fee_rate，结果不低于该链的最低转发费率
+func (b *BTCChain) EstimateFeeRate(ctx context.Context, targetBlocks int) int64 {
+	if targetBlocks <= 0 {
+		targetBlocks = b.ConfTarget
+	}
+	rate := b.FeeRate
+	if backend, err := b.backend(); err == nil {
+		est, err := backend.EstimateFeeRate(ctx, targetBlocks)
 		if err != nil {
-			return "", err
+			log.Printf("%s fee estimate (target %d) failed, fallback to %d sat/vB: %v", b.Name, targetBlocks, rate, err)
+		} else if est > 0 {
+			rate = int64(math.Ceil(est))
 		}
 	}
+	if rate < b.coin.minFeeRate {
+		rate = b.coin.minFeeRate
+	}
+	return rate
+}
+
+// PkScript 地址对应的 scriptPubKey，其他链 / 网络的地址返回错误
+func (b *BTCChain) PkScript(address string) ([]byte, error) {
+	addr, err := btcutil.DecodeAddress(address, b.netParams())
+	if err != nil {
+		return nil, err
+	}
+	if !addr.IsForNet(b.netParams()) {
+		return nil, fmt.Errorf("address %s is not for %s", address, b.netParams().Name)
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+// CoinType BIP44 coin type: btc 0 / ltc 2 / doge 3，所有测试网 1
+func (b *BTCChain) CoinType() int {
+	return int(b.netParams().HDCoinType)
+}
+
+// DeriveAddress 按 path 中的 purpose 决定地址类型 (44/49/84/86)
+func (b *BTCChain) DeriveAddress(seed []byte, path string) (string, error) {
+	indices, err := parseDerivationPath(path)
+	if err != nil {
+		return "", err
+	}
+	addrType, err := addressTypeForPurpose(indices[0] - hdkeychain.HardenedKeyStart)
+	if err != nil {
+		return "", err
+	}
+	return b.DeriveTypedAddress(seed, path, addrType)
+}
 
-	addr, err := key.Address(b.netParams())
+// DeriveTypedAddress 用指定的地址类型派生地址
+func (b *BTCChain) DeriveTypedAddress(seed []byte, path string, addrType BTCAddressType) (string, error) {
+	key, err := b.deriveExtendedKey(seed, path)
+	if err != nil {
+		return "", err
+	}
+	pub, err := key.ECPubKey()
+	if err != nil {
+		return "", err
+	}
+	addr, err := b.AddressFromPubKey(pub, addrType)
 	if err != nil {
 		return "", err
 	}
+	return addr.EncodeAddress(), nil
+}
 
+// PubKeyAddress 压缩公钥对应的地址
+func (b *BTCChain) PubKeyAddress(pubKey []byte, addrType BTCAddressType) (string, error) {
+	pub, err := btcec.ParsePubKey(pubKey)
+	if err != nil {
+		return "", err
+	}
+	addr, err := b.AddressFromPubKey(pub, addrType)
+	if err != nil {
+		return "", err
+	}
 	return addr.EncodeAddress(), nil
 }
+
+// DeriveKey 派生签名用的私钥，调用方用完后应尽快 Zero()
+func (b *BTCChain) DeriveKey(seed []byte, path string) (*btcec.PrivateKey, error) {
+	key, err := b.deriveExtendedKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPrivKey()
+}
+
+func (b *BTCChain) deriveExtendedKey(seed []byte, path string) (*hdkeychain.ExtendedKey, error) {
+	master, err := hdkeychain.NewMaster(seed, b.netParams())
+	if err != nil {
+		return nil, err
+	}
+
+	indices, err := parseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := master
+	for _, idx := range indices {
+		key, err = key.Derive(idx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// AddressFromPubKey 按地址类型把公钥编码成地址
+func (b *BTCChain) AddressFromPubKey(pub *btcec.PublicKey, addrType BTCAddressType) (btcutil.Address, error) {
+	params := b.netParams()
+	switch addrType {
+	case P2PKH:
+		return btcutil.NewAddressPubKeyHash(btcutil.Hash160(pub.SerializeCompressed()), params)
+	case P2WPKH:
+		return btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pub.SerializeCompressed()), params)
+	case P2SHP2WPKH:
+		// redeem script 是 v0 witness program: OP_0 <20-byte-hash>
+		witnessAddr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pub.SerializeCompressed()), params)
+		if err != nil {
+			return nil, err
+		}
+		redeemScript, err := txscript.PayToAddrScript(witnessAddr)
+		if err != nil {
+			return nil, err
+		}
+		return btcutil.NewAddressScriptHash(redeemScript, params)
+	case P2TR:
+		// BIP86: 没有 script path，只做 key path tweak
+		outputKey := txscript.ComputeTaprootKeyNoScript(pub)
+		return btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), params)
+	default:
+		return nil, errors.New("unsupported btc address type")
+	}
+}
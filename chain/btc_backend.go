@@ -0,0 +1,59 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/linlinbupt123-crypto/wallet_service/config"
+)
+
+// ErrBTCBackendNotConfigured 没有配置 bitcoind / esplora 时返回
+var ErrBTCBackendNotConfigured = errors.New("btc backend not configured")
+
+// BTCUnspent 后端返回的未花费输出
+type BTCUnspent struct {
+	TxID    string
+	Vout    uint32
+	Address string
+	Value   int64 // satoshi
+	Height  int64 // 0 表示未确认
+}
+
+// BTCBackend 比特币节点 / 索引服务的统一接口
+type BTCBackend interface {
+	// BlockHeight 当前链高度
+	BlockHeight(ctx context.Context) (int64, error)
+	// ListUnspent 查询一组地址的未花费输出
+	ListUnspent(ctx context.Context, addresses []string) ([]BTCUnspent, error)
+	// IsSpent 判断某个输出是否已被花费（包括 mempool 中的花费）
+	IsSpent(ctx context.Context, txid string, vout uint32) (bool, error)
+	// SpendingTxID 花掉某个输出的交易 txid，未花费或后端无法确定时返回空串
+	SpendingTxID(ctx context.Context, txid string, vout uint32) (string, error)
+	// TxHeight 交易所在区块高度，0 表示还在 mempool；found 为 false 表示节点上找不到 (被替换或丢弃)。
+	// outputs 为交易的输出个数，没有交易索引的 bitcoind 从未花费的输出上查确认数
+	TxHeight(ctx context.Context, txid string, outputs int) (height int64, found bool, err error)
+	// GetRawTransaction 查询原始交易，height 用于没有 txindex 的 bitcoind 定位区块
+	GetRawTransaction(ctx context.Context, txid string, height int64) ([]byte, error)
+	// Broadcast 广播已签名的原始交易，返回 txid
+	Broadcast(ctx context.Context, rawTx []byte) (string, error)
+	// EstimateFeeRate 估算 targetBlocks 个区块内确认所需费率 (sat/vB)，没有数据时返回 0
+	EstimateFeeRate(ctx context.Context, targetBlocks int) (float64, error)
+}
+
+func newBTCBackend(cfg config.BtcConfig) (BTCBackend, error) {
+	switch cfg.Backend {
+	case "esplora":
+		if cfg.EsploraURL == "" {
+			return nil, ErrBTCBackendNotConfigured
+		}
+		return NewEsploraBackend(cfg.EsploraURL), nil
+	case "bitcoind", "":
+		if cfg.RPC == "" {
+			return nil, ErrBTCBackendNotConfigured
+		}
+		return NewBitcoindBackend(cfg.RPC, cfg.RPCUser, cfg.RPCPass), nil
+	default:
+		return nil, fmt.Errorf("unsupported btc backend: %s", cfg.Backend)
+	}
+}
@@ -0,0 +1,214 @@
+package chain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+)
+
+// BIP380 descriptor checksum
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+var descriptorGenerator = [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+func descriptorPolymod(symbols []uint64) uint64 {
+	chk := uint64(1)
+	for _, v := range symbols {
+		top := chk >> 35
+		chk = (chk&0x7ffffffff)<<5 ^ v
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= descriptorGenerator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// DescriptorChecksum 计算 BIP380 的 8 字符 checksum
+func DescriptorChecksum(desc string) (string, error) {
+	var symbols, groups []uint64
+	for _, c := range desc {
+		v := strings.IndexRune(descriptorInputCharset, c)
+		if v < 0 {
+			return "", fmt.Errorf("invalid descriptor character %q", c)
+		}
+		symbols = append(symbols, uint64(v&31))
+		groups = append(groups, uint64(v>>5))
+		if len(groups) == 3 {
+			symbols = append(symbols, groups[0]*9+groups[1]*3+groups[2])
+			groups = groups[:0]
+		}
+	}
+	switch len(groups) {
+	case 1:
+		symbols = append(symbols, groups[0])
+	case 2:
+		symbols = append(symbols, groups[0]*3+groups[1])
+	}
+	symbols = append(symbols, make([]uint64, 8)...)
+
+	chk := descriptorPolymod(symbols) ^ 1
+	out := make([]byte, 8)
+	for i := range out {
+		out[i] = descriptorChecksumCharset[(chk>>(5*(7-i)))&31]
+	}
+	return string(out), nil
+}
+
+// WithDescriptorChecksum 返回 desc#checksum
+func WithDescriptorChecksum(desc string) (string, error) {
+	sum, err := DescriptorChecksum(desc)
+	if err != nil {
+		return "", err
+	}
+	return desc + "#" + sum, nil
+}
+
+// SLIP-132 扩展公钥版本字节
+var (
+	slip132XPub = []byte{0x04, 0x88, 0xb2, 0x1e} // mainnet P2PKH / P2TR
+	slip132YPub = []byte{0x04, 0x9d, 0x7c, 0xb2} // mainnet P2SH-P2WPKH
+	slip132ZPub = []byte{0x04, 0xb2, 0x47, 0x46} // mainnet P2WPKH
+	slip132TPub = []byte{0x04, 0x35, 0x87, 0xcf} // testnet P2PKH / P2TR
+	slip132UPub = []byte{0x04, 0x4a, 0x52, 0x62} // testnet P2SH-P2WPKH
+	slip132VPub = []byte{0x04, 0x5f, 0x1c, 0xf6} // testnet P2WPKH
+)
+
+// SLIP132Version 该地址类型的比特币扩展公钥版本；taproot 没有专门的前缀，使用 xpub / tpub
+func (t BTCAddressType) SLIP132Version(mainNet bool) []byte {
+	switch {
+	case t == P2SHP2WPKH && mainNet:
+		return slip132YPub
+	case t == P2SHP2WPKH:
+		return slip132UPub
+	case t == P2WPKH && mainNet:
+		return slip132ZPub
+	case t == P2WPKH:
+		return slip132VPub
+	case mainNet:
+		return slip132XPub
+	default:
+		return slip132TPub
+	}
+}
+
+// descriptorScript 地址类型对应的 descriptor 外层函数
+func (t BTCAddressType) descriptorScript(key string) string {
+	switch t {
+	case P2SHP2WPKH:
+		return "sh(wpkh(" + key + "))"
+	case P2WPKH:
+		return "wpkh(" + key + ")"
+	case P2TR:
+		return "tr(" + key + ")"
+	default:
+		return "pkh(" + key + ")"
+	}
+}
+
+// XPubFingerprint master 扩展公钥的 fingerprint (8 位 hex)，与网络无关
+func XPubFingerprint(xpub string) (string, error) {
+	key, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return "", err
+	}
+	if key.Depth() != 0 {
+		return "", errors.New("xpub is not a master key")
+	}
+	pub, err := key.ECPubKey()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(btcutil.Hash160(pub.SerializeCompressed())[:4]), nil
+}
+
+// AccountXPub 派生账户路径 (例如 m/84'/0'/0') 的扩展公钥，固定为 xpub / tpub 版本。
+// 账户路径是 hardened 的，需要 seed；之后的 change/index 可以用 DeriveXPubChild 只从公钥派生
+func (b *BTCChain) AccountXPub(seed []byte, path string) (string, error) {
+	key, err := b.deriveExtendedKey(seed, path)
+	if err != nil {
+		return "", err
+	}
+	pub, err := key.Neuter()
+	if err != nil {
+		return "", err
+	}
+	return pub.String(), nil
+}
+
+// DeriveXPubChild 账户扩展公钥派生 change/index 上的压缩公钥
+func (b *BTCChain) DeriveXPubChild(xpub string, change, index uint32) ([]byte, error) {
+	key, err := b.parseAccountXPub(xpub)
+	if err != nil {
+		return nil, err
+	}
+	if key, err = key.Derive(change); err != nil {
+		return nil, err
+	}
+	if key, err = key.Derive(index); err != nil {
+		return nil, err
+	}
+	pub, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return pub.SerializeCompressed(), nil
+}
+
+// BTCAccountExport 一个 BIP44/49/84/86 账户的 watch-only 导出
+type BTCAccountExport struct {
+	WalletID    string         `json:"wallet_id,omitempty"` // 用户有多个 HD 钱包时区分导出来自哪个钱包
+	AddressType BTCAddressType `json:"address_type"`
+	Path        string         `json:"path"`        // 账户路径，例如 m/84'/0'/0'
+	Fingerprint string         `json:"fingerprint"` // master fingerprint
+	XPub        string         `json:"xpub"`        // SLIP-132: xpub/ypub/zpub (测试网 tpub/upub/vpub)
+	Receive     string         `json:"receive"`     // 外部链 descriptor，带 checksum
+	Change      string         `json:"change"`      // 内部链 descriptor，带 checksum
+}
+
+// AccountExport 派生账户扩展公钥，生成 descriptor (key origin 为 [fingerprint/purpose'/coin'/account']) 和 SLIP-132 xpub。
+// SLIP-132 只定义了比特币的版本号，LTC / DOGE 不支持导出
+func (b *BTCChain) AccountExport(seed []byte, fingerprint string, addrType BTCAddressType, account uint32) (*BTCAccountExport, error) {
+	if b.Name != BTC {
+		return nil, fmt.Errorf("account export is only supported on btc, not %s", b.Name)
+	}
+	path := fmt.Sprintf("m/%d'/%d'/%d'", addrType.Purpose(), b.CoinType(), account)
+	key, err := b.deriveExtendedKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+	// descriptor 里固定使用 xpub / tpub
+	origin := fmt.Sprintf("[%s/%dh/%dh/%dh]", fingerprint, addrType.Purpose(), b.CoinType(), account)
+	receive, err := WithDescriptorChecksum(addrType.descriptorScript(origin + pub.String() + "/0/*"))
+	if err != nil {
+		return nil, err
+	}
+	change, err := WithDescriptorChecksum(addrType.descriptorScript(origin + pub.String() + "/1/*"))
+	if err != nil {
+		return nil, err
+	}
+	slip132, err := pub.CloneWithVersion(addrType.SLIP132Version(b.MainNet))
+	if err != nil {
+		return nil, err
+	}
+	return &BTCAccountExport{
+		AddressType: addrType,
+		Path:        path,
+		Fingerprint: fingerprint,
+		XPub:        slip132.String(),
+		Receive:     receive,
+		Change:      change,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package chain
+
+import (
+	"strings"
+	"testing"
+)
+
+// BIP380 的测试向量和 Bitcoin Core descriptor_tests 里的例子
+func TestDescriptorChecksum(t *testing.T) {
+	tests := []struct {
+		desc     string
+		checksum string
+	}{
+		{desc: "raw(deadbeef)", checksum: "89f8spxm"},
+		{
+			desc:     "pkh([d34db33f/44'/0'/0']xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL/1/*)",
+			checksum: "ml40v0wf",
+		},
+		{
+			desc:     "sh(multi(2,[00000000/111'/222]xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc,xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L/0))",
+			checksum: "ggrsrxfy",
+		},
+		{
+			desc:     "sh(multi(2,[00000000/111'/222]xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL,xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y/0))",
+			checksum: "tjg09x5t",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := DescriptorChecksum(tt.desc)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		if got != tt.checksum {
+			t.Errorf("%s: checksum %s, want %s", tt.desc, got, tt.checksum)
+		}
+		full, err := WithDescriptorChecksum(tt.desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if full != tt.desc+"#"+tt.checksum {
+			t.Errorf("WithDescriptorChecksum = %s", full)
+		}
+	}
+
+	if _, err := DescriptorChecksum("raw(deadbeef)é"); err == nil || !strings.Contains(err.Error(), "invalid descriptor character") {
+		t.Errorf("non-charset character accepted: %v", err)
+	}
+}
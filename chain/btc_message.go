@@ -0,0 +1,249 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// 消息签名格式
+const (
+	MessageSigLegacy       = "legacy"        // signmessage / BIP137，65 字节 compact 签名
+	MessageSigBIP322Simple = "bip322-simple" // BIP322 simple: 只有 witness
+	MessageSigBIP322Full   = "bip322-full"   // BIP322 full: 完整的 to_sign 交易，P2SH-P2WPKH 需要 scriptSig
+)
+
+// ErrInvalidMessageSignature 签名格式正确但与地址 / 消息不匹配
+var ErrInvalidMessageSignature = errors.New("invalid message signature")
+
+// bip322Tag BIP322 消息哈希的 tagged hash 标签
+var bip322Tag = []byte("BIP0322-signed-message")
+
+// SignMessage 用地址对应的私钥签名消息，返回 base64 签名和格式：
+// P2PKH 用 legacy signmessage，P2WPKH / P2TR 用 BIP322 simple，P2SH-P2WPKH 用 BIP322 full
+func (b *BTCChain) SignMessage(key *btcec.PrivateKey, addrType BTCAddressType, message string) (string, string, error) {
+	if addrType == P2PKH {
+		sig, err := ecdsa.SignCompact(key, b.messageHash(message), true)
+		if err != nil {
+			return "", "", err
+		}
+		return base64.StdEncoding.EncodeToString(sig), MessageSigLegacy, nil
+	}
+
+	addr, err := b.AddressFromPubKey(key.PubKey(), addrType)
+	if err != nil {
+		return "", "", err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return "", "", err
+	}
+	toSign := bip322ToSign(bip322ToSpend(message, pkScript))
+	fetcher := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	sigHashes := txscript.NewTxSigHashes(toSign, fetcher)
+
+	switch addrType {
+	case P2WPKH:
+		witness, err := txscript.WitnessSignature(toSign, sigHashes, 0, 0, pkScript, txscript.SigHashAll, key, true)
+		if err != nil {
+			return "", "", err
+		}
+		return encodeWitness(witness), MessageSigBIP322Simple, nil
+	case P2TR:
+		witness, err := txscript.TaprootWitnessSignature(toSign, sigHashes, 0, 0, pkScript, txscript.SigHashDefault, key)
+		if err != nil {
+			return "", "", err
+		}
+		return encodeWitness(witness), MessageSigBIP322Simple, nil
+	case P2SHP2WPKH:
+		redeemScript, err := p2wpkhScript(key.PubKey().SerializeCompressed())
+		if err != nil {
+			return "", "", err
+		}
+		witness, err := txscript.WitnessSignature(toSign, sigHashes, 0, 0, redeemScript, txscript.SigHashAll, key, true)
+		if err != nil {
+			return "", "", err
+		}
+		scriptSig, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+		if err != nil {
+			return "", "", err
+		}
+		toSign.TxIn[0].SignatureScript = scriptSig
+		toSign.TxIn[0].Witness = witness
+		var buf bytes.Buffer
+		if err := toSign.Serialize(&buf); err != nil {
+			return "", "", err
+		}
+		return base64.StdEncoding.EncodeToString(buf.Bytes()), MessageSigBIP322Full, nil
+	default:
+		return "", "", fmt.Errorf("message signing not supported for %s", addrType)
+	}
+}
+
+// VerifyMessage 验证任意地址的消息签名，返回识别出的签名格式。
+// 65 字节签名按 legacy / BIP137 处理，其余依次尝试 BIP322 simple 和 full；
+// 签名与地址不匹配时返回 ErrInvalidMessageSignature
+func (b *BTCChain) VerifyMessage(address, message, signature string) (string, error) {
+	addr, err := btcutil.DecodeAddress(address, b.netParams())
+	if err != nil {
+		return "", err
+	}
+	if !addr.IsForNet(b.netParams()) {
+		return "", fmt.Errorf("address %s is not for %s", address, b.netParams().Name)
+	}
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return "", fmt.Errorf("signature is not base64: %w", err)
+	}
+
+	if len(raw) == 65 && raw[0] >= 27 && raw[0] <= 42 {
+		return MessageSigLegacy, b.verifyLegacyMessage(addr, message, raw)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return "", err
+	}
+	toSpend := bip322ToSpend(message, pkScript)
+	if witness, err := decodeWitness(raw); err == nil {
+		toSign := bip322ToSign(toSpend)
+		toSign.TxIn[0].Witness = witness
+		return MessageSigBIP322Simple, verifyBIP322(toSign, pkScript)
+	}
+
+	toSign := &wire.MsgTx{}
+	if err := toSign.Deserialize(bytes.NewReader(raw)); err != nil {
+		return "", errors.New("unrecognized signature format")
+	}
+	if len(toSign.TxIn) != 1 || toSign.TxIn[0].PreviousOutPoint != *wire.NewOutPoint(ptrHash(toSpend.TxHash()), 0) {
+		return MessageSigBIP322Full, ErrInvalidMessageSignature
+	}
+	return MessageSigBIP322Full, verifyBIP322(toSign, pkScript)
+}
+
+// verifyLegacyMessage 从 compact 签名恢复公钥并与地址比对。
+// BIP137 用 header 35-38 / 39-42 表示 P2SH-P2WPKH / P2WPKH，恢复前换回 31-34
+func (b *BTCChain) verifyLegacyMessage(addr btcutil.Address, message string, sig []byte) error {
+	sig = append([]byte(nil), sig...)
+	switch {
+	case sig[0] >= 39:
+		sig[0] -= 8
+	case sig[0] >= 35:
+		sig[0] -= 4
+	}
+	pub, compressed, err := ecdsa.RecoverCompact(sig, b.messageHash(message))
+	if err != nil {
+		return ErrInvalidMessageSignature
+	}
+
+	if !compressed {
+		pkh, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pub.SerializeUncompressed()), b.netParams())
+		if err != nil || pkh.EncodeAddress() != addr.EncodeAddress() {
+			return ErrInvalidMessageSignature
+		}
+		return nil
+	}
+	for _, t := range []BTCAddressType{P2PKH, P2SHP2WPKH, P2WPKH} {
+		candidate, err := b.AddressFromPubKey(pub, t)
+		if err == nil && candidate.EncodeAddress() == addr.EncodeAddress() {
+			return nil
+		}
+	}
+	return ErrInvalidMessageSignature
+}
+
+// messageHash signmessage 的消息哈希: dsha256(varstr(magic) || varstr(message))
+func (b *BTCChain) messageHash(message string) []byte {
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, b.coin.messageMagic)
+	_ = wire.WriteVarString(&buf, 0, message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// bip322ToSpend BIP322 的虚拟 to_spend 交易，输出脚本是被证明的地址
+func bip322ToSpend(message string, pkScript []byte) *wire.MsgTx {
+	msgHash := chainhash.TaggedHash(bip322Tag, []byte(message))
+	scriptSig, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(msgHash[:]).Script()
+
+	tx := wire.NewMsgTx(0)
+	in := wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), scriptSig, nil)
+	in.Sequence = 0
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(0, pkScript))
+	return tx
+}
+
+// bip322ToSign 花费 to_spend 的虚拟 to_sign 交易，签名放在它的输入里
+func bip322ToSign(toSpend *wire.MsgTx) *wire.MsgTx {
+	tx := wire.NewMsgTx(0)
+	in := wire.NewTxIn(wire.NewOutPoint(ptrHash(toSpend.TxHash()), 0), nil, nil)
+	in.Sequence = 0
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+	return tx
+}
+
+// verifyBIP322 用脚本引擎执行 to_sign 对 to_spend 输出的花费，任意脚本类型都适用
+func verifyBIP322(toSign *wire.MsgTx, pkScript []byte) error {
+	fetcher := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	vm, err := txscript.NewEngine(pkScript, toSign, 0, txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(toSign, fetcher), 0, fetcher)
+	if err != nil {
+		return ErrInvalidMessageSignature
+	}
+	if err := vm.Execute(); err != nil {
+		return ErrInvalidMessageSignature
+	}
+	return nil
+}
+
+// encodeWitness witness stack 按共识格式序列化后 base64
+func encodeWitness(witness wire.TxWitness) string {
+	return base64.StdEncoding.EncodeToString(serializeWitness(witness))
+}
+
+// serializeWitness witness stack 的共识格式: 元素个数 + 每个元素的 varbytes
+func serializeWitness(witness wire.TxWitness) []byte {
+	var buf bytes.Buffer
+	_ = wire.WriteVarInt(&buf, 0, uint64(len(witness)))
+	for _, item := range witness {
+		_ = wire.WriteVarBytes(&buf, 0, item)
+	}
+	return buf.Bytes()
+}
+
+// decodeWitness 解析共识格式的 witness stack，必须正好用完所有字节
+func decodeWitness(raw []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(raw)
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 || n > uint64(len(raw)) {
+		return nil, errors.New("invalid witness item count")
+	}
+	witness := make(wire.TxWitness, 0, n)
+	for i := uint64(0); i < n; i++ {
+		item, err := wire.ReadVarBytes(r, 0, uint32(len(raw)), "witness item")
+		if err != nil {
+			return nil, err
+		}
+		witness = append(witness, item)
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("trailing bytes after witness")
+	}
+	return witness, nil
+}
+
+func ptrHash(h chainhash.Hash) *chainhash.Hash {
+	return &h
+}
@@ -0,0 +1,166 @@
+package chain
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/linlinbupt123-crypto/wallet_service/config"
+)
+
+// BIP322 的测试向量，私钥 L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k
+const (
+	bip322TestKey     = "L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k"
+	bip322TestAddress = "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l"
+)
+
+func testMainNetChain(t *testing.T) *BTCChain {
+	t.Helper()
+	b, err := NewUTXOChain(BTC, config.BtcConfig{MainNet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBIP322Transactions(t *testing.T) {
+	b := testMainNetChain(t)
+	addr, err := btcutil.DecodeAddress(bip322TestAddress, b.netParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		message string
+		hash    string
+		toSpend string
+		toSign  string
+	}{
+		{
+			message: "",
+			hash:    "c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1",
+			toSpend: "c5680aa69bb8d860bf82d4e9cd3504b55dde018de765a91bb566283c545a99a7",
+			toSign:  "1e9654e951a5ba44c8604c4de6c67fd78a27e81dcadcfe1edf638ba3aaebaed6",
+		},
+		{
+			message: "Hello World",
+			hash:    "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a",
+			toSpend: "b79d196740ad5217771c1098fc4a4b51e0535c32236c71f1ea4d61a2d603352b",
+			toSign:  "88737ae86f2077145f93cc4b153ae9a1cb8d56afa511988c149c5c8c9d93bddf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			toSpend := bip322ToSpend(tt.message, pkScript)
+			// to_spend 的输入 scriptSig 是 OP_0 PUSH32[message_hash]
+			if got := hex.EncodeToString(toSpend.TxIn[0].SignatureScript[2:]); got != tt.hash {
+				t.Errorf("message hash %s, want %s", got, tt.hash)
+			}
+			if got := toSpend.TxHash().String(); got != tt.toSpend {
+				t.Errorf("to_spend %s, want %s", got, tt.toSpend)
+			}
+			if got := bip322ToSign(toSpend).TxHash().String(); got != tt.toSign {
+				t.Errorf("to_sign %s, want %s", got, tt.toSign)
+			}
+		})
+	}
+}
+
+func TestVerifyBIP322Vectors(t *testing.T) {
+	b := testMainNetChain(t)
+	tests := []struct {
+		name      string
+		address   string
+		message   string
+		signature string
+		err       error
+	}{
+		{
+			name:      "p2wpkh empty message",
+			address:   bip322TestAddress,
+			message:   "",
+			signature: "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			name:      "p2wpkh hello world",
+			address:   bip322TestAddress,
+			message:   "Hello World",
+			signature: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			name:      "p2wpkh hello world, second signature",
+			address:   bip322TestAddress,
+			message:   "Hello World",
+			signature: "AkgwRQIhAOzyynlqt93lOKJr+wmmxIens//zPzl9tqIOua93wO6MAiBi5n5EyAcPScOjf1lAqIUIQtr3zKNeavYabHyR8eGhowEhAsfxIAMZZEKUPYWI4BruhAQjzFT8FSFSajuFwrDL1Yhy",
+		},
+		{
+			name:      "p2tr hello world",
+			address:   "bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3",
+			message:   "Hello World",
+			signature: "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ==",
+		},
+		{
+			name:      "signature of another message",
+			address:   bip322TestAddress,
+			message:   "Hello World",
+			signature: "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+			err:       ErrInvalidMessageSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := b.VerifyMessage(tt.address, tt.message, tt.signature)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != MessageSigBIP322Simple {
+				t.Errorf("format %s, want %s", format, MessageSigBIP322Simple)
+			}
+		})
+	}
+}
+
+func TestSignMessageRoundTrip(t *testing.T) {
+	b := testMainNetChain(t)
+	wif, err := btcutil.DecodeWIF(bip322TestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addrType := range []BTCAddressType{P2PKH, P2SHP2WPKH, P2WPKH, P2TR} {
+		t.Run(string(addrType), func(t *testing.T) {
+			addr, err := b.AddressFromPubKey(wif.PrivKey.PubKey(), addrType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if addrType == P2WPKH && addr.EncodeAddress() != bip322TestAddress {
+				t.Fatalf("address %s, want %s", addr.EncodeAddress(), bip322TestAddress)
+			}
+			sig, format, err := b.SignMessage(wif.PrivKey, addrType, "Hello World")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := b.VerifyMessage(addr.EncodeAddress(), "Hello World", sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != format {
+				t.Errorf("verified as %s, signed as %s", got, format)
+			}
+			if _, err := b.VerifyMessage(addr.EncodeAddress(), "Hello World!", sig); !errors.Is(err, ErrInvalidMessageSignature) {
+				t.Errorf("signature accepted for another message: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// MaxMultisigKeys P2WSH multisig 支持的最大 cosigner 数
+const MaxMultisigKeys = 15
+
+// BTCKeyOrigin 公钥及其 BIP32 来源，写入 PSBT 让各签名方找到自己的密钥
+type BTCKeyOrigin struct {
+	PubKey      []byte
+	Fingerprint uint32 // PSBT 小端格式
+	Path        string // 完整路径，例如 m/48'/1'/0'/2'/0/5
+}
+
+// BTCCosigner multisig 的一个参与方：账户扩展公钥 + key origin
+type BTCCosigner struct {
+	Fingerprint string // master fingerprint, 8 位 hex
+	Path        string // 账户路径，例如 m/48'/1'/0'/2'
+	XPub        string // 账户扩展公钥，接受 xpub/tpub 以及 SLIP-132 Zpub/Vpub
+}
+
+// BTCMultisig sorted multisig (BIP67) 策略
+type BTCMultisig struct {
+	Threshold int
+	Cosigners []BTCCosigner
+}
+
+// BTCMultisigAddress 某个 change/index 上的 multisig 地址
+type BTCMultisigAddress struct {
+	Address       string
+	PkScript      []byte
+	WitnessScript []byte
+	KeyOrigins    []BTCKeyOrigin // 与 witness script 中的公钥顺序一致
+}
+
+// ParseFingerprint 8 位 hex fingerprint 转成 PSBT 的小端 uint32
+func ParseFingerprint(s string) (uint32, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return 0, fmt.Errorf("invalid fingerprint: %s", s)
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// FormatFingerprint PSBT 小端 uint32 fingerprint 转成 8 位 hex
+func FormatFingerprint(fp uint32) string {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], fp)
+	return hex.EncodeToString(b[:])
+}
+
+// NormalizeDerivationPath 把 descriptor 风格的 h 硬化标记统一成 '
+func NormalizeDerivationPath(path string) string {
+	path = strings.ReplaceAll(path, "h", "'")
+	if !strings.HasPrefix(path, "m/") {
+		path = "m/" + strings.TrimPrefix(path, "/")
+	}
+	return path
+}
+
+// parseAccountXPub 解析 cosigner 的账户扩展公钥，SLIP-132 版本统一转成 xpub / tpub
+func (b *BTCChain) parseAccountXPub(xpub string) (*hdkeychain.ExtendedKey, error) {
+	key, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsPrivate() {
+		return nil, errors.New("cosigner key must be public")
+	}
+	return key.CloneWithVersion(P2PKH.SLIP132Version(b.MainNet))
+}
+
+// ValidateMultisig 检查阈值、cosigner 个数以及每个 cosigner 的 key origin
+func (b *BTCChain) ValidateMultisig(ms BTCMultisig) error {
+	n := len(ms.Cosigners)
+	if n < 1 || n > MaxMultisigKeys {
+		return fmt.Errorf("multisig needs 1-%d cosigners, got %d", MaxMultisigKeys, n)
+	}
+	if ms.Threshold < 1 || ms.Threshold > n {
+		return fmt.Errorf("invalid threshold %d of %d", ms.Threshold, n)
+	}
+	seen := make(map[string]bool, n)
+	for i, c := range ms.Cosigners {
+		if _, err := ParseFingerprint(c.Fingerprint); err != nil {
+			return fmt.Errorf("cosigner %d: %w", i, err)
+		}
+		if _, err := parseDerivationPath(NormalizeDerivationPath(c.Path)); err != nil {
+			return fmt.Errorf("cosigner %d: invalid path %s: %w", i, c.Path, err)
+		}
+		key, err := b.parseAccountXPub(c.XPub)
+		if err != nil {
+			return fmt.Errorf("cosigner %d: %w", i, err)
+		}
+		if seen[key.String()] {
+			return fmt.Errorf("cosigner %d: duplicate xpub", i)
+		}
+		seen[key.String()] = true
+	}
+	return nil
+}
+
+// MultisigAccount 本钱包在 multisig 中的 cosigner: BIP48 P2WSH 账户 m/48'/coin'/account'/2'
+func (b *BTCChain) MultisigAccount(seed []byte, account uint32) (*BTCCosigner, error) {
+	return b.bip48Account(seed, account, 2)
+}
+
+// bip48Account BIP48 账户 m/48'/coin'/account'/scriptType'
+func (b *BTCChain) bip48Account(seed []byte, account, scriptType uint32) (*BTCCosigner, error) {
+	path := fmt.Sprintf("m/48'/%d'/%d'/%d'", b.CoinType(), account, scriptType)
+	key, err := b.deriveExtendedKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := b.Fingerprint(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &BTCCosigner{
+		Fingerprint: FormatFingerprint(fingerprint),
+		Path:        path,
+		XPub:        pub.String(),
+	}, nil
+}
+
+// MultisigAddress 派生 change/index 上的 sorted multisig P2WSH 地址
+func (b *BTCChain) MultisigAddress(ms BTCMultisig, change, index uint32) (*BTCMultisigAddress, error) {
+	origins := make([]BTCKeyOrigin, 0, len(ms.Cosigners))
+	for _, c := range ms.Cosigners {
+		origin, err := b.cosignerKey(c, change, index)
+		if err != nil {
+			return nil, err
+		}
+		origins = append(origins, *origin)
+	}
+	// BIP67: 公钥按字典序排序
+	sort.Slice(origins, func(i, j int) bool { return bytes.Compare(origins[i].PubKey, origins[j].PubKey) < 0 })
+
+	builder := txscript.NewScriptBuilder().AddInt64(int64(ms.Threshold))
+	for _, o := range origins {
+		builder.AddData(o.PubKey)
+	}
+	witnessScript, err := builder.
+		AddInt64(int64(len(origins))).
+		AddOp(txscript.OP_CHECKMULTISIG).
+		Script()
+	if err != nil {
+		return nil, err
+	}
+
+	scriptHash := sha256.Sum256(witnessScript)
+	addr, err := btcutil.NewAddressWitnessScriptHash(scriptHash[:], b.netParams())
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &BTCMultisigAddress{
+		Address:       addr.EncodeAddress(),
+		PkScript:      pkScript,
+		WitnessScript: witnessScript,
+		KeyOrigins:    origins,
+	}, nil
+}
+
+// MultisigDescriptor wsh(sortedmulti(...)) descriptor，带 checksum
+func (b *BTCChain) MultisigDescriptor(ms BTCMultisig, change uint32) (string, error) {
+	keys := make([]string, 0, len(ms.Cosigners))
+	for _, c := range ms.Cosigners {
+		key, err := b.descriptorKey(c, change)
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, key)
+	}
+	return WithDescriptorChecksum("wsh(sortedmulti(" + strconv.Itoa(ms.Threshold) + "," + strings.Join(keys, ",") + "))")
+}
+
+// cosignerKey 账户扩展公钥派生出 change/index 上的公钥及其来源
+func (b *BTCChain) cosignerKey(c BTCCosigner, change, index uint32) (*BTCKeyOrigin, error) {
+	pub, err := b.DeriveXPubChild(c.XPub, change, index)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := ParseFingerprint(c.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return &BTCKeyOrigin{
+		PubKey:      pub,
+		Fingerprint: fingerprint,
+		Path:        fmt.Sprintf("%s/%d/%d", NormalizeDerivationPath(c.Path), change, index),
+	}, nil
+}
+
+// descriptorKey [fingerprint/path]xpub/change/*
+func (b *BTCChain) descriptorKey(c BTCCosigner, change uint32) (string, error) {
+	key, err := b.parseAccountXPub(c.XPub)
+	if err != nil {
+		return "", err
+	}
+	origin := strings.ReplaceAll(strings.TrimPrefix(NormalizeDerivationPath(c.Path), "m"), "'", "h")
+	return fmt.Sprintf("[%s%s]%s/%d/*", c.Fingerprint, origin, key.String(), change), nil
+}
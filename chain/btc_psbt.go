@@ -0,0 +1,371 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BTCInputSigStatus 单个输入的签名进度
+type BTCInputSigStatus struct {
+	Index      int `json:"index"`
+	Signatures int `json:"signatures"`
+	Required   int `json:"required"`
+}
+
+// BTCPSBTStatus PSBT 的签名进度
+type BTCPSBTStatus struct {
+	Inputs   []BTCInputSigStatus `json:"inputs"`
+	Signers  []string            `json:"signers"`  // 已签完所有可签输入的 master fingerprint
+	Complete bool                `json:"complete"` // 所有输入签名数都已达到阈值，可以 finalize
+}
+
+// PSBTStatus 统计每个输入已有的签名数和需要的签名数
+func PSBTStatus(p *psbt.Packet) *BTCPSBTStatus {
+	status := &BTCPSBTStatus{Complete: true}
+	// fingerprint -> 是否签完了它出现的所有输入
+	signers := make(map[uint32]bool)
+	var order []uint32
+
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		st := BTCInputSigStatus{Index: i, Required: 1}
+		switch {
+		case in.FinalScriptSig != nil || in.FinalScriptWitness != nil:
+			st.Signatures = st.Required
+		case in.WitnessScript != nil:
+			if _, m, err := txscript.CalcMultiSigStats(in.WitnessScript); err == nil {
+				st.Required = m
+			}
+			st.Signatures = len(in.PartialSigs)
+		case in.TaprootKeySpendSig != nil:
+			st.Signatures = 1
+		case len(in.TaprootScriptSpendSig) > 0:
+			st.Signatures = len(in.TaprootScriptSpendSig)
+		default:
+			st.Signatures = len(in.PartialSigs)
+		}
+		if st.Signatures < st.Required {
+			status.Complete = false
+		}
+		status.Inputs = append(status.Inputs, st)
+
+		for _, d := range in.Bip32Derivation {
+			done, ok := signers[d.MasterKeyFingerprint]
+			if !ok {
+				order = append(order, d.MasterKeyFingerprint)
+				done = true
+			}
+			signers[d.MasterKeyFingerprint] = done && hasPartialSig(in, d.PubKey)
+		}
+		for _, d := range in.TaprootBip32Derivation {
+			done, ok := signers[d.MasterKeyFingerprint]
+			if !ok {
+				order = append(order, d.MasterKeyFingerprint)
+				done = true
+			}
+			signed := in.TaprootKeySpendSig != nil
+			for _, leafHash := range d.LeafHashes {
+				signed = signed || hasTapScriptSig(in, d.XOnlyPubKey, leafHash)
+			}
+			signers[d.MasterKeyFingerprint] = done && signed
+		}
+	}
+	for _, fp := range order {
+		if signers[fp] {
+			status.Signers = append(status.Signers, FormatFingerprint(fp))
+		}
+	}
+	return status
+}
+
+// CombinePSBT 把 src 中 dst 没有的签名合并进 dst (BIP174 combiner)，返回新增的签名个数。
+// 两者必须是同一笔未签名交易，合并前校验每个签名；已 finalize 的输入用脚本引擎执行一遍 final script
+func CombinePSBT(dst, src *psbt.Packet) (int, error) {
+	if dst.UnsignedTx.TxHash() != src.UnsignedTx.TxHash() {
+		return 0, errors.New("psbt is for a different transaction")
+	}
+	if len(dst.Inputs) != len(src.Inputs) {
+		return 0, errors.New("psbt input count mismatch")
+	}
+
+	sigHashes, prevOuts, err := psbtSigHashes(dst)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for i := range src.Inputs {
+		d, s := &dst.Inputs[i], &src.Inputs[i]
+		if d.FinalScriptSig != nil || d.FinalScriptWitness != nil {
+			continue
+		}
+		if s.FinalScriptSig != nil || s.FinalScriptWitness != nil {
+			if err := verifyFinalScripts(dst, i, s, sigHashes, prevOuts); err != nil {
+				return added, fmt.Errorf("input %d: %w", i, err)
+			}
+			d.FinalScriptSig, d.FinalScriptWitness = s.FinalScriptSig, s.FinalScriptWitness
+			added++
+			continue
+		}
+		for _, sig := range s.PartialSigs {
+			if hasPartialSig(d, sig.PubKey) {
+				continue
+			}
+			if err := verifyPartialSig(dst, i, sigHashes, sig); err != nil {
+				return added, fmt.Errorf("input %d: %w", i, err)
+			}
+			d.PartialSigs = append(d.PartialSigs, sig)
+			added++
+		}
+		if d.TaprootKeySpendSig == nil && s.TaprootKeySpendSig != nil {
+			if err := verifyTaprootKeySpendSig(dst, i, sigHashes, prevOuts, s.TaprootKeySpendSig); err != nil {
+				return added, fmt.Errorf("input %d: %w", i, err)
+			}
+			d.TaprootKeySpendSig = s.TaprootKeySpendSig
+			added++
+		}
+		for _, sig := range s.TaprootScriptSpendSig {
+			if hasTapScriptSig(d, sig.XOnlyPubKey, sig.LeafHash) {
+				continue
+			}
+			if err := verifyTapScriptSig(dst, i, s, sigHashes, prevOuts, sig); err != nil {
+				return added, fmt.Errorf("input %d: %w", i, err)
+			}
+			d.TaprootScriptSpendSig = append(d.TaprootScriptSpendSig, sig)
+			added++
+		}
+	}
+	return added, nil
+}
+
+func psbtSigHashes(p *psbt.Packet) (*txscript.TxSigHashes, txscript.PrevOutputFetcher, error) {
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, txIn := range p.UnsignedTx.TxIn {
+		utxo, err := inputUtxo(p, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		prevOuts.AddPrevOut(txIn.PreviousOutPoint, utxo)
+	}
+	return txscript.NewTxSigHashes(p.UnsignedTx, prevOuts), prevOuts, nil
+}
+
+// verifyFinalScripts 把导入的 final scriptSig / witness 放进交易副本，用脚本引擎校验能否花掉该输入
+func verifyFinalScripts(p *psbt.Packet, i int, in *psbt.PInput, sigHashes *txscript.TxSigHashes, prevOuts txscript.PrevOutputFetcher) error {
+	utxo, err := inputUtxo(p, i)
+	if err != nil {
+		return err
+	}
+	tx := p.UnsignedTx.Copy()
+	tx.TxIn[i].SignatureScript = in.FinalScriptSig
+	if in.FinalScriptWitness != nil {
+		witness, err := parseWitness(in.FinalScriptWitness)
+		if err != nil {
+			return fmt.Errorf("invalid final witness: %w", err)
+		}
+		tx.TxIn[i].Witness = witness
+	}
+	vm, err := txscript.NewEngine(utxo.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, utxo.Value, prevOuts)
+	if err != nil {
+		return err
+	}
+	if err := vm.Execute(); err != nil {
+		return fmt.Errorf("final script does not spend the input: %w", err)
+	}
+	return nil
+}
+
+// parseWitness 解析 PSBT 里序列化的 witness 栈
+func parseWitness(b []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(b)
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(b)) {
+		return nil, errors.New("too many witness items")
+	}
+	witness := make(wire.TxWitness, n)
+	for j := range witness {
+		if witness[j], err = wire.ReadVarBytes(r, 0, uint32(len(b)), "witness item"); err != nil {
+			return nil, err
+		}
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("trailing witness bytes")
+	}
+	return witness, nil
+}
+
+// verifyTaprootKeySpendSig 校验 key path Schnorr 签名，公钥是输出脚本里的 output key
+func verifyTaprootKeySpendSig(p *psbt.Packet, i int, sigHashes *txscript.TxSigHashes, prevOuts txscript.PrevOutputFetcher, sig []byte) error {
+	utxo, err := inputUtxo(p, i)
+	if err != nil {
+		return err
+	}
+	if !txscript.IsPayToTaproot(utxo.PkScript) {
+		return errors.New("taproot key spend signature on a non-taproot input")
+	}
+	pub, err := schnorr.ParsePubKey(utxo.PkScript[2:])
+	if err != nil {
+		return err
+	}
+	return verifySchnorr(sig, pub, func(hashType txscript.SigHashType) ([]byte, error) {
+		return txscript.CalcTaprootSignatureHash(sigHashes, hashType, p.UnsignedTx, i, prevOuts)
+	})
+}
+
+// verifyTapScriptSig 校验 script path Schnorr 签名，leaf script 先从 dst 找，找不到用导入方带的
+func verifyTapScriptSig(p *psbt.Packet, i int, src *psbt.PInput, sigHashes *txscript.TxSigHashes, prevOuts txscript.PrevOutputFetcher, sig *psbt.TaprootScriptSpendSig) error {
+	leaf, err := psbt.FindLeafScript(&p.Inputs[i], sig.LeafHash)
+	if err != nil {
+		if leaf, err = psbt.FindLeafScript(src, sig.LeafHash); err != nil {
+			return fmt.Errorf("no leaf script for tapscript signature by %x", sig.XOnlyPubKey)
+		}
+	}
+	tapLeaf := txscript.NewTapLeaf(leaf.LeafVersion, leaf.Script)
+	pub, err := schnorr.ParsePubKey(sig.XOnlyPubKey)
+	if err != nil {
+		return err
+	}
+	raw := sig.Signature
+	if sig.SigHash != txscript.SigHashDefault {
+		raw = append(append([]byte{}, raw...), byte(sig.SigHash))
+	}
+	return verifySchnorr(raw, pub, func(hashType txscript.SigHashType) ([]byte, error) {
+		return txscript.CalcTapscriptSignaturehash(sigHashes, hashType, p.UnsignedTx, i, prevOuts, tapLeaf)
+	})
+}
+
+// verifySchnorr 64 字节签名为 SIGHASH_DEFAULT，65 字节时最后一个字节是 sighash type
+func verifySchnorr(raw []byte, pub *btcec.PublicKey, sigHash func(txscript.SigHashType) ([]byte, error)) error {
+	hashType := txscript.SigHashDefault
+	switch len(raw) {
+	case schnorr.SignatureSize:
+	case schnorr.SignatureSize + 1:
+		hashType = txscript.SigHashType(raw[schnorr.SignatureSize])
+		raw = raw[:schnorr.SignatureSize]
+	default:
+		return fmt.Errorf("invalid schnorr signature length %d", len(raw))
+	}
+	hash, err := sigHash(hashType)
+	if err != nil {
+		return err
+	}
+	sig, err := schnorr.ParseSignature(raw)
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(hash, pub) {
+		return fmt.Errorf("invalid schnorr signature for pubkey %x", schnorr.SerializePubKey(pub))
+	}
+	return nil
+}
+
+// verifyPartialSig 校验 legacy / segwit v0 部分签名
+func verifyPartialSig(p *psbt.Packet, i int, sigHashes *txscript.TxSigHashes, partial *psbt.PartialSig) error {
+	if len(partial.Signature) < 2 {
+		return errors.New("empty signature")
+	}
+	utxo, err := inputUtxo(p, i)
+	if err != nil {
+		return err
+	}
+	in := p.Inputs[i]
+	hashType := txscript.SigHashType(partial.Signature[len(partial.Signature)-1])
+	script := utxo.PkScript
+	if in.RedeemScript != nil {
+		script = in.RedeemScript
+	}
+	if in.WitnessScript != nil {
+		script = in.WitnessScript
+	}
+
+	var hash []byte
+	if in.WitnessScript == nil && !txscript.IsWitnessProgram(script) {
+		hash, err = txscript.CalcSignatureHash(script, hashType, p.UnsignedTx, i)
+	} else {
+		hash, err = txscript.CalcWitnessSigHash(script, sigHashes, hashType, p.UnsignedTx, i, utxo.Value)
+	}
+	if err != nil {
+		return err
+	}
+
+	sig, err := ecdsa.ParseDERSignature(partial.Signature[:len(partial.Signature)-1])
+	if err != nil {
+		return err
+	}
+	pub, err := btcec.ParsePubKey(partial.PubKey)
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(hash, pub) {
+		return fmt.Errorf("invalid signature for pubkey %x", partial.PubKey)
+	}
+	return nil
+}
+
+// EncodePSBT PSBT 序列化成 base64
+func EncodePSBT(p *psbt.Packet) (string, error) {
+	return p.B64Encode()
+}
+
+// SerializePSBT PSBT 二进制格式 (.psbt 文件)
+func SerializePSBT(p *psbt.Packet) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodePSBT 解析 base64 或二进制 PSBT
+func DecodePSBT(data []byte) (*psbt.Packet, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("psbt\xff")) {
+		return psbt.NewFromRawBytes(bytes.NewReader(trimmed), false)
+	}
+	return psbt.NewFromRawBytes(bytes.NewReader(trimmed), true)
+}
+
+// PSBTOutput 未签名交易的一个输出
+type PSBTOutput struct {
+	Address string
+	Value   int64
+}
+
+// PSBTOutputs 解码 PSBT 中未签名交易的输出，非标准脚本的地址为空
+func (b *BTCChain) PSBTOutputs(p *psbt.Packet) []PSBTOutput {
+	out := make([]PSBTOutput, 0, len(p.UnsignedTx.TxOut))
+	for _, txOut := range p.UnsignedTx.TxOut {
+		o := PSBTOutput{Value: txOut.Value}
+		if _, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, b.netParams()); err == nil && len(addrs) == 1 {
+			o.Address = addrs[0].EncodeAddress()
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// PSBTFee 输入金额减输出金额 (satoshi)，输入需要带 witness_utxo 或完整的前序交易
+func PSBTFee(p *psbt.Packet) (int64, error) {
+	var fee int64
+	for i := range p.UnsignedTx.TxIn {
+		utxo, err := inputUtxo(p, i)
+		if err != nil {
+			return 0, err
+		}
+		fee += utxo.Value
+	}
+	for _, out := range p.UnsignedTx.TxOut {
+		fee -= out.Value
+	}
+	return fee, nil
+}
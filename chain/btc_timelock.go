@@ -0,0 +1,239 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// MaxTimelockBlocks older(N) 按区块计的相对时间锁上限 (BIP68 低 16 位)
+const MaxTimelockBlocks = 0xffff
+
+// 时间锁钱包输入的 vsize 估算值 (vbytes)
+const (
+	timelockWshPrimaryVSize  = 80 // [sig, witness script]
+	timelockWshRecoveryVSize = 81 // [sig, 空元素, witness script]
+	timelockTrRecoveryVSize  = 76 // [sig, leaf script, control block]
+)
+
+// BTCTimelock "primary 随时可花，或 recovery 在 Older 个区块后可花" 的策略，
+// miniscript 为 or_d(pk(primary),and_v(v:pk(recovery),older(N)))；
+// taproot 下 primary 作为 internal key 走 key path，recovery 分支是唯一的 script leaf
+type BTCTimelock struct {
+	ScriptType BTCAddressType // P2WSH / P2TR
+	Primary    BTCCosigner
+	Recovery   BTCCosigner
+	Older      uint32 // 区块数
+}
+
+// BTCTimelockAddress 某个 change/index 上的时间锁地址
+type BTCTimelockAddress struct {
+	Address  string
+	PkScript []byte
+	Older    uint32
+	Primary  BTCKeyOrigin
+	Recovery BTCKeyOrigin
+
+	WitnessScript []byte // P2WSH
+
+	// P2TR: internal key 是 primary 的 x-only 公钥
+	LeafScript   []byte
+	ControlBlock []byte
+	MerkleRoot   []byte
+}
+
+// TimelockMiniscript 策略的 miniscript，key 用 primary / recovery 占位；taproot 返回 recovery leaf
+func TimelockMiniscript(scriptType BTCAddressType, older uint32) string {
+	if scriptType == P2TR {
+		return fmt.Sprintf("and_v(v:pk(recovery),older(%d))", older)
+	}
+	return fmt.Sprintf("or_d(pk(primary),and_v(v:pk(recovery),older(%d)))", older)
+}
+
+// TimelockInputVSize 时间锁输入的 vsize，recovery 表示走时间锁分支
+func TimelockInputVSize(scriptType BTCAddressType, recovery bool) int64 {
+	switch {
+	case scriptType == P2TR && recovery:
+		return timelockTrRecoveryVSize
+	case scriptType == P2TR:
+		return inputVSize(P2TR)
+	case recovery:
+		return timelockWshRecoveryVSize
+	default:
+		return timelockWshPrimaryVSize
+	}
+}
+
+// ValidateTimelock 检查脚本类型、时间锁和两把 key 的来源
+func (b *BTCChain) ValidateTimelock(t BTCTimelock) error {
+	if t.ScriptType != P2WSH && t.ScriptType != P2TR {
+		return fmt.Errorf("timelock wallets support p2wsh or p2tr, got %s", t.ScriptType)
+	}
+	if t.Older < 1 || t.Older > MaxTimelockBlocks {
+		return fmt.Errorf("older must be 1-%d blocks, got %d", MaxTimelockBlocks, t.Older)
+	}
+	ms := BTCMultisig{Threshold: 1, Cosigners: []BTCCosigner{t.Primary, t.Recovery}}
+	if err := b.ValidateMultisig(ms); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TimelockAccount 本钱包的 primary key: m/48'/coin'/account'/2' (p2wsh) 或 3' (p2tr)
+func (b *BTCChain) TimelockAccount(seed []byte, scriptType BTCAddressType, account uint32) (*BTCCosigner, error) {
+	if scriptType == P2TR {
+		return b.bip48Account(seed, account, 3)
+	}
+	return b.bip48Account(seed, account, 2)
+}
+
+// TimelockAddress 派生 change/index 上的时间锁地址
+func (b *BTCChain) TimelockAddress(t BTCTimelock, change, index uint32) (*BTCTimelockAddress, error) {
+	primary, err := b.cosignerKey(t.Primary, change, index)
+	if err != nil {
+		return nil, fmt.Errorf("primary: %w", err)
+	}
+	recovery, err := b.cosignerKey(t.Recovery, change, index)
+	if err != nil {
+		return nil, fmt.Errorf("recovery: %w", err)
+	}
+	out := &BTCTimelockAddress{Older: t.Older, Primary: *primary, Recovery: *recovery}
+
+	var addr btcutil.Address
+	switch t.ScriptType {
+	case P2WSH:
+		out.WitnessScript, err = timelockWitnessScript(primary.PubKey, recovery.PubKey, t.Older)
+		if err != nil {
+			return nil, err
+		}
+		scriptHash := sha256.Sum256(out.WitnessScript)
+		addr, err = btcutil.NewAddressWitnessScriptHash(scriptHash[:], b.netParams())
+	case P2TR:
+		internalKey, err := btcec.ParsePubKey(primary.PubKey)
+		if err != nil {
+			return nil, err
+		}
+		out.LeafScript, err = timelockLeafScript(recovery.PubKey, t.Older)
+		if err != nil {
+			return nil, err
+		}
+		tree := txscript.AssembleTaprootScriptTree(txscript.NewBaseTapLeaf(out.LeafScript))
+		root := tree.RootNode.TapHash()
+		out.MerkleRoot = root[:]
+		controlBlock := tree.LeafMerkleProofs[0].ToControlBlock(internalKey)
+		out.ControlBlock, err = controlBlock.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		outputKey := txscript.ComputeTaprootOutputKey(internalKey, out.MerkleRoot)
+		addr, err = btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), b.netParams())
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported timelock script type: %s", t.ScriptType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.Address = addr.EncodeAddress()
+	if out.PkScript, err = txscript.PayToAddrScript(addr); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TimelockDescriptor wsh(or_d(...)) / tr(primary,and_v(...)) descriptor，带 checksum
+func (b *BTCChain) TimelockDescriptor(t BTCTimelock, change uint32) (string, error) {
+	primary, err := b.descriptorKey(t.Primary, change)
+	if err != nil {
+		return "", err
+	}
+	recovery, err := b.descriptorKey(t.Recovery, change)
+	if err != nil {
+		return "", err
+	}
+	if t.ScriptType == P2TR {
+		return WithDescriptorChecksum(fmt.Sprintf("tr(%s,and_v(v:pk(%s),older(%d)))", primary, recovery, t.Older))
+	}
+	return WithDescriptorChecksum(fmt.Sprintf("wsh(or_d(pk(%s),and_v(v:pk(%s),older(%d))))", primary, recovery, t.Older))
+}
+
+// timelockWitnessScript <A> CHECKSIG IFDUP NOTIF <B> CHECKSIGVERIFY <N> CSV ENDIF
+func timelockWitnessScript(primary, recovery []byte, older uint32) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddData(primary).AddOp(txscript.OP_CHECKSIG).
+		AddOp(txscript.OP_IFDUP).AddOp(txscript.OP_NOTIF).
+		AddData(recovery).AddOp(txscript.OP_CHECKSIGVERIFY).
+		AddInt64(int64(older)).AddOp(txscript.OP_CHECKSEQUENCEVERIFY).
+		AddOp(txscript.OP_ENDIF).
+		Script()
+}
+
+// timelockLeafScript tapscript: <xonly B> CHECKSIGVERIFY <N> CSV
+func timelockLeafScript(recovery []byte, older uint32) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddData(recovery[1:]).AddOp(txscript.OP_CHECKSIGVERIFY).
+		AddInt64(int64(older)).AddOp(txscript.OP_CHECKSEQUENCEVERIFY).
+		Script()
+}
+
+// parseTimelockWitnessScript 识别 timelockWitnessScript，返回两把公钥
+func parseTimelockWitnessScript(script []byte) (primary, recovery []byte, ok bool) {
+	var ops []byte
+	var pushes [][]byte
+	tok := txscript.MakeScriptTokenizer(0, script)
+	for tok.Next() {
+		ops = append(ops, tok.Opcode())
+		if len(tok.Data()) == 33 {
+			pushes = append(pushes, tok.Data())
+		}
+	}
+	if tok.Err() != nil || len(ops) != 9 || len(pushes) != 2 {
+		return nil, nil, false
+	}
+	if ops[1] != txscript.OP_CHECKSIG || ops[2] != txscript.OP_IFDUP || ops[3] != txscript.OP_NOTIF ||
+		ops[5] != txscript.OP_CHECKSIGVERIFY || ops[7] != txscript.OP_CHECKSEQUENCEVERIFY || ops[8] != txscript.OP_ENDIF {
+		return nil, nil, false
+	}
+	return pushes[0], pushes[1], true
+}
+
+// finalizeTimelockInputs 为 P2WSH 时间锁输入组装 witness，btcd 的 finalizer 只认识 multisig:
+// primary 签名优先 [sigA, ws]，否则走 recovery 分支 [sigB, 空, ws]
+func finalizeTimelockInputs(p *psbt.Packet) error {
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.FinalScriptWitness != nil || in.WitnessScript == nil {
+			continue
+		}
+		primary, recovery, ok := parseTimelockWitnessScript(in.WitnessScript)
+		if !ok {
+			continue
+		}
+		var witness wire.TxWitness
+		for _, sig := range in.PartialSigs {
+			if bytes.Equal(sig.PubKey, primary) {
+				witness = wire.TxWitness{sig.Signature, in.WitnessScript}
+				break
+			}
+			if bytes.Equal(sig.PubKey, recovery) {
+				witness = wire.TxWitness{sig.Signature, nil, in.WitnessScript}
+			}
+		}
+		if witness == nil {
+			return fmt.Errorf("input %d: timelock input is not signed", i)
+		}
+		finalized := psbt.NewPsbtInput(nil, in.WitnessUtxo)
+		finalized.FinalScriptWitness = serializeWitness(witness)
+		p.Inputs[i] = *finalized
+	}
+	return nil
+}
@@ -0,0 +1,653 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	wrapErrors "github.com/linlinbupt123-crypto/wallet_service/errors"
+)
+
+// DustLimit 低于该值的找零输出直接并入手续费 (satoshi)
+const DustLimit int64 = 546
+
+// btcInputSequence 输入的 nSequence，0xfffffffd 表示可被替换 (BIP125 opt-in RBF)
+const btcInputSequence = wire.MaxTxInSequenceNum - 2
+
+// MinRelayFeeRate 节点默认的最低转发费率 (sat/vB)，RBF 替换至少要多付这么多
+const MinRelayFeeRate int64 = 1
+
+// BTCInput 待花费的输入，以及签名方需要的派生信息
+type BTCInput struct {
+	TxID        string
+	Vout        uint32
+	Value       int64 // satoshi
+	PkScript    []byte
+	AddressType BTCAddressType
+	Path        string      // 派生路径，例如 m/84'/0'/0'/0/3
+	PubKey      []byte      // 压缩公钥
+	PrevTx      *wire.MsgTx // legacy P2PKH 输入必须带上完整的前序交易
+
+	// P2WSH multisig 输入: witness script 和所有 cosigner 的派生信息，Path/PubKey 不用填
+	WitnessScript []byte
+	KeyOrigins    []BTCKeyOrigin
+
+	// 时间锁钱包输入，Recovery 为 true 时走时间锁分支 (nSequence = Older)
+	Timelock *BTCTimelockAddress
+	Recovery bool
+}
+
+// BTCOutput 交易输出；Path/PubKey 只有找零输出需要填写
+type BTCOutput struct {
+	Address     string
+	Value       int64 // satoshi
+	AddressType BTCAddressType
+	Path        string
+	PubKey      []byte
+
+	// P2WSH multisig 找零输出，同 BTCInput
+	WitnessScript []byte
+	KeyOrigins    []BTCKeyOrigin
+
+	// 时间锁钱包的找零输出
+	Timelock *BTCTimelockAddress
+}
+
+// IsChange 只有本钱包的找零输出带派生信息
+func (o BTCOutput) IsChange() bool {
+	return o.Path != "" || len(o.KeyOrigins) > 0 || o.Timelock != nil
+}
+
+// 各类型输入/输出的 vsize 估算值 (vbytes)
+func inputVSize(t BTCAddressType) int64 {
+	switch t {
+	case P2SHP2WPKH:
+		return 91
+	case P2WPKH:
+		return 68
+	case P2TR:
+		return 58
+	case P2WSH:
+		// 没有具体策略时按 2-of-3 估算，multisig 钱包应使用 MultisigInputVSize
+		return MultisigInputVSize(2, 3)
+	default:
+		return 148
+	}
+}
+
+func outputVSize(pkScript []byte) int64 {
+	// 8 字节金额 + 1 字节脚本长度 + 脚本
+	return int64(9 + len(pkScript))
+}
+
+// MultisigInputVSize m-of-n P2WSH multisig 输入的 vsize:
+// 41 字节非 witness 部分 + witness (元素个数、CHECKMULTISIG 的空元素、m 个签名、witness script) / 4
+func MultisigInputVSize(m, n int) int64 {
+	scriptLen := int64(3 + 34*n)
+	witness := int64(1+1+73*m) + scriptLen + 1
+	if scriptLen > 252 {
+		witness += 2
+	}
+	return 41 + (witness+3)/4
+}
+
+// EstimateVSize 估算交易 vsize，用于按 sat/vB 计算手续费
+func EstimateVSize(inputs []BTCAddressType, outputs [][]byte) int64 {
+	sizes := make([]int64, len(inputs))
+	segwit := false
+	for i, t := range inputs {
+		sizes[i] = inputVSize(t)
+		segwit = segwit || t != P2PKH
+	}
+	return estimateVSize(sizes, segwit, outputs)
+}
+
+func estimateVSize(inputSizes []int64, segwit bool, outputs [][]byte) int64 {
+	// version + locktime + 输入/输出个数
+	size := int64(10)
+	if segwit {
+		// segwit marker + flag
+		size++
+	}
+	for _, s := range inputSizes {
+		size += s
+	}
+	for _, o := range outputs {
+		size += outputVSize(o)
+	}
+	return size
+}
+
+// TxVSize 已签名交易的实际 vsize: ceil(weight / 4)
+func TxVSize(tx *wire.MsgTx) int64 {
+	weight := int64(tx.SerializeSizeStripped()*3 + tx.SerializeSize())
+	return (weight + 3) / 4
+}
+
+// MasterFingerprint BIP32 master key fingerprint，按 PSBT 的小端格式返回
+func MasterFingerprint(master *hdkeychain.ExtendedKey) (uint32, error) {
+	pub, err := master.ECPubKey()
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(btcutil.Hash160(pub.SerializeCompressed())[:4]), nil
+}
+
+// Fingerprint 由 seed 计算 master fingerprint
+func (b *BTCChain) Fingerprint(seed []byte) (uint32, error) {
+	master, err := hdkeychain.NewMaster(seed, b.netParams())
+	if err != nil {
+		return 0, err
+	}
+	return MasterFingerprint(master)
+}
+
+// DerivePubKey 派生 path 对应的压缩公钥
+func (b *BTCChain) DerivePubKey(seed []byte, path string) ([]byte, error) {
+	key, err := b.deriveExtendedKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return pub.SerializeCompressed(), nil
+}
+
+// BuildPSBT 构造未签名的 PSBT，每个输入都带上 BIP32 派生信息，
+// 签名方 (本服务或外部硬件钱包) 据此找到对应私钥
+func (b *BTCChain) BuildPSBT(fingerprint uint32, inputs []BTCInput, outputs []BTCOutput) (*psbt.Packet, error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return nil, errors.New("psbt needs at least one input and one output")
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for _, in := range inputs {
+		hash, err := chainhash.NewHashFromStr(in.TxID)
+		if err != nil {
+			return nil, err
+		}
+		txIn := wire.NewTxIn(wire.NewOutPoint(hash, in.Vout), nil, nil)
+		txIn.Sequence = btcInputSequence
+		// BIP68 相对时间锁: nSequence 为区块数，交易版本至少为 2
+		if in.Recovery && in.Timelock != nil {
+			txIn.Sequence = in.Timelock.Older
+			tx.Version = 2
+		}
+		tx.AddTxIn(txIn)
+	}
+	for _, out := range outputs {
+		pkScript, err := b.PkScript(out.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output address %s: %w", out.Address, err)
+		}
+		tx.AddTxOut(wire.NewTxOut(out.Value, pkScript))
+	}
+
+	p, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	u, err := psbt.NewUpdater(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, in := range inputs {
+		if err := addInputInfo(u, i, fingerprint, in); err != nil {
+			return nil, fmt.Errorf("psbt input %d: %w", i, err)
+		}
+	}
+	for i, out := range outputs {
+		if !out.IsChange() {
+			continue
+		}
+		if err := addOutputInfo(u, i, fingerprint, out); err != nil {
+			return nil, fmt.Errorf("psbt output %d: %w", i, err)
+		}
+	}
+	return p, nil
+}
+
+func addInputInfo(u *psbt.Updater, i int, fingerprint uint32, in BTCInput) error {
+	if in.Timelock != nil {
+		return addTimelockInputInfo(u, i, in)
+	}
+	if in.AddressType == P2WSH {
+		if err := u.AddInWitnessUtxo(wire.NewTxOut(in.Value, in.PkScript), i); err != nil {
+			return err
+		}
+		if err := u.AddInWitnessScript(in.WitnessScript, i); err != nil {
+			return err
+		}
+		for _, o := range in.KeyOrigins {
+			path, err := parseDerivationPath(o.Path)
+			if err != nil {
+				return err
+			}
+			if err := u.AddInBip32Derivation(o.Fingerprint, path, o.PubKey, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path, err := parseDerivationPath(in.Path)
+	if err != nil {
+		return err
+	}
+
+	switch in.AddressType {
+	case P2PKH:
+		if in.PrevTx == nil {
+			return errors.New("legacy input requires previous transaction")
+		}
+		if err := u.AddInNonWitnessUtxo(in.PrevTx, i); err != nil {
+			return err
+		}
+	default:
+		if err := u.AddInWitnessUtxo(wire.NewTxOut(in.Value, in.PkScript), i); err != nil {
+			return err
+		}
+	}
+
+	switch in.AddressType {
+	case P2TR:
+		xOnly := in.PubKey[1:]
+		u.Upsbt.Inputs[i].TaprootInternalKey = xOnly
+		u.Upsbt.Inputs[i].TaprootBip32Derivation = []*psbt.TaprootBip32Derivation{{
+			XOnlyPubKey:          xOnly,
+			MasterKeyFingerprint: fingerprint,
+			Bip32Path:            path,
+		}}
+		return nil
+	case P2SHP2WPKH:
+		redeemScript, err := p2wpkhScript(in.PubKey)
+		if err != nil {
+			return err
+		}
+		if err := u.AddInRedeemScript(redeemScript, i); err != nil {
+			return err
+		}
+	}
+	return u.AddInBip32Derivation(fingerprint, path, in.PubKey, i)
+}
+
+func addOutputInfo(u *psbt.Updater, i int, fingerprint uint32, out BTCOutput) error {
+	if out.Timelock != nil {
+		return addTimelockOutputInfo(u, i, out.Timelock)
+	}
+	if out.AddressType == P2WSH {
+		if err := u.AddOutWitnessScript(out.WitnessScript, i); err != nil {
+			return err
+		}
+		for _, o := range out.KeyOrigins {
+			path, err := parseDerivationPath(o.Path)
+			if err != nil {
+				return err
+			}
+			if err := u.AddOutBip32Derivation(o.Fingerprint, path, o.PubKey, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path, err := parseDerivationPath(out.Path)
+	if err != nil {
+		return err
+	}
+	switch out.AddressType {
+	case P2TR:
+		xOnly := out.PubKey[1:]
+		u.Upsbt.Outputs[i].TaprootInternalKey = xOnly
+		u.Upsbt.Outputs[i].TaprootBip32Derivation = []*psbt.TaprootBip32Derivation{{
+			XOnlyPubKey:          xOnly,
+			MasterKeyFingerprint: fingerprint,
+			Bip32Path:            path,
+		}}
+		return nil
+	case P2SHP2WPKH:
+		redeemScript, err := p2wpkhScript(out.PubKey)
+		if err != nil {
+			return err
+		}
+		if err := u.AddOutRedeemScript(redeemScript, i); err != nil {
+			return err
+		}
+	}
+	return u.AddOutBip32Derivation(fingerprint, path, out.PubKey, i)
+}
+
+// p2wpkhScript OP_0 <hash160(pubkey)>
+func p2wpkhScript(pubKey []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).
+		AddData(btcutil.Hash160(pubKey)).
+		Script()
+}
+
+// inputUtxo 取出输入花费的前序输出
+func inputUtxo(p *psbt.Packet, i int) (*wire.TxOut, error) {
+	in := p.Inputs[i]
+	if in.WitnessUtxo != nil {
+		return in.WitnessUtxo, nil
+	}
+	if in.NonWitnessUtxo != nil {
+		idx := p.UnsignedTx.TxIn[i].PreviousOutPoint.Index
+		if int(idx) >= len(in.NonWitnessUtxo.TxOut) {
+			return nil, errors.New("previous output index out of range")
+		}
+		return in.NonWitnessUtxo.TxOut[idx], nil
+	}
+	return nil, fmt.Errorf("input %d has no utxo information", i)
+}
+
+// SignPSBT 用 seed 派生的密钥签署 PSBT 中所有属于本钱包 (fingerprint 匹配) 的输入，
+// 返回签名的输入个数
+func (b *BTCChain) SignPSBT(p *psbt.Packet, seed []byte) (int, error) {
+	master, err := hdkeychain.NewMaster(seed, b.netParams())
+	if err != nil {
+		return 0, err
+	}
+	fingerprint, err := MasterFingerprint(master)
+	if err != nil {
+		return 0, err
+	}
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, txIn := range p.UnsignedTx.TxIn {
+		utxo, err := inputUtxo(p, i)
+		if err != nil {
+			return 0, err
+		}
+		prevOuts.AddPrevOut(txIn.PreviousOutPoint, utxo)
+	}
+	sigHashes := txscript.NewTxSigHashes(p.UnsignedTx, prevOuts)
+
+	u, err := psbt.NewUpdater(p)
+	if err != nil {
+		return 0, err
+	}
+
+	signed := 0
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.FinalScriptSig != nil || in.FinalScriptWitness != nil {
+			continue
+		}
+		utxo := prevOuts.FetchPrevOutput(p.UnsignedTx.TxIn[i].PreviousOutPoint)
+
+		// taproot key path
+		for _, d := range in.TaprootBip32Derivation {
+			if d.MasterKeyFingerprint != fingerprint || len(d.LeafHashes) > 0 || in.TaprootKeySpendSig != nil {
+				continue
+			}
+			key, err := deriveFromPath(master, d.Bip32Path)
+			if err != nil {
+				return signed, err
+			}
+			if !bytes.Equal(schnorr.SerializePubKey(key.PubKey()), d.XOnlyPubKey) {
+				key.Zero()
+				return signed, fmt.Errorf("input %d: derived key does not match psbt", i)
+			}
+			sig, err := txscript.RawTxInTaprootSignature(
+				p.UnsignedTx, sigHashes, i, utxo.Value, utxo.PkScript,
+				in.TaprootMerkleRoot, txscript.SigHashDefault, key,
+			)
+			key.Zero()
+			if err != nil {
+				return signed, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "RawTxInTaprootSignature", err)
+			}
+			in.TaprootKeySpendSig = sig
+			signed++
+		}
+
+		// taproot script path，只签 PSBT 里带了 leaf script 的分支
+		for _, d := range in.TaprootBip32Derivation {
+			if d.MasterKeyFingerprint != fingerprint {
+				continue
+			}
+			for _, leafHash := range d.LeafHashes {
+				if hasTapScriptSig(in, d.XOnlyPubKey, leafHash) {
+					continue
+				}
+				leaf, err := psbt.FindLeafScript(in, leafHash)
+				if err != nil {
+					continue
+				}
+				key, err := deriveFromPath(master, d.Bip32Path)
+				if err != nil {
+					return signed, err
+				}
+				if !bytes.Equal(schnorr.SerializePubKey(key.PubKey()), d.XOnlyPubKey) {
+					key.Zero()
+					return signed, fmt.Errorf("input %d: derived key does not match psbt", i)
+				}
+				sig, err := txscript.RawTxInTapscriptSignature(
+					p.UnsignedTx, sigHashes, i, utxo.Value, utxo.PkScript,
+					txscript.NewTapLeaf(leaf.LeafVersion, leaf.Script), txscript.SigHashDefault, key,
+				)
+				key.Zero()
+				if err != nil {
+					return signed, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "RawTxInTapscriptSignature", err)
+				}
+				in.TaprootScriptSpendSig = append(in.TaprootScriptSpendSig, &psbt.TaprootScriptSpendSig{
+					XOnlyPubKey: d.XOnlyPubKey,
+					LeafHash:    leafHash,
+					Signature:   sig,
+					SigHash:     txscript.SigHashDefault,
+				})
+				signed++
+			}
+		}
+
+		// legacy / segwit v0
+		for _, d := range in.Bip32Derivation {
+			if d.MasterKeyFingerprint != fingerprint || hasPartialSig(in, d.PubKey) {
+				continue
+			}
+			key, err := deriveFromPath(master, d.Bip32Path)
+			if err != nil {
+				return signed, err
+			}
+			if !bytes.Equal(key.PubKey().SerializeCompressed(), d.PubKey) {
+				key.Zero()
+				return signed, fmt.Errorf("input %d: derived key does not match psbt", i)
+			}
+			sig, err := signV0Input(p, i, utxo, sigHashes, key)
+			key.Zero()
+			if err != nil {
+				return signed, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "SignPSBT", err)
+			}
+			if _, err := u.Sign(i, sig, d.PubKey, nil, nil); err != nil {
+				return signed, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "psbt Sign", err)
+			}
+			signed++
+		}
+	}
+	return signed, nil
+}
+
+// signV0Input 对 legacy / segwit v0 输入签名，脚本按 witnessScript > redeemScript > pkScript 选取
+func signV0Input(p *psbt.Packet, i int, utxo *wire.TxOut, sigHashes *txscript.TxSigHashes, key *btcec.PrivateKey) ([]byte, error) {
+	in := p.Inputs[i]
+	script := utxo.PkScript
+	if in.RedeemScript != nil {
+		script = in.RedeemScript
+	}
+	if in.WitnessScript != nil {
+		script = in.WitnessScript
+	}
+
+	if in.WitnessScript == nil && !txscript.IsWitnessProgram(script) {
+		return txscript.RawTxInSignature(p.UnsignedTx, i, script, txscript.SigHashAll, key)
+	}
+	return txscript.RawTxInWitnessSignature(p.UnsignedTx, sigHashes, i, utxo.Value, script, txscript.SigHashAll, key)
+}
+
+func hasPartialSig(in *psbt.PInput, pubKey []byte) bool {
+	for _, s := range in.PartialSigs {
+		if bytes.Equal(s.PubKey, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTapScriptSig(in *psbt.PInput, xOnly, leafHash []byte) bool {
+	for _, s := range in.TaprootScriptSpendSig {
+		if bytes.Equal(s.XOnlyPubKey, xOnly) && bytes.Equal(s.LeafHash, leafHash) {
+			return true
+		}
+	}
+	return false
+}
+
+// addTimelockInputInfo 时间锁输入只写要走的分支的 key，签名方据此决定签哪个分支
+func addTimelockInputInfo(u *psbt.Updater, i int, in BTCInput) error {
+	t := in.Timelock
+	if err := u.AddInWitnessUtxo(wire.NewTxOut(in.Value, in.PkScript), i); err != nil {
+		return err
+	}
+	origin := t.Primary
+	if in.Recovery {
+		origin = t.Recovery
+	}
+	path, err := parseDerivationPath(origin.Path)
+	if err != nil {
+		return err
+	}
+
+	if t.WitnessScript != nil {
+		if err := u.AddInWitnessScript(t.WitnessScript, i); err != nil {
+			return err
+		}
+		return u.AddInBip32Derivation(origin.Fingerprint, path, origin.PubKey, i)
+	}
+
+	pin := &u.Upsbt.Inputs[i]
+	pin.TaprootInternalKey = t.Primary.PubKey[1:]
+	pin.TaprootMerkleRoot = t.MerkleRoot
+	derivation := &psbt.TaprootBip32Derivation{
+		XOnlyPubKey:          origin.PubKey[1:],
+		MasterKeyFingerprint: origin.Fingerprint,
+		Bip32Path:            path,
+	}
+	if in.Recovery {
+		leafHash := txscript.NewBaseTapLeaf(t.LeafScript).TapHash()
+		derivation.LeafHashes = [][]byte{leafHash[:]}
+		pin.TaprootLeafScript = []*psbt.TaprootTapLeafScript{{
+			ControlBlock: t.ControlBlock,
+			Script:       t.LeafScript,
+			LeafVersion:  txscript.BaseLeafVersion,
+		}}
+	}
+	pin.TaprootBip32Derivation = []*psbt.TaprootBip32Derivation{derivation}
+	return nil
+}
+
+// addTimelockOutputInfo 时间锁找零输出带上两把 key 的来源
+func addTimelockOutputInfo(u *psbt.Updater, i int, t *BTCTimelockAddress) error {
+	primaryPath, err := parseDerivationPath(t.Primary.Path)
+	if err != nil {
+		return err
+	}
+	recoveryPath, err := parseDerivationPath(t.Recovery.Path)
+	if err != nil {
+		return err
+	}
+
+	if t.WitnessScript != nil {
+		if err := u.AddOutWitnessScript(t.WitnessScript, i); err != nil {
+			return err
+		}
+		if err := u.AddOutBip32Derivation(t.Primary.Fingerprint, primaryPath, t.Primary.PubKey, i); err != nil {
+			return err
+		}
+		return u.AddOutBip32Derivation(t.Recovery.Fingerprint, recoveryPath, t.Recovery.PubKey, i)
+	}
+
+	leafHash := txscript.NewBaseTapLeaf(t.LeafScript).TapHash()
+	pout := &u.Upsbt.Outputs[i]
+	pout.TaprootInternalKey = t.Primary.PubKey[1:]
+	pout.TaprootBip32Derivation = []*psbt.TaprootBip32Derivation{{
+		XOnlyPubKey:          t.Primary.PubKey[1:],
+		MasterKeyFingerprint: t.Primary.Fingerprint,
+		Bip32Path:            primaryPath,
+	}, {
+		XOnlyPubKey:          t.Recovery.PubKey[1:],
+		LeafHashes:           [][]byte{leafHash[:]},
+		MasterKeyFingerprint: t.Recovery.Fingerprint,
+		Bip32Path:            recoveryPath,
+	}}
+	return nil
+}
+
+func deriveFromPath(master *hdkeychain.ExtendedKey, path []uint32) (*btcec.PrivateKey, error) {
+	key := master
+	var err error
+	for _, idx := range path {
+		key, err = key.Derive(idx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key.ECPrivKey()
+}
+
+// FinalizePSBT 完成所有输入并提取出可广播的交易
+func FinalizePSBT(p *psbt.Packet) (*wire.MsgTx, error) {
+	if err := finalizeTimelockInputs(p); err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "finalize psbt", err)
+	}
+	if err := psbt.MaybeFinalizeAll(p); err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "finalize psbt", err)
+	}
+	return psbt.Extract(p)
+}
+
+// Broadcast 广播已签名交易，返回 txid
+func (b *BTCChain) Broadcast(ctx context.Context, tx *wire.MsgTx) (string, error) {
+	backend, err := b.backend()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return "", err
+	}
+	txid, err := backend.Broadcast(ctx, buf.Bytes())
+	if err != nil {
+		return "", wrapErrors.WrapWithCode(wrapErrors.SendTxErr, "btc broadcast", err)
+	}
+	return txid, nil
+}
+
+// GetRawTransaction 查询前序交易；height 为 0 表示未确认或未知
+func (b *BTCChain) GetRawTransaction(ctx context.Context, txid string, height int64) (*wire.MsgTx, error) {
+	backend, err := b.backend()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := backend.GetRawTransaction(ctx, txid, height)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -3,8 +3,10 @@ package chain
 type ChainType string
 
 const (
-	BTC ChainType = "btc"
-	ETH ChainType = "eth"
+	BTC  ChainType = "btc"
+	ETH  ChainType = "eth"
+	LTC  ChainType = "ltc"  // 复用 BTCChain 的 UTXO 流程
+	DOGE ChainType = "doge" // 复用 BTCChain 的 UTXO 流程，只支持 P2PKH
 )
 
 // WalletChain 定义统一接口
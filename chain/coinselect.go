@@ -0,0 +1,339 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// 选币策略
+const (
+	CoinSelectBnB          = "bnb"           // branch-and-bound，优先寻找无找零的组合，失败时退回 largest_first
+	CoinSelectLargestFirst = "largest_first" // 金额从大到小累加
+	CoinSelectOldestFirst  = "oldest_first"  // 确认高度从低到高累加，未确认的排在最后
+	CoinSelectAvoidMixing  = "avoid_mixing"  // 按地址分组整组花费，尽量不把多个地址的币放进同一笔交易
+)
+
+// bnbMaxTries branch-and-bound 最多搜索的节点数
+const bnbMaxTries = 100_000
+
+// ErrInsufficientFunds 可用 UTXO 不足以支付金额 + 手续费
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Coin 选币候选
+type Coin struct {
+	TxID        string
+	Vout        uint32
+	Value       int64 // satoshi
+	Address     string
+	AddressType BTCAddressType
+	Height      int64 // 0 表示未确认
+	Frozen      bool  // 被冻结的币任何策略都不会选
+	InputVSize  int64 // 非 0 时覆盖按地址类型估算的输入 vsize (multisig)
+}
+
+// vsize 花费该币的输入 vsize
+func (c Coin) vsize() int64 {
+	if c.InputVSize > 0 {
+		return c.InputVSize
+	}
+	return inputVSize(c.AddressType)
+}
+
+// EstimateCoinsVSize 按选中的币估算交易 vsize
+func EstimateCoinsVSize(coins []Coin, outputs [][]byte) int64 {
+	sizes := make([]int64, len(coins))
+	segwit := false
+	for i, c := range coins {
+		sizes[i] = c.vsize()
+		segwit = segwit || c.AddressType != P2PKH
+	}
+	return estimateVSize(sizes, segwit, outputs)
+}
+
+// CoinSelectionParams 选币参数
+type CoinSelectionParams struct {
+	Target        int64    // 发送总额 (不含手续费)
+	FeeRate       int64    // sat/vB
+	OutputScripts [][]byte // 收款输出脚本 (不含找零)
+	ChangeScript  []byte   // 找零输出脚本
+	DustLimit     int64    // 找零低于该值时并入手续费
+	// 找零地址类型，BnB 按它估算将来花掉找零的成本；ChangeInputVSize 非 0 时覆盖 (multisig / 时间锁)
+	ChangeType       BTCAddressType
+	ChangeInputVSize int64
+}
+
+// changeInputVSize 将来花掉找零输出的输入 vsize
+func (p CoinSelectionParams) changeInputVSize() int64 {
+	if p.ChangeInputVSize > 0 {
+		return p.ChangeInputVSize
+	}
+	return inputVSize(p.ChangeType)
+}
+
+// CoinSelection 选币结果，Change 为 0 表示没有找零输出
+type CoinSelection struct {
+	Coins  []Coin
+	Change int64
+	Fee    int64
+}
+
+// CoinSelector 选币策略接口，相同输入必须得到相同结果，且不能选中 Frozen 的币
+type CoinSelector interface {
+	Select(coins []Coin, params CoinSelectionParams) (*CoinSelection, error)
+}
+
+// NewCoinSelector 按名字返回选币策略，空字符串使用 bnb
+func NewCoinSelector(strategy string) (CoinSelector, error) {
+	switch strategy {
+	case "", CoinSelectBnB:
+		return BnBSelector{}, nil
+	case CoinSelectLargestFirst:
+		return LargestFirstSelector{}, nil
+	case CoinSelectOldestFirst:
+		return OldestFirstSelector{}, nil
+	case CoinSelectAvoidMixing:
+		return AvoidMixingSelector{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported coin selection strategy: %s", strategy)
+	}
+}
+
+// finishSelection 计算选中币的手续费和找零；钱不够时返回 false
+func finishSelection(coins []Coin, params CoinSelectionParams) (*CoinSelection, bool) {
+	var total int64
+	for _, c := range coins {
+		total += c.Value
+	}
+
+	dust := params.DustLimit
+	if dust <= 0 {
+		dust = DustLimit
+	}
+
+	withChange := append(append([][]byte{}, params.OutputScripts...), params.ChangeScript)
+	feeWithChange := params.FeeRate * EstimateCoinsVSize(coins, withChange)
+	if change := total - params.Target - feeWithChange; change >= dust {
+		return &CoinSelection{Coins: coins, Change: change, Fee: feeWithChange}, true
+	}
+
+	feeNoChange := params.FeeRate * EstimateCoinsVSize(coins, params.OutputScripts)
+	if total >= params.Target+feeNoChange {
+		// 多出来的不足 dust，全部给矿工
+		return &CoinSelection{Coins: coins, Fee: total - params.Target}, true
+	}
+	return nil, false
+}
+
+// accumulate 按给定顺序累加，直到够付金额 + 手续费
+func accumulate(coins []Coin, params CoinSelectionParams) (*CoinSelection, error) {
+	var (
+		selected []Coin
+		total    int64
+	)
+	for _, c := range coins {
+		selected = append(selected, c)
+		total += c.Value
+		if res, ok := finishSelection(selected, params); ok {
+			return res, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: have %d sat, need %d sat plus fee", ErrInsufficientFunds, total, params.Target)
+}
+
+// lessOutpoint 金额/高度相同时按 outpoint 排序，保证结果确定
+func lessOutpoint(a, b Coin) bool {
+	if a.TxID != b.TxID {
+		return a.TxID < b.TxID
+	}
+	return a.Vout < b.Vout
+}
+
+func sortedCoins(coins []Coin, less func(a, b Coin) bool) []Coin {
+	out := append([]Coin{}, coins...)
+	sort.SliceStable(out, func(i, j int) bool { return less(out[i], out[j]) })
+	return out
+}
+
+func byValueDesc(a, b Coin) bool {
+	if a.Value != b.Value {
+		return a.Value > b.Value
+	}
+	return lessOutpoint(a, b)
+}
+
+// spendable 去掉被冻结的币
+func spendable(coins []Coin) []Coin {
+	out := make([]Coin, 0, len(coins))
+	for _, c := range coins {
+		if !c.Frozen {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// LargestFirstSelector 金额从大到小选币，输入个数最少
+type LargestFirstSelector struct{}
+
+func (LargestFirstSelector) Select(coins []Coin, params CoinSelectionParams) (*CoinSelection, error) {
+	return accumulate(sortedCoins(spendable(coins), byValueDesc), params)
+}
+
+// OldestFirstSelector 先花确认最早的币，未确认的排在最后
+type OldestFirstSelector struct{}
+
+func (OldestFirstSelector) Select(coins []Coin, params CoinSelectionParams) (*CoinSelection, error) {
+	return accumulate(sortedCoins(spendable(coins), func(a, b Coin) bool {
+		ha, hb := a.Height, b.Height
+		if ha == 0 {
+			ha = 1<<63 - 1
+		}
+		if hb == 0 {
+			hb = 1<<63 - 1
+		}
+		if ha != hb {
+			return ha < hb
+		}
+		return lessOutpoint(a, b)
+	}), params)
+}
+
+// AvoidMixingSelector 把同一地址上的币作为一组整体花费：
+// 优先用单个地址即可支付的最小一组；都不够时按组总额从大到小合并，尽量少混合地址
+type AvoidMixingSelector struct{}
+
+func (AvoidMixingSelector) Select(coins []Coin, params CoinSelectionParams) (*CoinSelection, error) {
+	type group struct {
+		address string
+		coins   []Coin
+		total   int64
+	}
+	byAddr := make(map[string]*group)
+	var groups []*group
+	for _, c := range sortedCoins(spendable(coins), lessOutpoint) {
+		g, ok := byAddr[c.Address]
+		if !ok {
+			g = &group{address: c.Address}
+			byAddr[c.Address] = g
+			groups = append(groups, g)
+		}
+		g.coins = append(g.coins, c)
+		g.total += c.Value
+	}
+
+	// 单个地址就够：选总额最小的那一组
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].total != groups[j].total {
+			return groups[i].total < groups[j].total
+		}
+		return groups[i].address < groups[j].address
+	})
+	for _, g := range groups {
+		if res, ok := finishSelection(g.coins, params); ok {
+			return res, nil
+		}
+	}
+
+	// 需要多个地址：组总额从大到小合并
+	var selected []Coin
+	var total int64
+	for i := len(groups) - 1; i >= 0; i-- {
+		selected = append(selected, groups[i].coins...)
+		total += groups[i].total
+		if res, ok := finishSelection(selected, params); ok {
+			return res, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: have %d sat, need %d sat plus fee", ErrInsufficientFunds, total, params.Target)
+}
+
+// BnBSelector branch-and-bound (Murch)，按有效金额 (金额 - 花费该输入的手续费)
+// 搜索落在 [target, target + 找零成本] 区间的组合，这样可以不要找零输出；
+// 多个解时取浪费最少的，找不到时退回 largest_first
+type BnBSelector struct{}
+
+func (BnBSelector) Select(coins []Coin, params CoinSelectionParams) (*CoinSelection, error) {
+	coins = spendable(coins)
+	if res := bnbSearch(coins, params); res != nil {
+		return res, nil
+	}
+	return LargestFirstSelector{}.Select(coins, params)
+}
+
+func bnbSearch(coins []Coin, params CoinSelectionParams) *CoinSelection {
+	type candidate struct {
+		coin      Coin
+		effective int64
+	}
+
+	var pool []candidate
+	for _, c := range sortedCoins(coins, byValueDesc) {
+		eff := c.Value - params.FeeRate*c.vsize()
+		if eff > 0 {
+			pool = append(pool, candidate{coin: c, effective: eff})
+		}
+	}
+	sort.SliceStable(pool, func(i, j int) bool { return pool[i].effective > pool[j].effective })
+
+	// 不含输入的基础交易手续费；segwit marker 的 1 vB 忽略，选出后再按精确 vsize 复核
+	target := params.Target + params.FeeRate*EstimateVSize(nil, params.OutputScripts)
+	// 找零成本 = 找零输出的手续费 + 将来花掉它的手续费 (按找零地址类型估算)
+	costOfChange := params.FeeRate * (outputVSize(params.ChangeScript) + params.changeInputVSize())
+
+	remaining := make([]int64, len(pool)+1)
+	for i := len(pool) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + pool[i].effective
+	}
+	if remaining[0] < target {
+		return nil
+	}
+
+	var (
+		best      []int
+		bestWaste int64 = -1
+		current   []int
+		tries     int
+	)
+	var search func(depth int, value int64)
+	search = func(depth int, value int64) {
+		tries++
+		if tries > bnbMaxTries {
+			return
+		}
+		if value > target+costOfChange {
+			return
+		}
+		if value >= target {
+			if waste := value - target; bestWaste < 0 || waste < bestWaste {
+				bestWaste = waste
+				best = append([]int{}, current...)
+			}
+			return
+		}
+		if depth >= len(pool) || value+remaining[depth] < target {
+			return
+		}
+		// 先尝试包含该币，再尝试跳过
+		current = append(current, depth)
+		search(depth+1, value+pool[depth].effective)
+		current = current[:len(current)-1]
+		search(depth+1, value)
+	}
+	search(0, 0)
+
+	if best == nil {
+		return nil
+	}
+	selected := make([]Coin, len(best))
+	var total int64
+	for i, idx := range best {
+		selected[i] = pool[idx].coin
+		total += pool[idx].coin.Value
+	}
+	// 复核精确手续费，多出的部分不足找零成本，直接给矿工
+	if total < params.Target+params.FeeRate*EstimateCoinsVSize(selected, params.OutputScripts) {
+		return nil
+	}
+	return &CoinSelection{Coins: selected, Fee: total - params.Target}
+}
@@ -0,0 +1,228 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// p2wpkh 输出脚本: OP_0 + 20 字节
+var (
+	testPayScript    = append([]byte{0x00, 0x14}, bytes.Repeat([]byte{0x01}, 20)...)
+	testChangeScript = append([]byte{0x00, 0x14}, bytes.Repeat([]byte{0x02}, 20)...)
+)
+
+func testCoin(txid string, value int64, address string, height int64) Coin {
+	return Coin{TxID: txid, Value: value, Address: address, AddressType: P2WPKH, Height: height}
+}
+
+func TestCoinSelectors(t *testing.T) {
+	// feeRate 1: 1 个 p2wpkh 输入 + 1 个输出 110 vB，加找零 141 vB；2 个输入 178 / 209 vB
+	tests := []struct {
+		name     string
+		strategy string
+		coins    []Coin
+		target   int64
+		want     []string // 选中币的 txid，按选中顺序
+		change   int64
+		fee      int64
+		err      error
+	}{
+		{
+			name:     "bnb exact single coin",
+			strategy: CoinSelectBnB,
+			coins:    []Coin{testCoin("a", 50000, "x", 1), testCoin("b", 10110, "x", 1), testCoin("c", 3000, "x", 1)},
+			target:   10000,
+			want:     []string{"b"},
+			fee:      110,
+		},
+		{
+			name:     "bnb exact combination without change",
+			strategy: CoinSelectBnB,
+			coins:    []Coin{testCoin("a", 20000, "x", 1), testCoin("b", 7000, "x", 1), testCoin("c", 5000, "x", 1)},
+			target:   11800,
+			want:     []string{"b", "c"},
+			fee:      200,
+		},
+		{
+			name:     "bnb falls back to largest_first with change",
+			strategy: CoinSelectBnB,
+			coins:    []Coin{testCoin("a", 30000, "x", 1), testCoin("b", 50000, "x", 1)},
+			target:   20000,
+			want:     []string{"b"},
+			change:   29859,
+			fee:      141,
+		},
+		{
+			name:     "largest_first change exactly at dust limit",
+			strategy: CoinSelectLargestFirst,
+			coins:    []Coin{testCoin("a", 10000+141+546, "x", 1)},
+			target:   10000,
+			want:     []string{"a"},
+			change:   546,
+			fee:      141,
+		},
+		{
+			name:     "largest_first change below dust goes to fee",
+			strategy: CoinSelectLargestFirst,
+			coins:    []Coin{testCoin("a", 10000+141+545, "x", 1)},
+			target:   10000,
+			want:     []string{"a"},
+			fee:      686,
+		},
+		{
+			name:     "largest_first accumulates",
+			strategy: CoinSelectLargestFirst,
+			coins:    []Coin{testCoin("a", 4000, "x", 1), testCoin("b", 9000, "x", 1), testCoin("c", 6000, "x", 1)},
+			target:   12000,
+			want:     []string{"b", "c"},
+			change:   2791,
+			fee:      209,
+		},
+		{
+			name:     "frozen coins are never selected",
+			strategy: CoinSelectLargestFirst,
+			coins:    []Coin{{TxID: "a", Value: 90000, AddressType: P2WPKH, Frozen: true}, testCoin("b", 20000, "x", 1)},
+			target:   10000,
+			want:     []string{"b"},
+			change:   9859,
+			fee:      141,
+		},
+		{
+			name:     "insufficient funds",
+			strategy: CoinSelectLargestFirst,
+			coins:    []Coin{testCoin("a", 10000, "x", 1)},
+			target:   10000,
+			err:      ErrInsufficientFunds,
+		},
+		{
+			name:     "oldest_first puts unconfirmed last",
+			strategy: CoinSelectOldestFirst,
+			coins:    []Coin{testCoin("a", 90000, "x", 0), testCoin("b", 20000, "x", 100), testCoin("c", 20000, "x", 50)},
+			target:   10000,
+			want:     []string{"c"},
+			change:   9859,
+			fee:      141,
+		},
+		{
+			name:     "avoid_mixing uses the smallest sufficient address",
+			strategy: CoinSelectAvoidMixing,
+			coins:    []Coin{testCoin("a1", 3000, "a", 1), testCoin("a2", 3000, "a", 1), testCoin("b1", 8000, "b", 1)},
+			target:   5000,
+			want:     []string{"a1", "a2"},
+			change:   791,
+			fee:      209,
+		},
+		{
+			name:     "avoid_mixing merges largest addresses first",
+			strategy: CoinSelectAvoidMixing,
+			coins:    []Coin{testCoin("a1", 3000, "a", 1), testCoin("a2", 3000, "a", 1), testCoin("b1", 8000, "b", 1), testCoin("c1", 1000, "c", 1)},
+			target:   12000,
+			want:     []string{"b1", "a1", "a2"},
+			change:   1723,
+			fee:      277,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := NewCoinSelector(tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params := CoinSelectionParams{
+				Target:        tt.target,
+				FeeRate:       1,
+				OutputScripts: [][]byte{testPayScript},
+				ChangeScript:  testChangeScript,
+				DustLimit:     546,
+				ChangeType:    P2WPKH,
+			}
+			res, err := selector.Select(tt.coins, params)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSelection(t, res, tt.want, tt.change, tt.fee)
+
+			// 结果不依赖输入顺序
+			reversed := make([]Coin, len(tt.coins))
+			for i, c := range tt.coins {
+				reversed[len(tt.coins)-1-i] = c
+			}
+			again, err := selector.Select(reversed, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSelection(t, again, tt.want, tt.change, tt.fee)
+		})
+	}
+}
+
+func TestBnBCostOfChange(t *testing.T) {
+	// 多出 150 sat：超过 p2wpkh 找零的成本 (31 + 68 vB)，但不到 p2pkh 找零的成本 (31 + 148 vB)
+	coins := []Coin{testCoin("a", 10_000+110+150, "addr1", 1)}
+	tests := []struct {
+		changeType BTCAddressType
+		change     int64
+		fee        int64
+	}{
+		{changeType: P2WPKH, change: 119, fee: 141},
+		{changeType: P2PKH, change: 0, fee: 260},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.changeType), func(t *testing.T) {
+			selector, err := NewCoinSelector(CoinSelectBnB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := selector.Select(coins, CoinSelectionParams{
+				Target:        10_000,
+				FeeRate:       1,
+				OutputScripts: [][]byte{testPayScript},
+				ChangeScript:  testChangeScript,
+				DustLimit:     1,
+				ChangeType:    tt.changeType,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSelection(t, res, []string{"a"}, tt.change, tt.fee)
+		})
+	}
+}
+
+func checkSelection(t *testing.T, res *CoinSelection, want []string, change, fee int64) {
+	t.Helper()
+	var got []string
+	for _, c := range res.Coins {
+		got = append(got, c.TxID)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("coins = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("coins = %v, want %v", got, want)
+		}
+	}
+	if res.Change != change || res.Fee != fee {
+		t.Fatalf("change, fee = %d, %d, want %d, %d", res.Change, res.Fee, change, fee)
+	}
+}
+
+func TestNewCoinSelector(t *testing.T) {
+	for _, s := range []string{"", CoinSelectBnB, CoinSelectLargestFirst, CoinSelectOldestFirst, CoinSelectAvoidMixing} {
+		if _, err := NewCoinSelector(s); err != nil {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+	if _, err := NewCoinSelector("random"); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+}
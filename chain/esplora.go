@@ -0,0 +1,210 @@
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	wrapErrors "github.com/linlinbupt123-crypto/wallet_service/errors"
+)
+
+// errEsploraNotFound 404，交易不在 mempool 也不在链上
+var errEsploraNotFound = errors.New("not found")
+
+// EsploraBackend 通过 Esplora REST API (blockstream.info / mempool.space) 查询链上数据
+type EsploraBackend struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewEsploraBackend(baseURL string) *EsploraBackend {
+	return &EsploraBackend{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do 发送请求并返回 body，非 2xx 作为错误返回
+func (e *EsploraBackend) do(req *http.Request) ([]byte, error) {
+	op := "esplora " + req.Method + " " + req.URL.Path
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, op, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, op,
+			fmt.Errorf("%w: %s", errEsploraNotFound, strings.TrimSpace(string(body))))
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, op,
+			fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+	return body, nil
+}
+
+// get 请求 path 并返回 body
+func (e *EsploraBackend) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.do(req)
+}
+
+func (e *EsploraBackend) BlockHeight(ctx context.Context) (int64, error) {
+	body, err := e.get(ctx, "/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+}
+
+type esploraUTXO struct {
+	TxID   string `json:"txid"`
+	Vout   uint32 `json:"vout"`
+	Value  int64  `json:"value"`
+	Status struct {
+		Confirmed   bool  `json:"confirmed"`
+		BlockHeight int64 `json:"block_height"`
+	} `json:"status"`
+}
+
+func (e *EsploraBackend) ListUnspent(ctx context.Context, addresses []string) ([]BTCUnspent, error) {
+	var out []BTCUnspent
+	for _, addr := range addresses {
+		body, err := e.get(ctx, "/address/"+addr+"/utxo")
+		if err != nil {
+			return nil, err
+		}
+		var utxos []esploraUTXO
+		if err := json.Unmarshal(body, &utxos); err != nil {
+			return nil, err
+		}
+		for _, u := range utxos {
+			var height int64
+			if u.Status.Confirmed {
+				height = u.Status.BlockHeight
+			}
+			out = append(out, BTCUnspent{
+				TxID:    u.TxID,
+				Vout:    u.Vout,
+				Address: addr,
+				Value:   u.Value,
+				Height:  height,
+			})
+		}
+	}
+	return out, nil
+}
+
+func (e *EsploraBackend) IsSpent(ctx context.Context, txid string, vout uint32) (bool, error) {
+	body, err := e.get(ctx, fmt.Sprintf("/tx/%s/outspend/%d", txid, vout))
+	if err != nil {
+		return false, err
+	}
+	var res struct {
+		Spent bool `json:"spent"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return false, err
+	}
+	return res.Spent, nil
+}
+
+func (e *EsploraBackend) SpendingTxID(ctx context.Context, txid string, vout uint32) (string, error) {
+	body, err := e.get(ctx, fmt.Sprintf("/tx/%s/outspend/%d", txid, vout))
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		Spent bool   `json:"spent"`
+		TxID  string `json:"txid"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	if !res.Spent {
+		return "", nil
+	}
+	return res.TxID, nil
+}
+
+func (e *EsploraBackend) TxHeight(ctx context.Context, txid string, _ int) (int64, bool, error) {
+	body, err := e.get(ctx, "/tx/"+txid+"/status")
+	if errors.Is(err, errEsploraNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	var res struct {
+		Confirmed   bool  `json:"confirmed"`
+		BlockHeight int64 `json:"block_height"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return 0, false, err
+	}
+	if !res.Confirmed {
+		return 0, true, nil
+	}
+	return res.BlockHeight, true, nil
+}
+
+func (e *EsploraBackend) GetRawTransaction(ctx context.Context, txid string, _ int64) ([]byte, error) {
+	body, err := e.get(ctx, "/tx/"+txid+"/hex")
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(body)))
+}
+
+func (e *EsploraBackend) Broadcast(ctx context.Context, rawTx []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/tx",
+		strings.NewReader(hex.EncodeToString(rawTx)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	body, err := e.do(req)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func (e *EsploraBackend) EstimateFeeRate(ctx context.Context, targetBlocks int) (float64, error) {
+	body, err := e.get(ctx, "/fee-estimates")
+	if err != nil {
+		return 0, err
+	}
+	// {"1": 87.882, "2": 87.882, ..., "144": 1.027}
+	var estimates map[string]float64
+	if err := json.Unmarshal(body, &estimates); err != nil {
+		return 0, err
+	}
+	// 取不超过 targetBlocks 的最大目标
+	bestTarget, rate := 0, 0.0
+	for k, v := range estimates {
+		t, err := strconv.Atoi(k)
+		if err != nil || t > targetBlocks {
+			continue
+		}
+		if t > bestTarget {
+			bestTarget, rate = t, v
+		}
+	}
+	return rate, nil
+}
@@ -3,86 +3,308 @@ package chain
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
+	"fmt"
 	"math/big"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	ethparams "github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/linlinbupt123-crypto/wallet_service/config"
 	wrapErrors "github.com/linlinbupt123-crypto/wallet_service/errors"
 )
 
 type ETHChain struct {
-	Rpc       string
+	Clients   *ETHClientPool
 	ChainID   *big.Int
 	TestToken string
 	MainNet   bool
+	GasMargin uint64 // EstimateGas 结果上加的余量，百分比
+
+	ReplacementBump uint64        // 替换 pending 交易时费用至少上调的百分比，不低于节点的 price bump
+	Confirmations   uint64        // 达到该确认数后不再跟踪回执
+	DropAfter       time.Duration // 节点上找不到交易超过该时间视为 dropped
+	StuckAfter      time.Duration // 广播后超过该时间还没上链视为卡住
+
+	NFTCollections []NFTCollection // 需要索引持仓的 NFT 合约
 }
 
 func NewETHChain(cfg config.EthConfig) *ETHChain {
+	if cfg.ReplacementBump == 0 {
+		cfg.ReplacementBump = DefaultReplacementBump
+	}
 	return &ETHChain{
-		Rpc:       cfg.RPC,
+		Clients:   NewETHClientPool(cfg),
 		ChainID:   big.NewInt(cfg.ChainID),
 		TestToken: cfg.TestToken,
+		GasMargin: cfg.GasMargin,
+
+		ReplacementBump: cfg.ReplacementBump,
+		Confirmations:   cfg.Confirmations,
+		DropAfter:       time.Duration(cfg.DropAfter) * time.Second,
+		StuckAfter:      time.Duration(cfg.StuckAfter) * time.Second,
+
+		NFTCollections: nftCollections(cfg.NFTCollections),
 	}
 }
 
-func (e *ETHChain) SendETH(ctx context.Context, priv *ecdsa.PrivateKey, to string, amountWei *big.Int) (string, error) {
-	client, err := ethclient.Dial(e.Rpc)
+// ETHSendParams 一笔 ETH 交易的内容
+type ETHSendParams struct {
+	To       string   // 为空时部署合约，Data 为 bytecode + 构造参数
+	Value    *big.Int // wei
+	Data     []byte   // calldata，普通转账为空
+	GasLimit uint64   // 0 表示用 EstimateGas 估算
+	Nonce    *uint64  // nil 表示用节点的 pending nonce
+	SignOnly bool     // 只签名不广播，返回的交易由调用方自行广播
+}
+
+// SendETH 签名并广播，返回已签名的交易 (hash、raw bytes、费用参数都在里面)
+func (e *ETHChain) SendETH(ctx context.Context, priv *ecdsa.PrivateKey, params ETHSendParams) (*types.Transaction, error) {
+	tx, chainID, err := e.PrepareETH(ctx, crypto.PubkeyToAddress(priv.PublicKey).Hex(), params)
 	if err != nil {
-		return "", wrapErrors.WrapWithCode(wrapErrors.DailChain, "eth dial", err)
+		return nil, err
 	}
-	chainID, err := client.NetworkID(ctx)
+	signedTx, err := SignETHTx(priv, tx, chainID)
 	if err != nil {
-		return "", wrapErrors.WrapWithCode(wrapErrors.GetchainIDErr, "get chainID", err)
+		return nil, err
+	}
+	if params.SignOnly {
+		return signedTx, nil
 	}
-	e.ChainID = chainID
 
-	fromAddr := crypto.PubkeyToAddress(priv.PublicKey)
-	nonce, err := client.PendingNonceAt(ctx, fromAddr)
+	// 已签名的交易在任何节点广播结果都一样，失败时可以直接换节点
+	err = e.Clients.Do(ctx, "send tx", func(client *ethclient.Client) error {
+		return client.SendTransaction(ctx, signedTx)
+	})
 	if err != nil {
-		return "", wrapErrors.WrapWithCode(wrapErrors.PendingNonceAt, "PendingNonceAt", err)
+		return nil, wrapErrors.WrapWithCode(wrapErrors.SendTxErr, "SendTransaction", err)
+	}
+
+	return signedTx, nil
+}
+
+// PrepareETH 取 chain id、nonce 和费用，估算 gas，返回未签名的交易；离线签名时交给冷钱包签
+func (e *ETHChain) PrepareETH(ctx context.Context, from string, params ETHSendParams) (*types.Transaction, *big.Int, error) {
+	fromAddr := common.HexToAddress(from)
+	var toAddr *common.Address // nil 为部署合约
+	if params.To != "" {
+		addr := common.HexToAddress(params.To)
+		toAddr = &addr
 	}
 
-	tip, err := client.SuggestGasTipCap(ctx)
+	// 同一个节点上取 chain id、nonce 和费用
+	var tx *types.Transaction
+	chainID := e.ChainID
+	err := e.Clients.Do(ctx, "prepare tx", func(client *ethclient.Client) error {
+		if chainID == nil || chainID.Sign() == 0 {
+			id, err := client.NetworkID(ctx)
+			if err != nil {
+				return wrapErrors.WrapWithCode(wrapErrors.GetchainIDErr, "get chainID", err)
+			}
+			chainID = id
+		}
+
+		var nonce uint64
+		if params.Nonce != nil {
+			nonce = *params.Nonce
+		} else {
+			var err error
+			nonce, err = client.PendingNonceAt(ctx, fromAddr)
+			if err != nil {
+				return wrapErrors.WrapWithCode(wrapErrors.PendingNonceAt, "PendingNonceAt", err)
+			}
+		}
+
+		fees, err := suggestFees(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		msg := ethereum.CallMsg{
+			From:  fromAddr,
+			To:    toAddr,
+			Value: params.Value,
+			Data:  params.Data,
+		}
+		var accessList types.AccessList
+		if !fees.London && len(params.Data) > 0 {
+			accessList = legacyAccessList(ctx, client, msg)
+			msg.AccessList = accessList
+		}
+
+		gas := params.GasLimit
+		if gas == 0 {
+			gas, err = e.estimateGas(ctx, client, msg)
+			if err != nil {
+				return err
+			}
+		}
+
+		tx = fees.newTx(chainID, nonce, gas, toAddr, params.Value, params.Data, accessList)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, chainID, nil
+}
+
+// SignETHTx 按交易类型选择 signer 签名；legacy 交易未签名时带不出 chain id，需要单独传
+func SignETHTx(priv *ecdsa.PrivateKey, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	signedTx, err := types.SignTx(tx, ethSigner(tx, chainID), priv)
 	if err != nil {
-		return "", err
+		return nil, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "SignTx", err)
 	}
+	return signedTx, nil
+}
 
-	header, err := client.HeaderByNumber(ctx, nil)
+// estimateGas 估算 gas 并加上 GasMargin 百分比的余量；普通转账固定 21000，不加余量
+func (e *ETHChain) estimateGas(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg) (uint64, error) {
+	gas, err := client.EstimateGas(ctx, msg)
 	if err != nil {
-		return "", err
-	}
-
-	baseFee := header.BaseFee
-
-	feeCap := new(big.Int).Add(
-		new(big.Int).Mul(baseFee, big.NewInt(2)), // 留 buffer
-		tip,
-	)
-	toAddr := common.HexToAddress(to)
-	tx := types.NewTx(&types.DynamicFeeTx{
-		ChainID:   e.ChainID,
-		Nonce:     nonce,
-		GasTipCap: tip,
-		GasFeeCap: feeCap,
-		Gas:       21000,
-		To:        &toAddr,
-		Value:     amountWei,
+		return 0, wrapErrors.WrapWithCode(wrapErrors.CodeGasEstimate, "EstimateGas", revertError(err))
+	}
+	if gas == ethparams.TxGas {
+		return gas, nil
+	}
+	return gas + gas*e.GasMargin/100, nil
+}
+
+// revertError 估算 revert 时把 Error(string) 里的原因解出来
+func revertError(err error) error {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return err
+	}
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return err
+	}
+	reason, unpackErr := abi.UnpackRevert(common.FromHex(data))
+	if unpackErr != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, reason)
+}
+
+// Receipt 交易回执，还没上链时返回 nil
+func (e *ETHChain) Receipt(ctx context.Context, hash string) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := e.Clients.Do(ctx, "TransactionReceipt", func(client *ethclient.Client) error {
+		r, err := client.TransactionReceipt(ctx, common.HexToHash(hash))
+		if errors.Is(err, ethereum.NotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		receipt = r
+		return nil
+	})
+	return receipt, err
+}
+
+// BlockNumber 当前区块高度
+func (e *ETHChain) BlockNumber(ctx context.Context) (uint64, error) {
+	var height uint64
+	err := e.Clients.Do(ctx, "BlockNumber", func(client *ethclient.Client) error {
+		var err error
+		height, err = client.BlockNumber(ctx)
+		return err
 	})
+	return height, err
+}
 
-	signer := types.NewLondonSigner(e.ChainID)
-	signedTx, err := types.SignTx(tx, signer, priv)
+// Nonces 地址已确认的 nonce (latest) 和算上 mempool 的 nonce (pending)，两次查询在同一个节点上
+func (e *ETHChain) Nonces(ctx context.Context, address string) (latest, pending uint64, err error) {
+	addr := common.HexToAddress(address)
+	err = e.Clients.Do(ctx, "nonces", func(client *ethclient.Client) error {
+		var err error
+		if latest, err = client.NonceAt(ctx, addr, nil); err != nil {
+			return err
+		}
+		pending, err = client.PendingNonceAt(ctx, addr)
+		return err
+	})
 	if err != nil {
-		return "", wrapErrors.WrapWithCode(wrapErrors.SignerErr, "SignTx", err)
+		return 0, 0, wrapErrors.WrapWithCode(wrapErrors.PendingNonceAt, "Nonces", err)
+	}
+	return latest, pending, nil
+}
+
+// TransactionKnown 节点是否知道这笔交易 (已上链或在 mempool 里)
+func (e *ETHChain) TransactionKnown(ctx context.Context, hash string) (bool, error) {
+	known := false
+	err := e.Clients.Do(ctx, "TransactionByHash", func(client *ethclient.Client) error {
+		_, _, err := client.TransactionByHash(ctx, common.HexToHash(hash))
+		if errors.Is(err, ethereum.NotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		known = true
+		return nil
+	})
+	return known, err
+}
+
+// BroadcastRawETH 原样广播一笔已签名交易，节点已经有这笔交易时视为成功
+func (e *ETHChain) BroadcastRawETH(ctx context.Context, raw []byte) (*types.Transaction, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.SendTxErr, "UnmarshalBinary", err)
+	}
+	if err := e.SendSignedETH(ctx, tx); err != nil {
+		return nil, err
 	}
+	return tx, nil
+}
 
-	if err := client.SendTransaction(ctx, signedTx); err != nil {
-		return "", wrapErrors.WrapWithCode(wrapErrors.SendTxErr, "SendTransaction", err)
+// SendSignedETH 广播已签名交易，节点已经有这笔交易时视为成功
+func (e *ETHChain) SendSignedETH(ctx context.Context, tx *types.Transaction) error {
+	err := e.Clients.Do(ctx, "send raw tx", func(client *ethclient.Client) error {
+		err := client.SendTransaction(ctx, tx)
+		if err != nil && strings.Contains(strings.ToLower(err.Error()), "already known") {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return wrapErrors.WrapWithCode(wrapErrors.SendTxErr, "SendTransaction", err)
 	}
+	return nil
+}
 
-	return signedTx.Hash().Hex(), nil
+// DecodeRawETH 解析外部传入的已签名交易，校验 chain id 并恢复签名地址；
+// 没有 EIP-155 保护的 legacy 交易可以在任意链上重放，直接拒绝
+func (e *ETHChain) DecodeRawETH(ctx context.Context, raw []byte) (*types.Transaction, string, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, "", fmt.Errorf("invalid signed transaction: %w", err)
+	}
+	if !tx.Protected() {
+		return nil, "", errors.New("transaction is not replay-protected (EIP-155)")
+	}
+	chainID, err := e.NetworkChainID(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if tx.ChainId().Cmp(chainID) != 0 {
+		return nil, "", fmt.Errorf("transaction is for chain %s, but this service is on chain %s", tx.ChainId(), chainID)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid transaction signature: %w", err)
+	}
+	return tx, from.Hex(), nil
 }
 
 func (e *ETHChain) GetBalance(
@@ -90,15 +312,15 @@ func (e *ETHChain) GetBalance(
 	address string,
 ) (*big.Int, error) {
 
-	client, err := ethclient.Dial(e.Rpc)
-	if err != nil {
-		return nil, err
-	}
-
 	addr := common.HexToAddress(address)
 
 	// latest block balance
-	balance, err := client.BalanceAt(ctx, addr, nil)
+	var balance *big.Int
+	err := e.Clients.Do(ctx, "BalanceAt", func(client *ethclient.Client) error {
+		var err error
+		balance, err = client.BalanceAt(ctx, addr, nil)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,292 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ContractValue 解码后的一个返回值，整数统一用十进制字符串，bytes 用 0x hex，tuple 用对象
+type ContractValue struct {
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// ParseContractABI 接受完整的 JSON ABI 数组，或者单个 fragment 对象
+func ParseContractABI(def []byte) (abi.ABI, error) {
+	def = bytes.TrimSpace(def)
+	if len(def) > 0 && def[0] == '{' {
+		def = append(append([]byte{'['}, def...), ']')
+	}
+	parsed, err := abi.JSON(bytes.NewReader(def))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("invalid abi: %w", err)
+	}
+	return parsed, nil
+}
+
+// FindContractMethod 按方法名查找；重载的方法用完整签名，如 transfer(address,uint256)
+func FindContractMethod(parsed abi.ABI, name string) (abi.Method, error) {
+	if strings.Contains(name, "(") {
+		sig := strings.ReplaceAll(name, " ", "")
+		for _, m := range parsed.Methods {
+			if m.Sig == sig {
+				return m, nil
+			}
+		}
+		return abi.Method{}, fmt.Errorf("method %s not found in abi", name)
+	}
+	m, ok := parsed.Methods[name]
+	if !ok {
+		return abi.Method{}, fmt.Errorf("method %s not found in abi", name)
+	}
+	var overloads []string
+	for _, other := range parsed.Methods {
+		if other.RawName == name {
+			overloads = append(overloads, other.Sig)
+		}
+	}
+	if len(overloads) > 1 {
+		return abi.Method{}, fmt.Errorf("method %s is overloaded, use one of %s", name, strings.Join(overloads, ", "))
+	}
+	return m, nil
+}
+
+// PackContractArgs 把 JSON 参数按 ABI 类型转换后编码 (不含方法 selector)
+func PackContractArgs(args abi.Arguments, raw []json.RawMessage) ([]byte, error) {
+	if len(raw) != len(args) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", len(args), len(raw))
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := abiValue(arg.Type, raw[i])
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s %s): %w", i, arg.Type.String(), arg.Name, err)
+		}
+		values[i] = v.Interface()
+	}
+	return args.Pack(values...)
+}
+
+// UnpackContractOutputs 解码 eth_call 的返回数据
+func UnpackContractOutputs(args abi.Arguments, data []byte) ([]ContractValue, error) {
+	if len(args) == 0 {
+		return []ContractValue{}, nil
+	}
+	if len(data) == 0 {
+		return nil, errors.New("call returned no data, is the address a contract?")
+	}
+	values, err := args.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ContractValue, len(args))
+	for i, arg := range args {
+		out[i] = ContractValue{Name: arg.Name, Type: arg.Type.String(), Value: abiJSON(arg.Type, values[i])}
+	}
+	return out, nil
+}
+
+// abiValue JSON 值转换成 go-ethereum 编码需要的 Go 类型
+func abiValue(t abi.Type, raw json.RawMessage) (reflect.Value, error) {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		n, err := jsonInteger(raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if err := checkIntRange(t, n); err != nil {
+			return reflect.Value{}, err
+		}
+		typ := t.GetType()
+		if typ == reflect.TypeOf(&big.Int{}) {
+			return reflect.ValueOf(n), nil
+		}
+		v := reflect.New(typ).Elem()
+		if t.T == abi.IntTy {
+			v.SetInt(n.Int64())
+		} else {
+			v.SetUint(n.Uint64())
+		}
+		return v, nil
+
+	case abi.BoolTy:
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return reflect.Value{}, errors.New("expected true or false")
+		}
+		return reflect.ValueOf(b), nil
+
+	case abi.StringTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return reflect.Value{}, errors.New("expected a string")
+		}
+		return reflect.ValueOf(s), nil
+
+	case abi.AddressTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil || !common.IsHexAddress(s) {
+			return reflect.Value{}, errors.New("expected an address")
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+
+	case abi.BytesTy, abi.FixedBytesTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return reflect.Value{}, errors.New("expected a 0x hex string")
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if t.T == abi.BytesTy {
+			return reflect.ValueOf(b), nil
+		}
+		if len(b) != t.Size {
+			return reflect.Value{}, fmt.Errorf("expected %d bytes, got %d", t.Size, len(b))
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v, nil
+
+	case abi.SliceTy, abi.ArrayTy:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return reflect.Value{}, errors.New("expected an array")
+		}
+		var v reflect.Value
+		if t.T == abi.ArrayTy {
+			if len(items) != t.Size {
+				return reflect.Value{}, fmt.Errorf("expected %d items, got %d", t.Size, len(items))
+			}
+			v = reflect.New(t.GetType()).Elem()
+		} else {
+			v = reflect.MakeSlice(t.GetType(), len(items), len(items))
+		}
+		for i, item := range items {
+			ev, err := abiValue(*t.Elem, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("item %d: %w", i, err)
+			}
+			v.Index(i).Set(ev)
+		}
+		return v, nil
+
+	case abi.TupleTy:
+		// 对象按字段名，数组按位置
+		var byName map[string]json.RawMessage
+		var byPos []json.RawMessage
+		if err := json.Unmarshal(raw, &byName); err != nil || byName == nil {
+			byName = nil
+			if err := json.Unmarshal(raw, &byPos); err != nil || len(byPos) != len(t.TupleElems) {
+				return reflect.Value{}, fmt.Errorf("expected an object or an array of %d items", len(t.TupleElems))
+			}
+		}
+		v := reflect.New(t.GetType()).Elem()
+		for i, elem := range t.TupleElems {
+			name := t.TupleRawNames[i]
+			item := json.RawMessage(nil)
+			if byName != nil {
+				var ok bool
+				if item, ok = byName[name]; !ok {
+					return reflect.Value{}, fmt.Errorf("missing field %s", name)
+				}
+			} else {
+				item = byPos[i]
+			}
+			ev, err := abiValue(*elem, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+			}
+			v.Field(i).Set(ev)
+		}
+		return v, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported abi type %s", t.String())
+}
+
+// jsonInteger JSON 数字，或十进制 / 0x 十六进制字符串 (大整数建议用字符串，避免 JS 精度丢失)
+func jsonInteger(raw json.RawMessage) (*big.Int, error) {
+	text := strings.TrimSpace(string(raw))
+	if s, err := strconv.Unquote(text); err == nil {
+		text = strings.TrimSpace(s)
+	}
+	base := 10
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		text, base = text[2:], 16
+	} else if strings.HasPrefix(text, "-0x") {
+		text, base = "-"+text[3:], 16
+	}
+	n, ok := new(big.Int).SetString(text, base)
+	if !ok {
+		return nil, fmt.Errorf("expected an integer, got %s", string(raw))
+	}
+	return n, nil
+}
+
+func checkIntRange(t abi.Type, n *big.Int) error {
+	if t.T == abi.UintTy {
+		if n.Sign() < 0 || n.BitLen() > t.Size {
+			return fmt.Errorf("%s out of range for uint%d", n, t.Size)
+		}
+		return nil
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return fmt.Errorf("%s out of range for int%d", n, t.Size)
+	}
+	return nil
+}
+
+// abiJSON 解码出来的 Go 值转成适合 JSON 输出的值
+func abiJSON(t abi.Type, v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		if n, ok := v.(*big.Int); ok {
+			return n.String()
+		}
+		return fmt.Sprint(v)
+
+	case abi.AddressTy:
+		return v.(common.Address).Hex()
+
+	case abi.BytesTy:
+		return hexutil.Encode(v.([]byte))
+
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return hexutil.Encode(b)
+
+	case abi.SliceTy, abi.ArrayTy:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = abiJSON(*t.Elem, rv.Index(i).Interface())
+		}
+		return items
+
+	case abi.TupleTy:
+		fields := make(map[string]interface{}, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			name := t.TupleRawNames[i]
+			if name == "" {
+				name = strconv.Itoa(i)
+			}
+			fields[name] = abiJSON(*elem, rv.Field(i).Interface())
+		}
+		return fields
+	}
+	return v
+}
@@ -0,0 +1,183 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	wrapErrors "github.com/linlinbupt123-crypto/wallet_service/errors"
+)
+
+// erc20ABI 只用到转账、余额和元数据
+var erc20ABI = mustParseABI(`[
+	{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"decimals","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"type":"function","name":"symbol","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"type":"function","name":"name","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]}
+]`)
+
+func mustParseABI(def string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(def))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// ETHTokenMetadata 从合约读到的 ERC-20 元数据
+type ETHTokenMetadata struct {
+	Name     string
+	Symbol   string
+	Decimals uint8
+}
+
+// ERC20TransferData transfer(to, amount) 的 calldata，amount 已经是最小单位
+func ERC20TransferData(to string, amount *big.Int) ([]byte, error) {
+	return erc20ABI.Pack("transfer", common.HexToAddress(to), amount)
+}
+
+// DecodeERC20Transfer 解析 transfer(to, amount) 的 calldata，不是 transfer 时 ok 为 false
+func DecodeERC20Transfer(data []byte) (to string, amount *big.Int, ok bool) {
+	method := erc20ABI.Methods["transfer"]
+	if len(data) != 4+64 || !bytes.Equal(data[:4], method.ID) {
+		return "", nil, false
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil || len(args) != 2 {
+		return "", nil, false
+	}
+	addr, ok1 := args[0].(common.Address)
+	value, ok2 := args[1].(*big.Int)
+	if !ok1 || !ok2 {
+		return "", nil, false
+	}
+	return addr.Hex(), value, true
+}
+
+// NetworkChainID 配置了 chain_id 时直接用，否则问节点
+func (e *ETHChain) NetworkChainID(ctx context.Context) (*big.Int, error) {
+	if e.ChainID != nil && e.ChainID.Sign() > 0 {
+		return e.ChainID, nil
+	}
+	var id *big.Int
+	err := e.Clients.Do(ctx, "ChainID", func(client *ethclient.Client) error {
+		var err error
+		id, err = client.ChainID(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.GetchainIDErr, "get chainID", err)
+	}
+	return id, nil
+}
+
+// TokenBalance ERC-20 balanceOf(owner)，最小单位
+func (e *ETHChain) TokenBalance(ctx context.Context, token, owner string) (*big.Int, error) {
+	out, err := e.callERC20(ctx, token, "balanceOf", common.HexToAddress(owner))
+	if err != nil {
+		return nil, err
+	}
+	balance, ok := out[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected balanceOf result from %s", token)
+	}
+	return balance, nil
+}
+
+// TokenMetadata 读取 ERC-20 的 decimals / symbol / name。decimals 必须有；
+// symbol / name 是可选接口，早期合约 (如 MKR) 返回 bytes32，这里一并兼容
+func (e *ETHChain) TokenMetadata(ctx context.Context, token string) (*ETHTokenMetadata, error) {
+	var code []byte
+	err := e.Clients.Do(ctx, "CodeAt", func(client *ethclient.Client) error {
+		var err error
+		code, err = client.CodeAt(ctx, common.HexToAddress(token), nil)
+		return err
+	})
+	if err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, "CodeAt", err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("%s is not a contract", token)
+	}
+
+	out, err := e.callERC20(ctx, token, "decimals")
+	if err != nil {
+		return nil, fmt.Errorf("%s does not look like an ERC-20 token: %w", token, err)
+	}
+	decimals, ok := out[0].(uint8)
+	if !ok {
+		return nil, fmt.Errorf("unexpected decimals result from %s", token)
+	}
+
+	meta := &ETHTokenMetadata{Decimals: decimals}
+	meta.Symbol, _ = e.erc20String(ctx, token, "symbol")
+	meta.Name, _ = e.erc20String(ctx, token, "name")
+	if meta.Symbol == "" {
+		return nil, fmt.Errorf("%s has no symbol", token)
+	}
+	return meta, nil
+}
+
+// erc20String 读取 string 返回值，解不出来时按 bytes32 去掉末尾的 0 再试
+func (e *ETHChain) erc20String(ctx context.Context, token, method string) (string, error) {
+	data, err := e.callContract(ctx, token, erc20ABI.Methods[method].ID)
+	if err != nil {
+		return "", err
+	}
+	if out, err := erc20ABI.Unpack(method, data); err == nil {
+		if s, ok := out[0].(string); ok {
+			return s, nil
+		}
+	}
+	if len(data) == 32 {
+		return string(bytes.TrimRight(data, "\x00")), nil
+	}
+	return "", fmt.Errorf("unexpected %s result from %s", method, token)
+}
+
+func (e *ETHChain) callERC20(ctx context.Context, token, method string, args ...interface{}) ([]interface{}, error) {
+	input, err := erc20ABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	data, err := e.callContract(ctx, token, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty result from " + method)
+	}
+	return erc20ABI.Unpack(method, data)
+}
+
+// callContract eth_call 到 latest 区块；revert 属于合约本身的结果，不换节点
+func (e *ETHChain) callContract(ctx context.Context, to string, input []byte) ([]byte, error) {
+	return e.CallContract(ctx, "", to, input)
+}
+
+// CallContract 只读 eth_call，from 可以为空；view 函数里用到 msg.sender 时需要带上
+func (e *ETHChain) CallContract(ctx context.Context, from, to string, input []byte) ([]byte, error) {
+	addr := common.HexToAddress(to)
+	msg := ethereum.CallMsg{To: &addr, Data: input}
+	if from != "" {
+		msg.From = common.HexToAddress(from)
+	}
+	var out []byte
+	err := e.Clients.Do(ctx, "CallContract", func(client *ethclient.Client) error {
+		var err error
+		out, err = client.CallContract(ctx, msg, nil)
+		return err
+	})
+	if err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, "CallContract", revertError(err))
+	}
+	return out, nil
+}
@@ -0,0 +1,243 @@
+package chain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	ethparams "github.com/ethereum/go-ethereum/params"
+	wrapErrors "github.com/linlinbupt123-crypto/wallet_service/errors"
+)
+
+// ethFees 一笔交易的费用参数
+// 最新区块头没有 baseFee 时说明网络 (或 dev 节点) 没开 London，只能用 gas price
+type ethFees struct {
+	London   bool
+	GasPrice *big.Int // 非 London
+	TipCap   *big.Int // London
+	FeeCap   *big.Int // London
+}
+
+// suggestFees 按节点最新区块判断是否支持 EIP-1559，并给出对应的费用
+func suggestFees(ctx context.Context, client *ethclient.Client) (*ethFees, error) {
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.BaseFee == nil {
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &ethFees{GasPrice: gasPrice}, nil
+	}
+
+	tip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feeCap := new(big.Int).Add(
+		new(big.Int).Mul(header.BaseFee, big.NewInt(2)), // 留 buffer
+		tip,
+	)
+	return &ethFees{London: true, TipCap: tip, FeeCap: feeCap}, nil
+}
+
+// newTx London 网络用 DynamicFeeTx；否则有 access list 时用 AccessListTx (EIP-2930)，没有时用 LegacyTx
+func (f *ethFees) newTx(chainID *big.Int, nonce, gas uint64, to *common.Address, value *big.Int, data []byte, accessList types.AccessList) *types.Transaction {
+	switch {
+	case f.London:
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasTipCap:  f.TipCap,
+			GasFeeCap:  f.FeeCap,
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	case len(accessList) > 0:
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasPrice:   f.GasPrice,
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	default:
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: f.GasPrice,
+			Gas:      gas,
+			To:       to,
+			Value:    value,
+			Data:     data,
+		})
+	}
+}
+
+// ethSigner 按交易类型选签名器，legacy 交易用 EIP-155 带上 chain id 防重放
+func ethSigner(tx *types.Transaction, chainID *big.Int) types.Signer {
+	switch tx.Type() {
+	case types.DynamicFeeTxType:
+		return types.NewLondonSigner(chainID)
+	case types.AccessListTxType:
+		return types.NewEIP2930Signer(chainID)
+	default:
+		return types.NewEIP155Signer(chainID)
+	}
+}
+
+// legacyAccessList 非 London 网络上的合约调用用 eth_createAccessList 预先声明要访问的存储，
+// 节点不支持或结果为空时返回 nil，退回 LegacyTx
+func legacyAccessList(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg) types.AccessList {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+		"data": hexutil.Bytes(msg.Data),
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	var result struct {
+		AccessList types.AccessList `json:"accessList"`
+		Error      string           `json:"error,omitempty"`
+	}
+	if err := client.Client().CallContext(ctx, &result, "eth_createAccessList", arg, "pending"); err != nil {
+		return nil
+	}
+	if result.Error != "" || len(result.AccessList) == 0 {
+		return nil
+	}
+	return result.AccessList
+}
+
+// DefaultReplacementBump geth / erigon 默认要求替换交易的费用至少上调 10%
+const DefaultReplacementBump = 10
+
+// ReplaceETHTx 用原交易的 nonce 和类型重新签名一笔替换交易并广播。
+// cancel 为 true 时替换成给自己的 0 ETH 转账，否则原样加价 (speed-up)；
+// 每个费用字段取原交易上调 bumpPercent 后与当前建议费用中较大的一个
+func (e *ETHChain) ReplaceETHTx(ctx context.Context, priv *ecdsa.PrivateKey, orig *types.Transaction, cancel bool, bumpPercent uint64) (*types.Transaction, error) {
+	if bumpPercent < e.ReplacementBump {
+		bumpPercent = e.ReplacementBump
+	}
+	from := crypto.PubkeyToAddress(priv.PublicKey)
+	to, value, data, gas := orig.To(), orig.Value(), orig.Data(), orig.Gas()
+	accessList := orig.AccessList()
+	if cancel {
+		to, value, data, gas, accessList = &from, big.NewInt(0), nil, ethparams.TxGas, nil
+	}
+
+	var fees *ethFees
+	err := e.Clients.Do(ctx, "suggest fees", func(client *ethclient.Client) error {
+		var err error
+		fees, err = suggestFees(ctx, client)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bumped := &ethFees{London: orig.Type() == types.DynamicFeeTxType}
+	if bumped.London {
+		bumped.TipCap = maxBig(bumpFee(orig.GasTipCap(), bumpPercent), fees.TipCap)
+		bumped.FeeCap = maxBig(bumpFee(orig.GasFeeCap(), bumpPercent), fees.FeeCap)
+		if bumped.FeeCap.Cmp(bumped.TipCap) < 0 {
+			bumped.FeeCap = new(big.Int).Set(bumped.TipCap)
+		}
+	} else {
+		suggested := fees.GasPrice
+		if fees.London {
+			suggested = fees.FeeCap
+		}
+		bumped.GasPrice = maxBig(bumpFee(orig.GasPrice(), bumpPercent), suggested)
+	}
+
+	chainID := orig.ChainId()
+	tx := bumped.newTx(chainID, orig.Nonce(), gas, to, value, data, accessList)
+	signedTx, err := types.SignTx(tx, ethSigner(tx, chainID), priv)
+	if err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "SignTx", err)
+	}
+
+	err = e.Clients.Do(ctx, "send tx", func(client *ethclient.Client) error {
+		return client.SendTransaction(ctx, signedTx)
+	})
+	if err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.SendTxErr, "SendTransaction", err)
+	}
+	return signedTx, nil
+}
+
+// EscalateETHTx 离线预签一组逐级加价的替换交易 (内容、nonce、类型都和原交易相同)，
+// 每一级在上一级基础上上调 bumpPercent，max fee (非 London 为 gas price) 超过 ceiling 或满 steps 级为止。
+// 后台任务拿不到私钥，只能在发送时把加价阶梯签好
+func (e *ETHChain) EscalateETHTx(priv *ecdsa.PrivateKey, orig *types.Transaction, ceiling *big.Int, steps int, bumpPercent uint64) ([]*types.Transaction, error) {
+	if bumpPercent < e.ReplacementBump {
+		bumpPercent = e.ReplacementBump
+	}
+	chainID := orig.ChainId()
+	fees := &ethFees{London: orig.Type() == types.DynamicFeeTxType}
+	if fees.London {
+		fees.TipCap, fees.FeeCap = orig.GasTipCap(), orig.GasFeeCap()
+	} else {
+		fees.GasPrice = orig.GasPrice()
+	}
+
+	var ladder []*types.Transaction
+	for len(ladder) < steps {
+		next := &ethFees{London: fees.London}
+		var limit *big.Int
+		if fees.London {
+			next.TipCap = bumpFee(fees.TipCap, bumpPercent)
+			next.FeeCap = bumpFee(fees.FeeCap, bumpPercent)
+			limit = next.FeeCap
+		} else {
+			next.GasPrice = bumpFee(fees.GasPrice, bumpPercent)
+			limit = next.GasPrice
+		}
+		if limit.Cmp(ceiling) > 0 {
+			break
+		}
+
+		tx := next.newTx(chainID, orig.Nonce(), orig.Gas(), orig.To(), orig.Value(), orig.Data(), orig.AccessList())
+		signedTx, err := types.SignTx(tx, ethSigner(tx, chainID), priv)
+		if err != nil {
+			return nil, wrapErrors.WrapWithCode(wrapErrors.SignerErr, "SignTx", err)
+		}
+		ladder = append(ladder, signedTx)
+		fees = next
+	}
+	return ladder, nil
+}
+
+// bumpFee old * (100 + percent) / 100 向上取整，且严格大于 old
+func bumpFee(old *big.Int, percent uint64) *big.Int {
+	n := new(big.Int).Mul(old, new(big.Int).SetUint64(100+percent))
+	n.Add(n, big.NewInt(99))
+	n.Div(n, big.NewInt(100))
+	if n.Cmp(old) <= 0 {
+		n.Add(old, big.NewInt(1))
+	}
+	return n
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if b != nil && b.Cmp(a) > 0 {
+		return new(big.Int).Set(b)
+	}
+	return a
+}
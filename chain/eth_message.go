@@ -0,0 +1,137 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ETHPersonalMessage personal_sign (EIP-191 0x45) 消息的解码结果
+type ETHPersonalMessage struct {
+	Encoding string `json:"encoding"`       // utf8 / hex
+	Text     string `json:"text,omitempty"` // hex 消息能解成可打印文本时也给出
+	Hex      string `json:"hex"`
+	Length   int    `json:"length"`
+	Hash     string `json:"hash"` // keccak256("\x19Ethereum Signed Message:\n" + len + message)
+}
+
+// ETHTypedData eth_signTypedData_v4 (EIP-712) 的解码结果
+type ETHTypedData struct {
+	Domain          map[string]interface{} `json:"domain"`
+	PrimaryType     string                 `json:"primary_type"`
+	Message         map[string]interface{} `json:"message"`
+	DomainSeparator string                 `json:"domain_separator"`
+	MessageHash     string                 `json:"message_hash"` // hashStruct(primaryType, message)
+	Hash            string                 `json:"hash"`         // keccak256("\x19\x01" + domainSeparator + messageHash)
+
+	ChainID *big.Int `json:"-"` // domain 里没有 chainId 时为 nil
+}
+
+// ParsePersonalMessage 和 MetaMask 一致：0x 开头的合法 hex 按原始字节签名，其他按 UTF-8 文本
+func ParsePersonalMessage(message string) (*ETHPersonalMessage, []byte) {
+	msg := &ETHPersonalMessage{Encoding: "utf8", Text: message}
+	data := []byte(message)
+	if b, err := hexutil.Decode(message); err == nil {
+		msg.Encoding, msg.Text, data = "hex", "", b
+		if printable(b) {
+			msg.Text = string(b)
+		}
+	}
+	hash := accounts.TextHash(data)
+	msg.Hex = hexutil.Encode(data)
+	msg.Length = len(data)
+	msg.Hash = hexutil.Encode(hash)
+	return msg, hash
+}
+
+// ParseTypedData 解析 EIP-712 JSON 并计算签名 hash；types 里必须带 EIP712Domain
+func ParseTypedData(raw []byte) (*ETHTypedData, []byte, error) {
+	var data apitypes.TypedData
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if err := dec.Decode(&data); err != nil {
+		return nil, nil, fmt.Errorf("invalid typed data: %w", err)
+	}
+	if _, ok := data.Types["EIP712Domain"]; !ok {
+		return nil, nil, errors.New("typed data: types.EIP712Domain is missing")
+	}
+	if _, ok := data.Types[data.PrimaryType]; !ok || data.PrimaryType == "EIP712Domain" {
+		return nil, nil, fmt.Errorf("typed data: primary type %q is not defined", data.PrimaryType)
+	}
+
+	domain := data.Domain.Map()
+	domainSeparator, err := data.HashStruct("EIP712Domain", domain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("typed data domain: %w", err)
+	}
+	messageHash, err := data.HashStruct(data.PrimaryType, data.Message)
+	if err != nil {
+		return nil, nil, fmt.Errorf("typed data message: %w", err)
+	}
+	hash := crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, messageHash)
+
+	out := &ETHTypedData{
+		Domain:          domain,
+		PrimaryType:     data.PrimaryType,
+		Message:         data.Message,
+		DomainSeparator: hexutil.Encode(domainSeparator),
+		MessageHash:     hexutil.Encode(messageHash),
+		Hash:            hexutil.Encode(hash),
+	}
+	if data.Domain.ChainId != nil {
+		out.ChainID = (*big.Int)(data.Domain.ChainId)
+		out.Domain["chainId"] = out.ChainID.String()
+	}
+	return out, hash, nil
+}
+
+// SignETHHash 签名 32 字节 hash，返回 r || s || v 的 hex，v 为 27 / 28
+func SignETHHash(priv *ecdsa.PrivateKey, hash []byte) (string, error) {
+	sig, err := crypto.Sign(hash, priv)
+	if err != nil {
+		return "", err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig), nil
+}
+
+// RecoverETHSigner 从签名恢复签名地址，v 接受 0 / 1 和 27 / 28
+func RecoverETHSigner(hash []byte, signature string) (string, error) {
+	sig, err := hexutil.Decode(strings.TrimSpace(signature))
+	if err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return "", fmt.Errorf("invalid signature length %d, expected %d", len(sig), crypto.SignatureLength)
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub).Hex(), nil
+}
+
+func printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package chain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// EIP-712 规范里的 Mail 例子，私钥为 keccak256("cow")
+const eip712MailExample = `{
+  "types": {
+    "EIP712Domain": [
+      {"name": "name", "type": "string"},
+      {"name": "version", "type": "string"},
+      {"name": "chainId", "type": "uint256"},
+      {"name": "verifyingContract", "type": "address"}
+    ],
+    "Person": [
+      {"name": "name", "type": "string"},
+      {"name": "wallet", "type": "address"}
+    ],
+    "Mail": [
+      {"name": "from", "type": "Person"},
+      {"name": "to", "type": "Person"},
+      {"name": "contents", "type": "string"}
+    ]
+  },
+  "primaryType": "Mail",
+  "domain": {
+    "name": "Ether Mail",
+    "version": "1",
+    "chainId": 1,
+    "verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+  },
+  "message": {
+    "from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+    "to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+    "contents": "Hello, Bob!"
+  }
+}`
+
+const (
+	eip712MailSigner    = "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"
+	eip712MailSignature = "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562" + "1c"
+)
+
+func TestParseTypedDataMail(t *testing.T) {
+	data, hash, err := ParseTypedData([]byte(eip712MailExample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.DomainSeparator != "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f" {
+		t.Errorf("domain separator %s", data.DomainSeparator)
+	}
+	if data.MessageHash != "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e" {
+		t.Errorf("message hash %s", data.MessageHash)
+	}
+	if data.Hash != "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2" {
+		t.Errorf("hash %s", data.Hash)
+	}
+	if data.ChainID == nil || data.ChainID.Int64() != 1 {
+		t.Errorf("chain id %v, want 1", data.ChainID)
+	}
+
+	signer, err := RecoverETHSigner(hash, eip712MailSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != eip712MailSigner {
+		t.Errorf("signer %s, want %s", signer, eip712MailSigner)
+	}
+
+	// 私钥 keccak256("cow") 签出来的就是规范里的签名
+	priv, err := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignETHHash(priv, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig != eip712MailSignature {
+		t.Errorf("signature %s, want %s", sig, eip712MailSignature)
+	}
+}
+
+func TestParseTypedDataErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		err  string
+	}{
+		{name: "not json", raw: "hello", err: "invalid typed data"},
+		{name: "no domain type", raw: strings.Replace(eip712MailExample, `"EIP712Domain"`, `"Domain"`, 1), err: "EIP712Domain is missing"},
+		{name: "undefined primary type", raw: strings.Replace(eip712MailExample, `"primaryType": "Mail"`, `"primaryType": "Letter"`, 1), err: "not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseTypedData([]byte(tt.raw))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/linlinbupt123-crypto/wallet_service/config"
+	wrapErrors "github.com/linlinbupt123-crypto/wallet_service/errors"
+)
+
+// NFT 标准
+const (
+	ERC721  = "erc721"
+	ERC1155 = "erc1155"
+)
+
+// ERC-165 interface id
+var (
+	erc721InterfaceID  = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	erc1155InterfaceID = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+)
+
+var erc721ABI = mustParseABI(`[
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"tokenURI","stateMutability":"view","inputs":[{"name":"tokenId","type":"uint256"}],"outputs":[{"name":"","type":"string"}]},
+	{"type":"function","name":"supportsInterface","stateMutability":"view","inputs":[{"name":"interfaceId","type":"bytes4"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]}
+]`)
+
+var erc1155ABI = mustParseABI(`[
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"uri","stateMutability":"view","inputs":[{"name":"id","type":"uint256"}],"outputs":[{"name":"","type":"string"}]},
+	{"type":"event","name":"TransferSingle","anonymous":false,"inputs":[{"name":"operator","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"id","type":"uint256","indexed":false},{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"TransferBatch","anonymous":false,"inputs":[{"name":"operator","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"ids","type":"uint256[]","indexed":false},{"name":"values","type":"uint256[]","indexed":false}]}
+]`)
+
+// NFTCollection 配置里需要索引持仓的合约
+type NFTCollection struct {
+	Name      string
+	Address   string // checksum
+	Standard  string // erc721 / erc1155
+	FromBlock uint64 // 合约部署高度，第一次索引从这里开始
+}
+
+// nftCollections 地址统一成 checksum，标准统一成小写
+func nftCollections(cfgs []config.EthNFTConfig) []NFTCollection {
+	out := make([]NFTCollection, 0, len(cfgs))
+	for _, c := range cfgs {
+		out = append(out, NFTCollection{
+			Name:      c.Name,
+			Address:   common.HexToAddress(c.Address).Hex(),
+			Standard:  strings.ToLower(c.Standard),
+			FromBlock: c.FromBlock,
+		})
+	}
+	return out
+}
+
+// NFTTransfer 从日志里解出的一次转移；ERC-721 的 Amount 为 1，TransferBatch 按 id 拆成多条
+type NFTTransfer struct {
+	BlockNumber uint64
+	TxHash      string
+	LogIndex    uint
+	BatchIndex  int // TransferBatch 里的第几个 id，其他日志为 0
+	From        string
+	To          string
+	TokenID     *big.Int
+	Amount      *big.Int
+}
+
+// ERC721TransferData safeTransferFrom(from, to, tokenId) 的 calldata
+func ERC721TransferData(from, to string, tokenID *big.Int) ([]byte, error) {
+	return erc721ABI.Pack("safeTransferFrom", common.HexToAddress(from), common.HexToAddress(to), tokenID)
+}
+
+// ERC1155TransferData safeTransferFrom(from, to, id, amount, "") 的 calldata
+func ERC1155TransferData(from, to string, id, amount *big.Int) ([]byte, error) {
+	return erc1155ABI.Pack("safeTransferFrom", common.HexToAddress(from), common.HexToAddress(to), id, amount, []byte{})
+}
+
+// NFTStandard 用 ERC-165 supportsInterface 判断合约是 ERC-721 还是 ERC-1155
+func (e *ETHChain) NFTStandard(ctx context.Context, contract string) (string, error) {
+	for _, c := range []struct {
+		standard string
+		id       [4]byte
+	}{{ERC721, erc721InterfaceID}, {ERC1155, erc1155InterfaceID}} {
+		input, err := erc721ABI.Pack("supportsInterface", c.id)
+		if err != nil {
+			return "", err
+		}
+		data, err := e.callContract(ctx, contract, input)
+		if err != nil {
+			continue
+		}
+		out, err := erc721ABI.Unpack("supportsInterface", data)
+		if err == nil && len(out) == 1 && out[0] == true {
+			return c.standard, nil
+		}
+	}
+	return "", fmt.Errorf("%s is neither an ERC-721 nor an ERC-1155 contract", contract)
+}
+
+// NFTTokenURI ERC-721 tokenURI(id) / ERC-1155 uri(id)；ERC-1155 的 {id} 按规范替换成 64 位小写 hex
+func (e *ETHChain) NFTTokenURI(ctx context.Context, contract, standard string, tokenID *big.Int) (string, error) {
+	contractABI, method := erc721ABI, "tokenURI"
+	if standard == ERC1155 {
+		contractABI, method = erc1155ABI, "uri"
+	}
+	input, err := contractABI.Pack(method, tokenID)
+	if err != nil {
+		return "", err
+	}
+	data, err := e.callContract(ctx, contract, input)
+	if err != nil {
+		return "", err
+	}
+	out, err := contractABI.Unpack(method, data)
+	if err != nil {
+		return "", err
+	}
+	uri, _ := out[0].(string)
+	if standard == ERC1155 {
+		uri = strings.ReplaceAll(uri, "{id}", fmt.Sprintf("%064x", tokenID))
+	}
+	return uri, nil
+}
+
+// NFTTransfers [from, to] 区块范围内合约的转移日志，按区块和日志顺序返回
+func (e *ETHChain) NFTTransfers(ctx context.Context, collection NFTCollection, from, to uint64) ([]NFTTransfer, error) {
+	var topics []common.Hash
+	if collection.Standard == ERC1155 {
+		topics = []common.Hash{erc1155ABI.Events["TransferSingle"].ID, erc1155ABI.Events["TransferBatch"].ID}
+	} else {
+		topics = []common.Hash{erc721ABI.Events["Transfer"].ID}
+	}
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{common.HexToAddress(collection.Address)},
+		Topics:    [][]common.Hash{topics},
+	}
+
+	var logs []types.Log
+	err := e.Clients.Do(ctx, "FilterLogs", func(client *ethclient.Client) error {
+		var err error
+		logs, err = client.FilterLogs(ctx, query)
+		return err
+	})
+	if err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, "FilterLogs", err)
+	}
+
+	var out []NFTTransfer
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		transfers, err := decodeNFTLog(l)
+		if err != nil {
+			return nil, fmt.Errorf("decode log %s#%d: %w", l.TxHash.Hex(), l.Index, err)
+		}
+		out = append(out, transfers...)
+	}
+	return out, nil
+}
+
+// decodeNFTLog ERC-20 的 Transfer 和 ERC-721 同签名但 tokenId 不在 topic 里，按 topic 数量区分后跳过
+func decodeNFTLog(l types.Log) ([]NFTTransfer, error) {
+	base := NFTTransfer{BlockNumber: l.BlockNumber, TxHash: l.TxHash.Hex(), LogIndex: l.Index}
+	switch l.Topics[0] {
+	case erc721ABI.Events["Transfer"].ID:
+		if len(l.Topics) != 4 {
+			return nil, nil
+		}
+		base.From = common.BytesToAddress(l.Topics[1].Bytes()).Hex()
+		base.To = common.BytesToAddress(l.Topics[2].Bytes()).Hex()
+		base.TokenID = new(big.Int).SetBytes(l.Topics[3].Bytes())
+		base.Amount = big.NewInt(1)
+		return []NFTTransfer{base}, nil
+
+	case erc1155ABI.Events["TransferSingle"].ID, erc1155ABI.Events["TransferBatch"].ID:
+		if len(l.Topics) != 4 {
+			return nil, nil
+		}
+		base.From = common.BytesToAddress(l.Topics[2].Bytes()).Hex()
+		base.To = common.BytesToAddress(l.Topics[3].Bytes()).Hex()
+
+		if l.Topics[0] == erc1155ABI.Events["TransferSingle"].ID {
+			out, err := erc1155ABI.Unpack("TransferSingle", l.Data)
+			if err != nil {
+				return nil, err
+			}
+			base.TokenID, base.Amount = out[0].(*big.Int), out[1].(*big.Int)
+			return []NFTTransfer{base}, nil
+		}
+
+		out, err := erc1155ABI.Unpack("TransferBatch", l.Data)
+		if err != nil {
+			return nil, err
+		}
+		ids, values := out[0].([]*big.Int), out[1].([]*big.Int)
+		if len(ids) != len(values) {
+			return nil, fmt.Errorf("TransferBatch has %d ids but %d values", len(ids), len(values))
+		}
+		transfers := make([]NFTTransfer, len(ids))
+		for i := range ids {
+			transfers[i] = base
+			transfers[i].BatchIndex = i
+			transfers[i].TokenID, transfers[i].Amount = ids[i], values[i]
+		}
+		return transfers, nil
+	}
+	return nil, nil
+}
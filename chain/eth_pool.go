@@ -0,0 +1,436 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/linlinbupt123-crypto/wallet_service/config"
+	wrapErrors "github.com/linlinbupt123-crypto/wallet_service/errors"
+)
+
+// ErrNoETHEndpoint 没有配置任何 ETH RPC 节点
+var ErrNoETHEndpoint = errors.New("no eth rpc endpoint configured")
+
+// 节点出错后多久内排到健康节点后面
+const ethEndpointCooldown = 30 * time.Second
+
+// rpc 节点返回的限流错误码 (infura / alchemy)
+const rpcLimitExceeded = -32005
+
+// ETHEndpointStatus 节点状态，用于调试
+type ETHEndpointStatus struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	Height    uint64    `json:"height"`
+	LastError string    `json:"last_error,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+}
+
+// ethEndpoint 一个长连接的 RPC 节点
+type ethEndpoint struct {
+	name    string // 日志和调试里展示的名字，不带 url 里的 api key
+	url     string
+	limiter *rateLimiter
+
+	mu        sync.Mutex
+	client    *ethclient.Client
+	height    uint64
+	lagging   bool
+	downUntil time.Time
+	lastErr   string
+	checkedAt time.Time
+}
+
+// ETHClientPool 多个 ETH RPC 节点：按配置顺序优先，出错或落后时切到下一个
+type ETHClientPool struct {
+	endpoints   []*ethEndpoint
+	chainID     *big.Int // 非 nil 时健康检查会校验节点的 chain id
+	maxBlockLag uint64
+}
+
+func NewETHClientPool(cfg config.EthConfig) *ETHClientPool {
+	p := &ETHClientPool{maxBlockLag: cfg.MaxBlockLag}
+	if cfg.ChainID > 0 {
+		p.chainID = big.NewInt(cfg.ChainID)
+	}
+
+	endpoints := cfg.Endpoints
+	if cfg.RPC != "" {
+		endpoints = append([]config.EthEndpointConfig{{URL: cfg.RPC}}, endpoints...)
+	}
+	for _, ec := range endpoints {
+		limit := ec.RateLimit
+		if limit == 0 {
+			limit = cfg.RateLimit
+		}
+		name := ec.Name
+		if name == "" {
+			name = endpointName(ec.URL)
+		}
+		p.endpoints = append(p.endpoints, &ethEndpoint{
+			name:    name,
+			url:     ec.URL,
+			limiter: newRateLimiter(limit),
+		})
+	}
+	return p
+}
+
+// Do 在第一个可用节点上执行 fn；节点不可达、限流或 5xx 时换下一个节点重试，
+// 节点正常返回的 JSON-RPC 错误 (nonce too low 之类) 直接返回
+func (p *ETHClientPool) Do(ctx context.Context, op string, fn func(*ethclient.Client) error) error {
+	if len(p.endpoints) == 0 {
+		return ErrNoETHEndpoint
+	}
+
+	var lastErr error
+	healthy, degraded := p.candidates()
+	for _, group := range [][]*ethEndpoint{healthy, degraded} {
+		tried := make(map[*ethEndpoint]bool, len(group))
+		for {
+			ep := pick(ctx, group, tried)
+			if ep == nil {
+				break
+			}
+			tried[ep] = true
+
+			client, err := ep.dial(ctx)
+			if err == nil {
+				err = fn(client)
+			}
+			if err == nil {
+				recordEndpoint(ctx, ep.name)
+				return nil
+			}
+			if ctx.Err() != nil || !shouldFailover(err) {
+				recordEndpoint(ctx, ep.name)
+				return err
+			}
+			log.Printf("eth rpc %s: %s failed, trying next endpoint: %v", op, ep.name, err)
+			ep.markDown(err)
+			lastErr = err
+		}
+	}
+	if lastErr == nil {
+		lastErr = ctx.Err()
+	}
+	return wrapErrors.WrapWithCode(wrapErrors.CodeChainRPC, op, lastErr)
+}
+
+// candidates 按配置顺序分成健康节点和出错 / 落后的节点，后者只在健康节点都失败时才用
+func (p *ETHClientPool) candidates() (healthy, degraded []*ethEndpoint) {
+	now := time.Now()
+	for _, ep := range p.endpoints {
+		if ep.available(now) {
+			healthy = append(healthy, ep)
+		} else {
+			degraded = append(degraded, ep)
+		}
+	}
+	return healthy, degraded
+}
+
+// pick 选第一个还没试过且没被限流的节点；全部被限流时等第一个节点的令牌
+func pick(ctx context.Context, candidates []*ethEndpoint, tried map[*ethEndpoint]bool) *ethEndpoint {
+	var first *ethEndpoint
+	for _, ep := range candidates {
+		if tried[ep] {
+			continue
+		}
+		if ep.limiter.allow() {
+			return ep
+		}
+		if first == nil {
+			first = ep
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	if err := first.limiter.wait(ctx); err != nil {
+		return nil
+	}
+	return first
+}
+
+// StartHealthCheck 定时检查每个节点的区块高度和 chain id，落后最高节点超过 max_block_lag 的标记为 lagging
+func (p *ETHClientPool) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		p.checkHealth(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("eth rpc health check stopped")
+				return
+
+			case <-ticker.C:
+				p.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+func (p *ETHClientPool) checkHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ep := range p.endpoints {
+		wg.Add(1)
+		go func(ep *ethEndpoint) {
+			defer wg.Done()
+			ep.check(ctx, p.chainID)
+		}(ep)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, ep := range p.endpoints {
+		if h := ep.currentHeight(); h > best {
+			best = h
+		}
+	}
+	for _, ep := range p.endpoints {
+		ep.mu.Lock()
+		ep.lagging = p.maxBlockLag > 0 && ep.height+p.maxBlockLag < best
+		ep.mu.Unlock()
+	}
+}
+
+// Status 每个节点当前的状态
+func (p *ETHClientPool) Status() []ETHEndpointStatus {
+	now := time.Now()
+	out := make([]ETHEndpointStatus, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		healthy := ep.available(now)
+		ep.mu.Lock()
+		out = append(out, ETHEndpointStatus{
+			Name:      ep.name,
+			Healthy:   healthy,
+			Height:    ep.height,
+			LastError: ep.lastErr,
+			CheckedAt: ep.checkedAt,
+		})
+		ep.mu.Unlock()
+	}
+	return out
+}
+
+// Close 关闭所有节点连接
+func (p *ETHClientPool) Close() {
+	for _, ep := range p.endpoints {
+		ep.mu.Lock()
+		if ep.client != nil {
+			ep.client.Close()
+			ep.client = nil
+		}
+		ep.mu.Unlock()
+	}
+}
+
+// dial 复用已有连接，没有时建立一个
+func (ep *ethEndpoint) dial(ctx context.Context) (*ethclient.Client, error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.client != nil {
+		return ep.client, nil
+	}
+	client, err := ethclient.DialContext(ctx, ep.url)
+	if err != nil {
+		return nil, wrapErrors.WrapWithCode(wrapErrors.DailChain, "eth dial "+ep.name, err)
+	}
+	ep.client = client
+	return client, nil
+}
+
+func (ep *ethEndpoint) check(ctx context.Context, chainID *big.Int) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client, err := ep.dial(ctx)
+	var height uint64
+	if err == nil {
+		height, err = client.BlockNumber(ctx)
+	}
+	if err == nil && chainID != nil {
+		var id *big.Int
+		id, err = client.ChainID(ctx)
+		if err == nil && id.Cmp(chainID) != 0 {
+			err = fmt.Errorf("chain id %s, want %s", id, chainID)
+		}
+	}
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.checkedAt = time.Now()
+	if err != nil {
+		ep.lastErr = err.Error()
+		ep.downUntil = time.Now().Add(ethEndpointCooldown)
+		return
+	}
+	ep.height = height
+	ep.lastErr = ""
+	ep.downUntil = time.Time{}
+}
+
+func (ep *ethEndpoint) markDown(err error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.lastErr = err.Error()
+	ep.downUntil = time.Now().Add(ethEndpointCooldown)
+}
+
+func (ep *ethEndpoint) available(now time.Time) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return !ep.lagging && now.After(ep.downUntil)
+}
+
+func (ep *ethEndpoint) currentHeight() uint64 {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.lastErr != "" {
+		return 0
+	}
+	return ep.height
+}
+
+// shouldFailover 判断错误是否是节点本身的问题，值得换节点重试
+func shouldFailover(err error) bool {
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == rpcLimitExceeded
+	}
+	// 连接失败、超时、EOF 等
+	return true
+}
+
+// endpointName url 里只保留 scheme + host，path / query 里常带 api key
+func endpointName(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "eth-rpc"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// rateLimiter 令牌桶，rate 为每秒请求数，0 表示不限流
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{rate: rate, tokens: burst(rate), last: time.Now()}
+}
+
+// burst 桶容量：最多攒 1 秒的请求
+func burst(rate float64) float64 {
+	if rate < 1 {
+		return 1
+	}
+	return rate
+}
+
+func (l *rateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if b := burst(l.rate); l.tokens > b {
+		l.tokens = b
+	}
+	l.last = now
+}
+
+func (l *rateLimiter) allow() bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// wait 预支一个令牌，等到令牌可用
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	l.refill(time.Now())
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type endpointTraceKey struct{}
+
+// endpointTrace 记录请求实际用到的节点
+type endpointTrace struct {
+	mu        sync.Mutex
+	endpoints []string
+}
+
+// WithEndpointTrace 返回的 ctx 会记录 ETH 请求实际用到的节点，配合 TracedEndpoints 调试用
+func WithEndpointTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, endpointTraceKey{}, &endpointTrace{})
+}
+
+// TracedEndpoints 按顺序返回 ctx 上的请求用到的节点 (去重)
+func TracedEndpoints(ctx context.Context) []string {
+	t, ok := ctx.Value(endpointTraceKey{}).(*endpointTrace)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.endpoints...)
+}
+
+func recordEndpoint(ctx context.Context, name string) {
+	t, ok := ctx.Value(endpointTraceKey{}).(*endpointTrace)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range t.endpoints {
+		if e == name {
+			return
+		}
+	}
+	t.endpoints = append(t.endpoints, name)
+}
@@ -0,0 +1,127 @@
+package chain
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Litecoin / Dogecoin 与比特币共用交易格式、签名和 PSBT 流程，只是网络参数不同。
+// 这里只填地址和 HD 密钥相关的字段，其余共识参数用不到
+var (
+	LTCMainNetParams = chaincfg.Params{
+		Name:             "litecoin-mainnet",
+		Net:              wire.BitcoinNet(0xdbb6c0fb),
+		Bech32HRPSegwit:  "ltc",
+		PubKeyHashAddrID: 0x30, // L...
+		ScriptHashAddrID: 0x32, // M...
+		PrivateKeyID:     0xb0,
+		HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4}, // xprv
+		HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e}, // xpub
+		HDCoinType:       2,
+	}
+
+	LTCTestNetParams = chaincfg.Params{
+		Name:             "litecoin-testnet4",
+		Net:              wire.BitcoinNet(0xf1c8d2fd),
+		Bech32HRPSegwit:  "tltc",
+		PubKeyHashAddrID: 0x6f, // m / n
+		ScriptHashAddrID: 0x3a, // Q...
+		PrivateKeyID:     0xef,
+		HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+		HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+		HDCoinType:       1,
+	}
+
+	DOGEMainNetParams = chaincfg.Params{
+		Name:             "dogecoin-mainnet",
+		Net:              wire.BitcoinNet(0xc0c0c0c0),
+		PubKeyHashAddrID: 0x1e, // D...
+		ScriptHashAddrID: 0x16, // 9 / A
+		PrivateKeyID:     0x9e,
+		HDPrivateKeyID:   [4]byte{0x02, 0xfa, 0xc3, 0x98}, // dgpv
+		HDPublicKeyID:    [4]byte{0x02, 0xfa, 0xca, 0xfd}, // dgub
+		HDCoinType:       3,
+	}
+
+	DOGETestNetParams = chaincfg.Params{
+		Name:             "dogecoin-testnet",
+		Net:              wire.BitcoinNet(0xdcb7c1fc),
+		PubKeyHashAddrID: 0x71, // n...
+		ScriptHashAddrID: 0xc4, // 2...
+		PrivateKeyID:     0xf1,
+		HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94}, // tprv
+		HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf}, // tpub
+		HDCoinType:       1,
+	}
+)
+
+// 注册后 btcutil 才能解码 ltc1 / tltc1 bech32 地址，dgpv 才能 Neuter 成 dgub。
+// LTC / DOGE 的 regtest 与比特币 regtest 的 magic 相同，无法同时注册，所以只支持主网和测试网
+func init() {
+	for _, p := range []*chaincfg.Params{&LTCMainNetParams, &LTCTestNetParams, &DOGEMainNetParams, &DOGETestNetParams} {
+		if err := chaincfg.Register(p); err != nil {
+			panic(fmt.Sprintf("register %s params: %v", p.Name, err))
+		}
+	}
+}
+
+// utxoCoin 一种 UTXO 币的网络参数和默认值
+type utxoCoin struct {
+	mainNet, testNet   *chaincfg.Params
+	addressTypes       []BTCAddressType // 支持的单签地址类型
+	defaultAddressType BTCAddressType
+	feeRate            int64  // 配置没有 fee_rate 时的默认费率 (sat/vB)
+	minFeeRate         int64  // 最低转发费率 (sat/vB)
+	dustLimit          int64  // 配置没有 dust_limit 时的找零 dust 阈值 (satoshi)
+	messageMagic       string // signmessage 的前缀
+	esploraOnly        bool   // 官方节点没有 scantxoutset，只能接 esplora
+}
+
+var utxoCoins = map[ChainType]utxoCoin{
+	BTC: {
+		mainNet:            &chaincfg.MainNetParams,
+		testNet:            &chaincfg.TestNet3Params,
+		addressTypes:       []BTCAddressType{P2PKH, P2SHP2WPKH, P2WPKH, P2TR},
+		defaultAddressType: P2WPKH,
+		feeRate:            defaultBTCFeeRate,
+		minFeeRate:         MinRelayFeeRate,
+		dustLimit:          DustLimit,
+		messageMagic:       "Bitcoin Signed Message:\n",
+	},
+	LTC: {
+		mainNet:            &LTCMainNetParams,
+		testNet:            &LTCTestNetParams,
+		addressTypes:       []BTCAddressType{P2PKH, P2SHP2WPKH, P2WPKH, P2TR},
+		defaultAddressType: P2WPKH,
+		feeRate:            defaultBTCFeeRate,
+		minFeeRate:         MinRelayFeeRate,
+		dustLimit:          DustLimit,
+		messageMagic:       "Litecoin Signed Message:\n",
+	},
+	// Dogecoin 没有 segwit；费率按 Dogecoin Core 1.14 的推荐值 0.01 DOGE/kB，
+	// 最低转发 0.001 DOGE/kB，dust 0.01 DOGE。Dogecoin Core 基于 bitcoind 0.14，没有 scantxoutset / estimatesmartfee
+	DOGE: {
+		mainNet:            &DOGEMainNetParams,
+		testNet:            &DOGETestNetParams,
+		addressTypes:       []BTCAddressType{P2PKH},
+		defaultAddressType: P2PKH,
+		feeRate:            1000,
+		minFeeRate:         100,
+		dustLimit:          1_000_000,
+		messageMagic:       "Dogecoin Signed Message:\n",
+		esploraOnly:        true,
+	},
+}
+
+// UTXOChainTypes 复用 BTCChain 流程的链
+func UTXOChainTypes() []ChainType {
+	return []ChainType{BTC, LTC, DOGE}
+}
+
+// IsUTXOChain name 是否是 BTCChain 支持的链
+func IsUTXOChain(name string) bool {
+	_, ok := utxoCoins[ChainType(name)]
+	return ok
+}
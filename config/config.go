@@ -8,17 +8,85 @@ import (
 
 type Config struct {
 	MongoURI string
-	BTCRPC   string
 	SolRPC   string
 	Port     string
 	Eth      EthConfig
+	Btc      BtcConfig
+	Ltc      BtcConfig // litecoin，复用 BTC 的配置项
+	Doge     BtcConfig // dogecoin，复用 BTC 的配置项，address_type 只能是 p2pkh
 }
 
 type EthConfig struct {
 	RPC       string `mapstructure:"rpc"`
-	TestToken string `mapstructure:"test_token"`
+	TestToken string `mapstructure:"test_token"` // 测试网 token 合约地址，启动时自动读取元数据登记
 	ChainID   int64  `mapstructure:"chain_id"`
 	MainNet   bool   `mapstructure:"main_net"`
+	GasMargin uint64 `mapstructure:"gas_margin"` // EstimateGas 结果上加的余量，百分比
+	// 已知 ERC-20 token，启动时登记到 chain_id 对应网络的 token 表
+	Tokens []EthTokenConfig `mapstructure:"tokens"`
+	// speed-up / cancel 时费用至少上调的百分比，不低于节点的 --txpool.pricebump，默认 10
+	ReplacementBump uint64 `mapstructure:"replacement_bump"`
+
+	// 交易回执跟踪
+	TxSyncInterval int    `mapstructure:"tx_sync_interval"` // 轮询回执间隔(秒)，0 表示不启动
+	Confirmations  uint64 `mapstructure:"confirmations"`    // 达到该确认数后不再跟踪
+	DropAfter      int    `mapstructure:"drop_after"`       // 节点上找不到交易超过该秒数标记为 dropped
+
+	// 卡住交易 watchdog
+	WatchdogInterval int `mapstructure:"watchdog_interval"` // 检查间隔(秒)，0 表示不启动
+	StuckAfter       int `mapstructure:"stuck_after"`       // 广播后超过该秒数还没上链视为卡住，尝试加价
+
+	// NFT 持仓索引：按合约的 Transfer / TransferSingle / TransferBatch 日志
+	NFTCollections  []EthNFTConfig `mapstructure:"nft_collections"`
+	NFTSyncInterval int            `mapstructure:"nft_sync_interval"` // 索引间隔(秒)，0 表示不启动
+	IPFSGateway     string         `mapstructure:"ipfs_gateway"`      // 读取 ipfs:// 元数据用的网关，默认 https://ipfs.io/ipfs/
+
+	// 备用节点，按顺序排在 rpc 之后；出错、被限流或区块落后时切换到下一个
+	Endpoints      []EthEndpointConfig `mapstructure:"endpoints"`
+	RateLimit      float64             `mapstructure:"rate_limit"`      // 每个节点每秒最多请求数，0 表示不限
+	MaxBlockLag    uint64              `mapstructure:"max_block_lag"`   // 落后最高节点超过该区块数视为不健康，0 表示不检查
+	HealthInterval int                 `mapstructure:"health_interval"` // 健康检查间隔(秒)，0 表示不启动
+}
+
+type EthTokenConfig struct {
+	Symbol   string `mapstructure:"symbol"`
+	Name     string `mapstructure:"name"`
+	Address  string `mapstructure:"address"`
+	Decimals uint8  `mapstructure:"decimals"`
+}
+
+type EthNFTConfig struct {
+	Name      string `mapstructure:"name"`
+	Address   string `mapstructure:"address"`
+	Standard  string `mapstructure:"standard"`   // erc721 / erc1155
+	FromBlock uint64 `mapstructure:"from_block"` // 合约部署高度
+}
+
+type EthEndpointConfig struct {
+	Name      string  `mapstructure:"name"` // 调试时展示的名字，默认 scheme://host
+	URL       string  `mapstructure:"url"`
+	RateLimit float64 `mapstructure:"rate_limit"` // 覆盖 eth.rate_limit
+}
+
+type BtcConfig struct {
+	MainNet     bool   `mapstructure:"main_net"`
+	Network     string `mapstructure:"network"`      // main_net=false 时生效: testnet / regtest / signet，只对 btc 生效
+	AddressType string `mapstructure:"address_type"` // p2pkh / p2sh-p2wpkh / p2wpkh / p2tr
+	FeeRate     int64  `mapstructure:"fee_rate"`     // 节点无法估算费率时的默认费率 sat/vB
+	ConfTarget  int    `mapstructure:"conf_target"`  // 估算费率的默认确认目标 (区块数)
+	// 默认选币策略: bnb / largest_first / oldest_first / avoid_mixing
+	CoinSelection string `mapstructure:"coin_selection"`
+	DustLimit     int64  `mapstructure:"dust_limit"` // 找零低于该值 (satoshi) 时并入手续费
+	// 收到的输出低于该值 (satoshi) 时自动冻结，0 表示不自动冻结
+	FreezeDustBelow int64 `mapstructure:"freeze_dust_below"`
+
+	// 后端: bitcoind (JSON-RPC) 或 esplora (REST)
+	Backend      string `mapstructure:"backend"`
+	RPC          string `mapstructure:"rpc"`
+	RPCUser      string `mapstructure:"rpc_user"`
+	RPCPass      string `mapstructure:"rpc_pass"`
+	EsploraURL   string `mapstructure:"esplora_url"`
+	SyncInterval int    `mapstructure:"sync_interval"` // UTXO 同步间隔(秒)，0 表示不启动后台同步
 }
 
 func Load(path string) (*Config, error) {
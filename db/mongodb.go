@@ -15,8 +15,8 @@ const (
 	dbName = "wallet_service"
 )
 
-func InitMongo() {}
-func init() {
+// InitMongo 连接数据库；不在 init 里连，离线签名模式不碰网络
+func InitMongo() {
 	ctx := context.Background()
 	var err error
 	MongoDB, err = NewMongoRepo(ctx, uri, dbName)
@@ -26,12 +26,21 @@ func init() {
 }
 
 type MongoRepo struct {
-	Client     *mongo.Client
-	DB         *mongo.Database
-	WalletColl *mongo.Collection
-	AssetColl  *mongo.Collection
-	SubColl    *mongo.Collection
-	AddrColl   *mongo.Collection
+	Client      *mongo.Client
+	DB          *mongo.Database
+	WalletColl  *mongo.Collection
+	AssetColl   *mongo.Collection
+	SubColl     *mongo.Collection
+	AddrColl    *mongo.Collection
+	UTXOColl    *mongo.Collection
+	BTCTxColl   *mongo.Collection
+	BTCPSBTColl *mongo.Collection
+	NonceColl   *mongo.Collection
+	TxColl      *mongo.Collection
+	TxEventColl *mongo.Collection
+	TokenColl   *mongo.Collection
+	NFTColl     *mongo.Collection
+	NFTSyncColl *mongo.Collection
 }
 
 func NewMongoRepo(ctx context.Context, uri, dbName string) (*MongoRepo, error) {
@@ -48,11 +57,20 @@ func NewMongoRepo(ctx context.Context, uri, dbName string) (*MongoRepo, error) {
 	}
 	db := client.Database(dbName)
 	return &MongoRepo{
-		Client:     client,
-		DB:         db,
-		WalletColl: db.Collection("wallets"),
-		AssetColl:  db.Collection("assets"),
-		SubColl:    db.Collection("subscriptions"),
-		AddrColl:   db.Collection("addresses"),
+		Client:      client,
+		DB:          db,
+		WalletColl:  db.Collection("wallets"),
+		AssetColl:   db.Collection("assets"),
+		SubColl:     db.Collection("subscriptions"),
+		AddrColl:    db.Collection("addresses"),
+		UTXOColl:    db.Collection("utxos"),
+		BTCTxColl:   db.Collection("btc_txs"),
+		BTCPSBTColl: db.Collection("btc_psbts"),
+		NonceColl:   db.Collection("nonces"),
+		TxColl:      db.Collection("transactions"),
+		TxEventColl: db.Collection("tx_events"),
+		TokenColl:   db.Collection("tokens"),
+		NFTColl:     db.Collection("nft_holdings"),
+		NFTSyncColl: db.Collection("nft_sync"),
 	}, nil
 }
@@ -25,6 +25,7 @@ import (
 	"golang.org/x/crypto/pbkdf2"
 
 	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	walletErr "github.com/linlinbupt123-crypto/wallet_service/errors"
 	"github.com/linlinbupt123-crypto/wallet_service/repository"
 	"github.com/linlinbupt123-crypto/wallet_service/utils"
 )
@@ -357,6 +358,38 @@ func (s *HDWallet) DeriveETHKeyPair(seed []byte, path string) (*ecdsa.PrivateKey
 	return ecdsaKey, addr.Hex(), nil
 }
 
+// ETHPrivateKey decrypts the ETH private key of a wallet: hd wallets derive it at path,
+// imported wallets decrypt the stored key and ignore path.
+func (s *HDWallet) ETHPrivateKey(wallet *entity.Wallet, path, passphrase string) (*ecdsa.PrivateKey, error) {
+	switch wallet.WalletType {
+	case "hd":
+		seed, err := s.DecryptSeed(wallet, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		defer clearBytes(seed)
+		privKey, _, err := s.DeriveETHKeyPair(seed, path)
+		if err != nil {
+			return nil, walletErr.WrapWithCode(walletErr.DeriveErr, "DeriveETHKeyPair", err)
+		}
+		return privKey, nil
+
+	case "imported":
+		key, err := utils.DeriveAESKey(passphrase, wallet.SaltHex)
+		if err != nil {
+			return nil, err
+		}
+		privKeyBytes, err := utils.DecryptAES(wallet.CipherKey, key)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.HexToECDSA(strings.TrimPrefix(string(privKeyBytes), "0x"))
+
+	default:
+		return nil, errors.New("unsupported wallet type")
+	}
+}
+
 // parseDerivationPath accepts "m/44'/60'/0'/0/0" or "44'/60'/0'/0/0"
 func parseDerivationPath(path string) ([]uint32, error) {
 	p := strings.TrimSpace(path)
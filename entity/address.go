@@ -5,12 +5,14 @@ import (
 )
 
 type Address struct {
-	ID        string    `bson:"_id,omitempty" json:"id"`
-	UserID    string    `bson:"user_id" json:"user_id"`
-	WalletID  string    `bson:"wallet_id" json:"wallet_id"`
-	Chain     string    `bson:"chain" json:"chain"`     // btc / eth / solana
-	Address   string    `bson:"address" json:"address"` // 主地址
-	Index     uint32    `bson:"index" json:"index"`     // 派生索引
-	Source    string    `bson:"source"`                 // "imported"
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	ID          string    `bson:"_id,omitempty" json:"id"`
+	UserID      string    `bson:"user_id" json:"user_id"`
+	WalletID    string    `bson:"wallet_id" json:"wallet_id"`
+	Chain       string    `bson:"chain" json:"chain"`                                   // btc / eth / solana
+	Address     string    `bson:"address" json:"address"`                               // 主地址
+	Index       uint32    `bson:"index" json:"index"`                                   // 派生索引
+	Change      uint32    `bson:"change,omitempty" json:"change,omitempty"`             // 0 外部接收链, 1 内部找零链
+	AddressType string    `bson:"address_type,omitempty" json:"address_type,omitempty"` // btc: p2pkh / p2sh-p2wpkh / p2wpkh / p2tr / p2wsh
+	Source      string    `bson:"source"`                                               // "imported"
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
 }
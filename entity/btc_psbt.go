@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// PSBT 状态
+const (
+	BTCPSBTSigning   = "signing"   // 等待签名
+	BTCPSBTBroadcast = "broadcast" // 已 finalize 并广播
+	BTCPSBTAbandoned = "abandoned" // 用户取消，输入已解冻
+)
+
+// BTCPSBT 等待多方签名的 PSBT
+type BTCPSBT struct {
+	ID       string        `bson:"_id,omitempty" json:"id"`
+	UserID   string        `bson:"user_id" json:"user_id"`
+	WalletID string        `bson:"wallet_id" json:"wallet_id"`
+	PSBT     string        `bson:"psbt" json:"psbt"` // base64，合并签名后更新
+	Inputs   []BTCTxInput  `bson:"inputs" json:"inputs"`
+	Outputs  []BTCTxOutput `bson:"outputs" json:"outputs"`   // 发起时的输出
+	Fee      int64         `bson:"fee" json:"fee"`           // satoshi
+	FeeRate  int64         `bson:"fee_rate" json:"fee_rate"` // sat/vB
+	Signers  []string      `bson:"signers" json:"signers"`   // 已签名的 master fingerprint
+	Status   string        `bson:"status" json:"status"`
+	TxID     string        `bson:"txid,omitempty" json:"txid,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,54 @@
+package entity
+
+import "time"
+
+// BTC 交易状态
+const (
+	BTCTxPending   = "pending"   // 已广播，等待确认
+	BTCTxConfirmed = "confirmed" // 已上链，确认数继续更新到 BTCTxFinalConfirmations
+	BTCTxReplaced  = "replaced"  // 已被 RBF 替换，或输入被其他交易花掉
+	BTCTxDropped   = "dropped"   // 不在 mempool 也不在链上，输入仍未花费
+)
+
+// BTCTxFinalConfirmations 达到该确认数后不再跟踪
+const BTCTxFinalConfirmations = 6
+
+// BTCTx 本服务发出的 BTC / LTC / DOGE 交易，BTC 交易可以 RBF / CPFP 加速
+type BTCTx struct {
+	ID       string        `bson:"_id,omitempty" json:"id"`
+	Chain    string        `bson:"chain,omitempty" json:"chain,omitempty"` // 老记录为空，按 btc 处理
+	UserID   string        `bson:"user_id" json:"user_id"`
+	WalletID string        `bson:"wallet_id" json:"wallet_id"`
+	TxID     string        `bson:"txid" json:"txid"`
+	Inputs   []BTCTxInput  `bson:"inputs" json:"inputs"`
+	Outputs  []BTCTxOutput `bson:"outputs" json:"outputs"`   // 与链上 vout 顺序一致
+	Fee      int64         `bson:"fee" json:"fee"`           // satoshi
+	FeeRate  int64         `bson:"fee_rate" json:"fee_rate"` // sat/vB
+	VSize    int64         `bson:"vsize" json:"vsize"`
+	RawTx    string        `bson:"raw_tx" json:"raw_tx"` // hex
+	Status   string        `bson:"status" json:"status"`
+
+	BlockHeight   int64 `bson:"block_height,omitempty" json:"block_height,omitempty"`
+	Confirmations int64 `bson:"confirmations" json:"confirmations"`
+
+	ReplacesTxID   string `bson:"replaces_txid,omitempty" json:"replaces_txid,omitempty"`       // RBF: 被本交易替换的交易
+	ReplacedByTxID string `bson:"replaced_by_txid,omitempty" json:"replaced_by_txid,omitempty"` // RBF: 替换本交易的交易
+	ParentTxID     string `bson:"parent_txid,omitempty" json:"parent_txid,omitempty"`           // CPFP: 被加速的父交易
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type BTCTxInput struct {
+	TxID    string `bson:"txid" json:"txid"`
+	Vout    uint32 `bson:"vout" json:"vout"`
+	Address string `bson:"address" json:"address"`
+	Value   int64  `bson:"value" json:"value"`
+	Height  int64  `bson:"height" json:"height"` // 前序交易高度，legacy 输入重新签名时要用
+}
+
+type BTCTxOutput struct {
+	Address string `bson:"address" json:"address"`
+	Value   int64  `bson:"value" json:"value"`
+	Change  bool   `bson:"change" json:"change"` // 是否为本钱包的找零输出
+}
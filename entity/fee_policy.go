@@ -0,0 +1,10 @@
+package entity
+
+// FeePolicy 钱包的 ETH 卡住交易自动加价策略。
+// 后台 watchdog 拿不到私钥，开启后发送交易时会按这里的上限预签好加价阶梯
+type FeePolicy struct {
+	AutoBump     bool   `bson:"auto_bump" json:"auto_bump"`
+	MaxFeePerGas string `bson:"max_fee_per_gas" json:"max_fee_per_gas"` // wei，max fee (非 London 为 gas price) 的上限
+	BumpPercent  uint64 `bson:"bump_percent" json:"bump_percent"`       // 每级上调的百分比，不低于 eth.replacement_bump
+	MaxBumps     int    `bson:"max_bumps" json:"max_bumps"`             // 最多加价次数
+}
@@ -8,13 +8,16 @@ type Wallet struct {
 	ID         string `bson:"_id,omitempty"`
 	UserID     string `bson:"user_id"`
 	WalletName string `bson:"wallet_name"`
-	WalletType string `bson:"wallet_type"` // "hd" / "imported"
+	WalletType string `bson:"wallet_type"` // "hd" / "imported" / "multisig"
 
 	// HD 类型相关
 	MnemonicEncrypted []byte `bson:"mnemonic_encrypted"`
 	EncryptedSeed     []byte `bson:"encrypted_seed"`
 	XPrvEncrypted     []byte `bson:"xprv_encrypted"`
 	XPub              string `bson:"xpub"`
+	BTCAddressType    string `bson:"btc_address_type,omitempty"` // 该钱包默认的 BTC 地址类型
+	// 账户路径 (m/84'/0'/0') -> 账户扩展公钥，导出离线钱包时保存，构造离线签名请求时不用解密 seed
+	BTCAccountXPubs map[string]string `bson:"btc_account_xpubs,omitempty"`
 
 	// common 字段
 	SaltHex string `bson:"salt_hex"`
@@ -22,5 +25,14 @@ type Wallet struct {
 	// Imported 类型相关
 	CipherKey []byte `bson:"cipher_key,omitempty"` // 加密私钥
 
+	// Multisig 类型相关
+	Multisig *MultisigPolicy `bson:"multisig,omitempty"`
+
+	// Timelock 类型相关
+	Timelock *TimelockPolicy `bson:"timelock,omitempty"`
+
+	// ETH 卡住交易的自动加价策略，nil 表示只重新广播不加价
+	FeePolicy *FeePolicy `bson:"fee_policy,omitempty"`
+
 	CreatedAt time.Time `bson:"created_at"`
 }
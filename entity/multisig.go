@@ -0,0 +1,18 @@
+package entity
+
+// MultisigPolicy m-of-n sorted multisig (P2WSH) 钱包的策略
+type MultisigPolicy struct {
+	Threshold      int        `bson:"threshold" json:"threshold"`
+	Cosigners      []Cosigner `bson:"cosigners" json:"cosigners"`
+	SignerWalletID string     `bson:"signer_wallet_id" json:"signer_wallet_id"` // 持有本地 cosigner 私钥的 HD 钱包
+	Account        uint32     `bson:"account" json:"account"`                   // 本地 cosigner 的 BIP48 account
+}
+
+// Cosigner multisig 的一个参与方
+type Cosigner struct {
+	Name        string `bson:"name" json:"name"`
+	Fingerprint string `bson:"fingerprint" json:"fingerprint"` // master fingerprint, 8 位 hex
+	Path        string `bson:"path" json:"path"`               // 账户路径，例如 m/48'/0'/0'/2'
+	XPub        string `bson:"xpub" json:"xpub"`               // 账户扩展公钥
+	Local       bool   `bson:"local" json:"local"`             // 本服务持有私钥的一方
+}
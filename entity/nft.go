@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// NFTHolding 托管地址持有的 NFT，由转移日志索引维护；ERC-721 的 amount 恒为 1
+type NFTHolding struct {
+	ID         string `bson:"_id,omitempty" json:"-"`
+	UserID     string `bson:"user_id" json:"user_id"`
+	Owner      string `bson:"owner" json:"owner"`
+	Collection string `bson:"collection" json:"collection"` // 合约地址
+	Standard   string `bson:"standard" json:"standard"`     // erc721 / erc1155
+	TokenID    string `bson:"token_id" json:"token_id"`     // 十进制
+	Amount     string `bson:"amount" json:"amount"`
+
+	// 最后一次生效的日志位置，重放同一段区块时跳过已经算过的日志
+	LastBlock      uint64 `bson:"last_block" json:"last_block"`
+	LastLogIndex   uint   `bson:"last_log_index" json:"-"`
+	LastBatchIndex int    `bson:"last_batch_index" json:"-"`
+
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// NFTSyncState 合约日志已经索引到的区块
+type NFTSyncState struct {
+	Collection string    `bson:"collection"`
+	LastBlock  uint64    `bson:"last_block"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// NonceAccount 一个 (chain, address) 的 nonce 分配状态，分配时用 lock_owner / locked_until 做数据库锁
+type NonceAccount struct {
+	ID          string     `bson:"_id,omitempty" json:"id"`
+	Chain       string     `bson:"chain" json:"chain"`
+	Address     string     `bson:"address" json:"address"`
+	Next        uint64     `bson:"next" json:"next"`       // 下一个分配的 nonce
+	Pending     []NonceTx  `bson:"pending" json:"pending"` // 已分配、链上还没确认的 nonce
+	LockOwner   string     `bson:"lock_owner,omitempty" json:"-"`
+	LockedUntil time.Time  `bson:"locked_until" json:"-"`
+	SyncedAt    *time.Time `bson:"synced_at,omitempty" json:"synced_at,omitempty"` // 上次与节点对账的时间
+	CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+// NonceTx 某个 nonce 上发出的交易
+type NonceTx struct {
+	Nonce     uint64    `bson:"nonce" json:"nonce"`
+	TxHash    string    `bson:"tx_hash" json:"tx_hash"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
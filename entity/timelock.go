@@ -0,0 +1,12 @@
+package entity
+
+// TimelockPolicy "primary 随时可花，或 recovery 在 Older 个区块后可花" 的 BTC 钱包策略，
+// miniscript: or_d(pk(primary),and_v(v:pk(recovery),older(N)))
+type TimelockPolicy struct {
+	ScriptType     string   `bson:"script_type" json:"script_type"` // p2wsh / p2tr
+	Older          uint32   `bson:"older" json:"older"`             // 相对时间锁，区块数
+	Primary        Cosigner `bson:"primary" json:"primary"`         // 本地 HD 钱包的 BIP48 账户
+	Recovery       Cosigner `bson:"recovery" json:"recovery"`       // 继承人 / 冷钱包的 key
+	SignerWalletID string   `bson:"signer_wallet_id" json:"signer_wallet_id"`
+	Account        uint32   `bson:"account" json:"account"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// token 来源
+const (
+	TokenSourceConfig     = "config"     // eth.tokens / eth.test_token
+	TokenSourceDiscovered = "discovered" // 第一次用到时从合约读取的元数据
+)
+
+// Token 某个网络 (chain id) 上的 ERC-20 合约
+type Token struct {
+	ID        string    `bson:"_id,omitempty" json:"-"`
+	ChainID   int64     `bson:"chain_id" json:"chain_id"`
+	Address   string    `bson:"address" json:"address"` // checksum
+	Symbol    string    `bson:"symbol" json:"symbol"`
+	Name      string    `bson:"name,omitempty" json:"name,omitempty"`
+	Decimals  uint8     `bson:"decimals" json:"decimals"`
+	Source    string    `bson:"source" json:"source"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,75 @@
+package entity
+
+import "time"
+
+// 交易状态
+const (
+	TxSigned    = "signed"    // broadcast=false 只签名未广播，nonce 已占用，等 raw_tx 通过广播接口发出
+	TxPending   = "pending"   // 已广播，还没有回执
+	TxConfirmed = "confirmed" // 已上链且执行成功
+	TxReverted  = "reverted"  // 已上链但执行失败，gas 照扣
+	TxDropped   = "dropped"   // 节点上找不到了，或者 nonce 已被别的交易用掉
+	TxReplaced  = "replaced"  // 同 nonce 的 speed-up / cancel 交易上链了
+)
+
+// Transaction 本服务发出的 ETH 交易
+type Transaction struct {
+	ID       string `bson:"_id,omitempty" json:"id"`
+	UserID   string `bson:"user_id" json:"user_id"`
+	WalletID string `bson:"wallet_id" json:"wallet_id"`
+	Chain    string `bson:"chain" json:"chain"`
+	From     string `bson:"from" json:"from"`
+	To       string `bson:"to" json:"to"`
+	Value    string `bson:"value" json:"value"` // wei
+	Data     string `bson:"data,omitempty" json:"data,omitempty"`
+	Nonce    uint64 `bson:"nonce" json:"nonce"`
+	Hash     string `bson:"hash" json:"hash"`
+	RawTx    string `bson:"raw_tx" json:"raw_tx"` // 已签名交易 hex
+
+	// ERC-20 transfer: 从 calldata 解出的收款地址和金额 (最小单位)，此时 to 是合约地址
+	TokenTo     string `bson:"token_to,omitempty" json:"token_to,omitempty"`
+	TokenAmount string `bson:"token_amount,omitempty" json:"token_amount,omitempty"`
+
+	// 部署合约的交易 (to 为空)：按 from + nonce 算出的合约地址
+	ContractAddress string `bson:"contract_address,omitempty" json:"contract_address,omitempty"`
+
+	// 费用参数，legacy / access list 交易只有 gas_price
+	TxType               uint8  `bson:"tx_type" json:"tx_type"`
+	GasLimit             uint64 `bson:"gas_limit" json:"gas_limit"`
+	GasPrice             string `bson:"gas_price,omitempty" json:"gas_price,omitempty"`
+	MaxFeePerGas         string `bson:"max_fee_per_gas,omitempty" json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas string `bson:"max_priority_fee_per_gas,omitempty" json:"max_priority_fee_per_gas,omitempty"`
+
+	Status string `bson:"status" json:"status"`
+
+	// speed-up / cancel: 同一个 nonce 上的替换关系
+	ReplacesHash   string `bson:"replaces_hash,omitempty" json:"replaces_hash,omitempty"`
+	ReplacedByHash string `bson:"replaced_by_hash,omitempty" json:"replaced_by_hash,omitempty"`
+	Cancel         bool   `bson:"cancel,omitempty" json:"cancel,omitempty"` // 取消原交易的 0 ETH 自转账
+
+	// 回执，上链后由 tracker 填写
+	BlockNumber       uint64     `bson:"block_number,omitempty" json:"block_number,omitempty"`
+	BlockHash         string     `bson:"block_hash,omitempty" json:"block_hash,omitempty"`
+	GasUsed           uint64     `bson:"gas_used,omitempty" json:"gas_used,omitempty"`
+	EffectiveGasPrice string     `bson:"effective_gas_price,omitempty" json:"effective_gas_price,omitempty"`
+	Fee               string     `bson:"fee,omitempty" json:"fee,omitempty"` // gas_used * effective_gas_price, wei
+	Confirmations     uint64     `bson:"confirmations" json:"confirmations"`
+	ConfirmedAt       *time.Time `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+
+	// watchdog: 最近一次广播时间，以及发送时按钱包费用策略预签好的加价交易 (费用从低到高)
+	BroadcastAt   time.Time      `bson:"broadcast_at" json:"broadcast_at"`
+	Escalations   []TxEscalation `bson:"escalations,omitempty" json:"escalations,omitempty"`
+	StuckReported bool           `bson:"stuck_reported,omitempty" json:"-"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// TxEscalation 一级预签的加价交易
+type TxEscalation struct {
+	Hash                 string `bson:"hash" json:"hash"`
+	RawTx                string `bson:"raw_tx" json:"-"`
+	GasPrice             string `bson:"gas_price,omitempty" json:"gas_price,omitempty"`
+	MaxFeePerGas         string `bson:"max_fee_per_gas,omitempty" json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas string `bson:"max_priority_fee_per_gas,omitempty" json:"max_priority_fee_per_gas,omitempty"`
+}
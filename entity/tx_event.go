@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// watchdog 事件类型
+const (
+	TxEventStuck          = "stuck"           // 超过 stuck_after 还没上链
+	TxEventRebroadcast    = "rebroadcast"     // 从 mempool 消失，重新广播原交易
+	TxEventFeeBumped      = "fee_bumped"      // 广播了预签的加价交易
+	TxEventCeilingReached = "ceiling_reached" // 加价阶梯用完 (到了钱包的费用上限)，需要人工处理
+	TxEventFailed         = "failed"          // 重新广播或加价失败
+)
+
+// TxEvent watchdog 对一笔交易做的每一步处理
+type TxEvent struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	UserID    string    `bson:"user_id" json:"user_id"`
+	Chain     string    `bson:"chain" json:"chain"`
+	From      string    `bson:"from" json:"from"`
+	Nonce     uint64    `bson:"nonce" json:"nonce"`
+	TxHash    string    `bson:"tx_hash" json:"tx_hash"`
+	Type      string    `bson:"type" json:"type"`
+	Detail    string    `bson:"detail,omitempty" json:"detail,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// FrozenReasonDust 同步时自动冻结的小额输出
+const FrozenReasonDust = "dust"
+
+// UTXO 受管 BTC 地址上的一个输出 (txid:vout)
+type UTXO struct {
+	ID            string     `bson:"_id,omitempty" json:"id"`
+	UserID        string     `bson:"user_id" json:"user_id"`
+	WalletID      string     `bson:"wallet_id" json:"wallet_id"`
+	Chain         string     `bson:"chain" json:"chain"`
+	Address       string     `bson:"address" json:"address"`
+	TxID          string     `bson:"txid" json:"txid"`
+	Vout          uint32     `bson:"vout" json:"vout"`
+	Value         int64      `bson:"value" json:"value"`         // satoshi
+	PkScript      string     `bson:"pk_script" json:"pk_script"` // hex
+	Height        int64      `bson:"height" json:"height"`       // 0 表示未确认
+	Confirmations int64      `bson:"confirmations" json:"confirmations"`
+	Spent         bool       `bson:"spent" json:"spent"`
+	Frozen        bool       `bson:"frozen" json:"frozen"` // 冻结的输出不参与选币
+	FrozenReason  string     `bson:"frozen_reason,omitempty" json:"frozen_reason,omitempty"`
+	FrozenAt      *time.Time `bson:"frozen_at,omitempty" json:"frozen_at,omitempty"`
+	SpentAt       *time.Time `bson:"spent_at,omitempty" json:"spent_at,omitempty"`
+	CreatedAt     time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `bson:"updated_at" json:"updated_at"`
+}
@@ -1,18 +1,35 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/linlinbupt123-crypto/wallet_service/api"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
 	"github.com/linlinbupt123-crypto/wallet_service/config"
 	"github.com/linlinbupt123-crypto/wallet_service/db"
 	"github.com/linlinbupt123-crypto/wallet_service/domain"
+	"github.com/linlinbupt123-crypto/wallet_service/offline"
 	"github.com/linlinbupt123-crypto/wallet_service/repository"
 	"github.com/linlinbupt123-crypto/wallet_service/service"
 )
 
 func main() {
+	// 离线签名模式：不连数据库和节点，签完退出
+	if len(os.Args) > 1 && os.Args[1] == "offline" {
+		cfg, err := config.Load("config/config.yaml")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := offline.Run(os.Args[2:], cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// 1. 初始化 MongoDB
 	db.InitMongo()
 
@@ -20,17 +37,88 @@ func main() {
 	hdDomain := domain.NewHDWallet()
 	walletRepo := repository.NewWalletRepo()
 	addressRepo := repository.NewAddressRepo()
+	utxoRepo := repository.NewUTXORepo()
+	btcTxRepo := repository.NewBTCTxRepo()
+	btcPSBTRepo := repository.NewBTCPSBTRepo()
+	nonceRepo := repository.NewNonceRepo()
+	transactionRepo := repository.NewTransactionRepo()
+	txEventRepo := repository.NewTxEventRepo()
+	tokenRepo := repository.NewTokenRepo()
+	nftRepo := repository.NewNFTRepo()
 	cfg, err := config.Load("config/config.yaml")
 	if err != nil {
 		log.Fatal(err)
 	}
+	// 索引由 script/mongodb 创建，正确性依赖唯一索引的 repo 在这里检查
+	if err := addressRepo.CheckIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if err := nonceRepo.CheckIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if err := transactionRepo.CheckIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if err := tokenRepo.CheckIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if err := nftRepo.CheckIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 
-	walletService := service.NewWalletService(
+	walletService, err := service.NewWalletService(
 		hdDomain,
 		walletRepo,
 		addressRepo,
+		utxoRepo,
+		btcTxRepo,
+		btcPSBTRepo,
+		nonceRepo,
+		transactionRepo,
+		txEventRepo,
+		tokenRepo,
+		nftRepo,
 		cfg.Eth,
+		cfg.Btc,
+		cfg.Ltc,
+		cfg.Doge,
 	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// BTC / LTC / DOGE UTXO 后台同步，间隔分别用各链的 sync_interval
+	for name, c := range map[chain.ChainType]config.BtcConfig{chain.BTC: cfg.Btc, chain.LTC: cfg.Ltc, chain.DOGE: cfg.Doge} {
+		if c.SyncInterval > 0 {
+			walletService.StartBTCUTXOTracker(context.Background(), name, time.Duration(c.SyncInterval)*time.Second)
+		}
+	}
+
+	// ETH RPC 节点健康检查，落后或不可用的节点会被排到后面
+	if cfg.Eth.HealthInterval > 0 {
+		walletService.StartETHHealthCheck(context.Background(), time.Duration(cfg.Eth.HealthInterval)*time.Second)
+	}
+
+	// 登记配置里的 ERC-20 token，test_token 从链上读取元数据
+	walletService.RegisterETHTokens(context.Background(), cfg.Eth.Tokens)
+
+	// 本地 nonce 和节点对账，发现空洞打日志
+	walletService.ReconcileETHNonces(context.Background())
+
+	// ETH 交易回执跟踪
+	if cfg.Eth.TxSyncInterval > 0 {
+		walletService.StartETHTxTracker(context.Background(), time.Duration(cfg.Eth.TxSyncInterval)*time.Second)
+	}
+
+	// 卡住交易 watchdog：重新广播、按钱包费用策略自动加价
+	if cfg.Eth.WatchdogInterval > 0 {
+		walletService.StartETHWatchdog(context.Background(), time.Duration(cfg.Eth.WatchdogInterval)*time.Second)
+	}
+
+	// NFT 持仓索引
+	if cfg.Eth.NFTSyncInterval > 0 {
+		walletService.StartNFTIndexer(context.Background(), time.Duration(cfg.Eth.NFTSyncInterval)*time.Second)
+	}
 
 	// 3. Gin
 	r := gin.Default()
@@ -44,14 +132,93 @@ func main() {
 	r.GET("/wallet/:userID/addresses", walletHandler.GetAddresses)
 
 	// derive new address
-	r.POST("/wallet/:userID/address/new", walletHandler.DeriveAddress) // ?chain=btc
+	r.POST("/wallet/:userID/address/new", walletHandler.DeriveAddress) // chain_name=btc|ltc|doge, address_type=p2wpkh
 
 	// send transaction
 	r.POST("/wallet/:userID/tx/send", walletHandler.SendTransaction)
+	r.GET("/wallet/:userID/tx/:hash", walletHandler.GetTransaction) // eth tx record with receipt
+	r.POST("/wallet/:userID/tx/:hash/speedup", walletHandler.SpeedUpETHTx)
+	r.POST("/wallet/:userID/tx/:hash/cancel", walletHandler.CancelETHTx)
+	r.GET("/wallet/:userID/tx/:hash/events", walletHandler.ETHTxEvents)      // stuck-tx watchdog events
+	r.POST("/wallet/:userID/eth/tx/broadcast", walletHandler.BroadcastETHTx) // raw signed tx hex, e.g. from tx/send with broadcast=false
+	r.GET("/wallet/:userID/eth/fee-policy/:walletID", walletHandler.GetETHFeePolicy)
+	r.POST("/wallet/:userID/eth/fee-policy/:walletID", walletHandler.SetETHFeePolicy)
+
+	// btc fee estimate
+	r.GET("/btc/fee", walletHandler.EstimateBTCFee) // ?conf_target=6
+
+	// bump an unconfirmed btc tx
+	r.POST("/wallet/:userID/btc/tx/:txid/bump", walletHandler.BumpBTCFee) // method=rbf|cpfp
+
+	// btc coin control
+	r.GET("/wallet/:userID/btc/utxos", walletHandler.ListBTCUTXOs) // ?chain=ltc&include_spent=true
+	r.POST("/wallet/:userID/btc/utxos/:txid/:vout/freeze", walletHandler.FreezeBTCUTXO)
+	r.POST("/wallet/:userID/btc/utxos/:txid/:vout/unfreeze", walletHandler.UnfreezeBTCUTXO)
+
+	// btc watch-only export: descriptors + xpub/ypub/zpub
+	r.POST("/wallet/:userID/btc/export", walletHandler.ExportBTCAccounts)
+
+	// btc message signing: legacy signmessage for p2pkh, bip322 for segwit / taproot
+	r.POST("/wallet/:userID/btc/message/sign", walletHandler.SignBTCMessage)
+	r.POST("/btc/message/verify", walletHandler.VerifyBTCMessage) // chain=btc|ltc|doge
+
+	// btc multisig (p2wsh sortedmulti)
+	r.POST("/wallet/:userID/multisig", walletHandler.CreateMultisigWallet)
+	r.GET("/wallet/:userID/multisig/:walletID", walletHandler.GetMultisigWallet)
+	r.POST("/wallet/:userID/multisig/:walletID/address/new", walletHandler.DeriveMultisigAddress)
+	r.POST("/wallet/:userID/multisig/:walletID/spend", walletHandler.CreateMultisigSpend)
+
+	// btc timelocked recovery (miniscript or_d(pk,and_v(v:pk,older)))
+	r.POST("/wallet/:userID/timelock", walletHandler.CreateTimelockWallet)
+	r.GET("/wallet/:userID/timelock/:walletID", walletHandler.GetTimelockWallet)
+	r.POST("/wallet/:userID/timelock/:walletID/address/new", walletHandler.DeriveTimelockAddress)
+	r.POST("/wallet/:userID/timelock/:walletID/spend", walletHandler.CreateTimelockSpend)
+	r.POST("/wallet/:userID/timelock/:walletID/recover", walletHandler.CreateTimelockRecovery)
+
+	// btc psbt: offline / third-party signing
+	r.POST("/wallet/:userID/btc/psbt", walletHandler.CreateBTCPSBT)
+	r.GET("/wallet/:userID/btc/psbt/:id", walletHandler.GetBTCPSBT)
+	r.POST("/wallet/:userID/btc/psbt/:id/export", walletHandler.ExportBTCPSBT)
+	r.POST("/wallet/:userID/btc/psbt/:id/import", walletHandler.ImportBTCPSBT)
+	r.POST("/wallet/:userID/btc/psbt/:id/finalize", walletHandler.FinalizeBTCPSBT)
+	r.POST("/wallet/:userID/btc/psbt/:id/cancel", walletHandler.CancelBTCPSBT)
+
+	// air-gapped signing: json / ur payloads, signed by `wallet_service offline sign`
+	r.POST("/wallet/:userID/offline/wallet/:walletID/export", walletHandler.ExportOfflineWallet)
+	r.POST("/wallet/:userID/offline/eth", walletHandler.CreateOfflineETHRequest)
+	r.POST("/wallet/:userID/offline/btc", walletHandler.CreateOfflineBTCRequest)
+	r.POST("/wallet/:userID/offline/import", walletHandler.ImportOfflinePayload)
 
 	// get balance
 	r.GET("/wallet/:userID/balance", walletHandler.GetBalance)
 
+	// eth nonce manager: gaps left by dropped transactions
+	r.GET("/wallet/:userID/eth/nonce/:address", walletHandler.ETHNonceStatus)
+	r.POST("/wallet/:userID/eth/nonce/:address/fill", walletHandler.FillETHNonceGaps)
+	r.POST("/wallet/:userID/eth/nonce/:address/cancel", walletHandler.CancelETHNonceGaps)
+
+	// eth rpc endpoints status, for debugging failover
+	r.GET("/eth/endpoints", walletHandler.ETHEndpoints)
+
+	// NFT (ERC-721 / ERC-1155)
+	r.GET("/wallet/:userID/nfts", walletHandler.ListNFTs)
+	r.POST("/wallet/:userID/nft/send", walletHandler.SendNFT)
+	r.GET("/eth/nft/:collection/:tokenID", walletHandler.GetNFTMetadata) // tokenURI / uri and the resolved metadata json
+
+	// 合约调用 / 部署
+	r.POST("/eth/contract/call", walletHandler.CallContract)
+	r.POST("/wallet/:userID/contract/write", walletHandler.WriteContract)
+	r.POST("/wallet/:userID/contract/deploy", walletHandler.DeployContract)
+
+	// personal_sign / eth_signTypedData_v4
+	r.POST("/wallet/:userID/eth/message/sign", walletHandler.SignETHMessage)
+	r.POST("/wallet/:userID/eth/typed-data/sign", walletHandler.SignETHTypedData)
+	r.POST("/eth/message/verify", walletHandler.VerifyETHMessage) // message or typed_data
+
+	// ERC-20 token 登记表
+	r.GET("/eth/tokens", walletHandler.ListETHTokens)
+	r.POST("/eth/tokens", walletHandler.AddETHToken) // register by contract address, metadata read from chain
+
 	// import wallet
 	r.POST("/wallet/:userID/import", walletHandler.ImportWallet)
 
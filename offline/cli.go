@@ -0,0 +1,129 @@
+package offline
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/config"
+)
+
+const usage = `usage: wallet_service offline sign -wallet <export.json> [-in <request>] [-out <file>] [-format json|ur] [-fragment n] [-yes]
+
+Signs an eth / btc sign request (JSON, or UR parts separated by whitespace) with an exported wallet.
+Never opens a network or database connection. The passphrase is read from WALLET_PASSPHRASE or the first line of stdin.`
+
+// Run 离线模式入口：wallet_service offline <command> ...
+func Run(args []string, cfg *config.Config) error {
+	if len(args) == 0 || args[0] != "sign" {
+		return errors.New(usage)
+	}
+
+	fs := flag.NewFlagSet("offline sign", flag.ContinueOnError)
+	walletPath := fs.String("wallet", "", "encrypted wallet export")
+	in := fs.String("in", "-", "sign request file, - for stdin")
+	out := fs.String("out", "-", "signed payload file, - for stdout")
+	format := fs.String("format", "json", "output format: json / ur")
+	fragment := fs.Int("fragment", DefaultURFragment, "max bytes per ur part")
+	yes := fs.Bool("yes", false, "sign without asking for confirmation")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *walletPath == "" {
+		return errors.New(usage)
+	}
+	if *format != "json" && *format != "ur" {
+		return fmt.Errorf("unsupported format %s", *format)
+	}
+
+	data, err := os.ReadFile(*walletPath)
+	if err != nil {
+		return err
+	}
+	wallet, err := ParseWalletExport(data)
+	if err != nil {
+		return err
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	passphrase, hasPassphrase := os.LookupEnv("WALLET_PASSPHRASE")
+	if *in == "-" && (!hasPassphrase || !*yes) {
+		return errors.New("reading the request from stdin needs WALLET_PASSPHRASE and -yes")
+	}
+	var text []byte
+	if *in == "-" {
+		text, err = io.ReadAll(stdin)
+	} else {
+		text, err = os.ReadFile(*in)
+	}
+	if err != nil {
+		return err
+	}
+	req, err := ParsePayload(string(text))
+	if err != nil {
+		return err
+	}
+
+	// 离线端没有后端，地址类型和网络按本地配置
+	btc, err := chain.NewUTXOChain(chain.BTC, config.BtcConfig{
+		MainNet:     cfg.Btc.MainNet,
+		Network:     cfg.Btc.Network,
+		AddressType: cfg.Btc.AddressType,
+	})
+	if err != nil {
+		return err
+	}
+	signer := NewSigner(wallet, btc, big.NewInt(cfg.Eth.ChainID))
+
+	summary, err := signer.Describe(req)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stderr, summary.String())
+	if !*yes {
+		fmt.Fprint(os.Stderr, "sign this transaction? [y/N] ")
+		answer, _ := stdin.ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("aborted")
+		}
+	}
+	if !hasPassphrase {
+		fmt.Fprint(os.Stderr, "passphrase: ")
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return errors.New("passphrase is required")
+		}
+		passphrase = strings.TrimRight(line, "\r\n")
+	}
+
+	signed, err := signer.Sign(req, passphrase)
+	if err != nil {
+		return err
+	}
+
+	var output []byte
+	if *format == "ur" {
+		parts, err := signed.UR(*fragment)
+		if err != nil {
+			return err
+		}
+		output = []byte(strings.Join(parts, "\n") + "\n")
+	} else {
+		if output, err = json.MarshalIndent(signed, "", "  "); err != nil {
+			return err
+		}
+		output = append(output, '\n')
+	}
+	if *out == "-" {
+		_, err = os.Stdout.Write(output)
+		return err
+	}
+	return os.WriteFile(*out, output, 0o600)
+}
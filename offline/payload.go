@@ -0,0 +1,149 @@
+package offline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// PayloadVersion 格式变了之后加一，离线端不认识的版本直接拒绝
+const PayloadVersion = 1
+
+// Payload 类型
+const (
+	ETHSignRequest = "eth-sign-request" // 在线端生成的未签名 ETH 交易
+	BTCSignRequest = "btc-sign-request" // 在线端生成的未签名 PSBT
+	ETHSigned      = "eth-signed"       // 离线端签好的 ETH 交易
+	BTCSigned      = "btc-signed"       // 离线端签好的 PSBT
+)
+
+// urType 在 QR 里传输时按 ur:bytes 包装 JSON
+const urType = "bytes"
+
+// Payload 在线端和离线端之间传递的内容，JSON 文件或 UR 编码的 QR 码
+type Payload struct {
+	Version  int    `json:"version"`
+	Type     string `json:"type"`
+	ID       string `json:"id"` // eth: from:nonce，btc: psbt 记录 id
+	WalletID string `json:"wallet_id"`
+	Chain    string `json:"chain"` // eth / btc
+
+	// eth: chain id 单独带上，legacy 交易未签名时带不出来
+	ChainID string `json:"chain_id,omitempty"`
+	From    string `json:"from,omitempty"`
+	Path    string `json:"path,omitempty"` // HD 派生路径，导入钱包为空
+	Tx      string `json:"tx,omitempty"`   // 0x RLP hex，请求里是未签名交易，签名结果里是已签名交易
+
+	// btc
+	PSBT string `json:"psbt,omitempty"` // base64
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WalletExport 加密的钱包导出，只有密文和 KDF 参数，离线端用 passphrase 解密后签名
+type WalletExport struct {
+	Version    int    `json:"version"`
+	WalletID   string `json:"wallet_id"`
+	UserID     string `json:"user_id"`
+	WalletType string `json:"wallet_type"` // hd / imported
+	SaltHex    string `json:"salt_hex"`
+
+	EncryptedSeed []byte `json:"encrypted_seed,omitempty"` // hd
+	CipherKey     []byte `json:"cipher_key,omitempty"`     // imported
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewWalletExport 只支持单签的 hd / 导入钱包，multisig / 时间锁钱包的签名方各自导出
+func NewWalletExport(wallet *entity.Wallet) (*WalletExport, error) {
+	if wallet.WalletType != utils.HdWalletType && wallet.WalletType != utils.ImportedWalletType {
+		return nil, fmt.Errorf("%s wallets can not be exported for offline signing", wallet.WalletType)
+	}
+	return &WalletExport{
+		Version:       PayloadVersion,
+		WalletID:      wallet.ID,
+		UserID:        wallet.UserID,
+		WalletType:    wallet.WalletType,
+		SaltHex:       wallet.SaltHex,
+		EncryptedSeed: wallet.EncryptedSeed,
+		CipherKey:     wallet.CipherKey,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// wallet 还原成解密需要的钱包字段
+func (w *WalletExport) wallet() *entity.Wallet {
+	return &entity.Wallet{
+		ID:            w.WalletID,
+		UserID:        w.UserID,
+		WalletType:    w.WalletType,
+		SaltHex:       w.SaltHex,
+		EncryptedSeed: w.EncryptedSeed,
+		CipherKey:     w.CipherKey,
+	}
+}
+
+// ParseWalletExport 读取导出文件
+func ParseWalletExport(data []byte) (*WalletExport, error) {
+	var w WalletExport
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("invalid wallet export: %w", err)
+	}
+	if w.Version != PayloadVersion {
+		return nil, fmt.Errorf("unsupported wallet export version %d", w.Version)
+	}
+	return &w, nil
+}
+
+// UR 编码成一段或多段 ur:bytes，每段对应一个 QR 码
+func (p *Payload) UR(maxFragment int) ([]string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeUR(urType, cborBytes(data), maxFragment), nil
+}
+
+// ParsePayload 接受 JSON，或者一行 / 空白分隔的 UR 分段
+func ParsePayload(text string) (*Payload, error) {
+	text = strings.TrimSpace(text)
+	data := []byte(text)
+	if !strings.HasPrefix(text, "{") {
+		typ, cbor, err := DecodeUR(strings.Fields(text))
+		if err != nil {
+			return nil, err
+		}
+		if typ != urType {
+			return nil, fmt.Errorf("unexpected ur type %s", typ)
+		}
+		if data, err = cborReadBytes(cbor); err != nil {
+			return nil, err
+		}
+	}
+
+	var p Payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	if p.Version != PayloadVersion {
+		return nil, fmt.Errorf("unsupported payload version %d", p.Version)
+	}
+	switch p.Type {
+	case ETHSignRequest, ETHSigned:
+		if p.Chain != "eth" || p.Tx == "" {
+			return nil, errors.New("invalid eth payload")
+		}
+	case BTCSignRequest, BTCSigned:
+		if p.Chain != "btc" || p.PSBT == "" {
+			return nil, errors.New("invalid btc payload")
+		}
+	default:
+		return nil, fmt.Errorf("unknown payload type %q", p.Type)
+	}
+	return &p, nil
+}
@@ -0,0 +1,229 @@
+package offline
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/domain"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// Signer 离线端签名，只用导出的钱包密文和本地配置，不访问节点和数据库
+type Signer struct {
+	Wallet  *WalletExport
+	BTC     *chain.BTCChain // 只用来解析地址和签 PSBT，没有后端
+	ChainID *big.Int        // 配置了 eth.chain_id 时，拒绝签别的链上的交易
+
+	hd domain.HDWallet
+}
+
+func NewSigner(wallet *WalletExport, btc *chain.BTCChain, chainID *big.Int) *Signer {
+	return &Signer{Wallet: wallet, BTC: btc, ChainID: chainID}
+}
+
+// Summary 从交易本身解码出的内容，签名前给用户核对，不信任请求里的其他字段
+type Summary struct {
+	Type  string `json:"type"`
+	Chain string `json:"chain"`
+	From  string `json:"from,omitempty"`
+
+	// eth
+	ChainID  string `json:"chain_id,omitempty"`
+	To       string `json:"to,omitempty"`
+	Value    string `json:"value,omitempty"` // ETH
+	Token    string `json:"token,omitempty"` // ERC-20 transfer 的合约地址
+	TokenTo  string `json:"token_to,omitempty"`
+	Amount   string `json:"token_amount,omitempty"` // 最小单位，离线端不知道 decimals
+	Nonce    uint64 `json:"nonce"`
+	Gas      uint64 `json:"gas,omitempty"`
+	MaxFee   string `json:"max_fee,omitempty"` // gas * fee cap，ETH
+	DataSize int    `json:"data_size,omitempty"`
+
+	// btc
+	Outputs []chain.PSBTOutput `json:"outputs,omitempty"`
+	Fee     int64              `json:"fee,omitempty"` // satoshi，输入不带 utxo 信息时为 0
+}
+
+// String 签名前打印的内容
+func (s *Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", s.Chain, s.Type)
+	if s.From != "" {
+		fmt.Fprintf(&b, "  from:    %s\n", s.From)
+	}
+	switch s.Chain {
+	case "eth":
+		fmt.Fprintf(&b, "  chain:   %s\n", s.ChainID)
+		fmt.Fprintf(&b, "  to:      %s\n", s.To)
+		fmt.Fprintf(&b, "  value:   %s ETH\n", s.Value)
+		if s.Token != "" {
+			fmt.Fprintf(&b, "  token:   %s\n", s.Token)
+			fmt.Fprintf(&b, "  transfer %s to %s\n", s.Amount, s.TokenTo)
+		} else if s.DataSize > 0 {
+			fmt.Fprintf(&b, "  data:    %d bytes\n", s.DataSize)
+		}
+		fmt.Fprintf(&b, "  nonce:   %d\n", s.Nonce)
+		fmt.Fprintf(&b, "  gas:     %d, max fee %s ETH\n", s.Gas, s.MaxFee)
+	case "btc":
+		for i, o := range s.Outputs {
+			addr := o.Address
+			if addr == "" {
+				addr = "(non-standard script)"
+			}
+			fmt.Fprintf(&b, "  output %d: %s %d sat\n", i, addr, o.Value)
+		}
+		if s.Fee > 0 {
+			fmt.Fprintf(&b, "  fee:     %d sat\n", s.Fee)
+		}
+	}
+	return b.String()
+}
+
+// Describe 解码签名请求
+func (s *Signer) Describe(p *Payload) (*Summary, error) {
+	switch p.Type {
+	case ETHSignRequest:
+		tx, chainID, err := s.ethRequest(p)
+		if err != nil {
+			return nil, err
+		}
+		return ethSummary(p, tx, chainID), nil
+	case BTCSignRequest:
+		packet, err := chain.DecodePSBT([]byte(p.PSBT))
+		if err != nil {
+			return nil, fmt.Errorf("invalid psbt: %w", err)
+		}
+		sum := &Summary{Type: p.Type, Chain: p.Chain, Outputs: s.BTC.PSBTOutputs(packet)}
+		if fee, err := chain.PSBTFee(packet); err == nil {
+			sum.Fee = fee
+		}
+		return sum, nil
+	default:
+		return nil, fmt.Errorf("%s is not a sign request", p.Type)
+	}
+}
+
+// Sign 签名请求，返回交给在线端导入的签名结果
+func (s *Signer) Sign(p *Payload, passphrase string) (*Payload, error) {
+	if p.WalletID != s.Wallet.WalletID {
+		return nil, fmt.Errorf("request is for wallet %s, loaded wallet is %s", p.WalletID, s.Wallet.WalletID)
+	}
+	out := &Payload{
+		Version:   PayloadVersion,
+		ID:        p.ID,
+		WalletID:  p.WalletID,
+		Chain:     p.Chain,
+		ChainID:   p.ChainID,
+		From:      p.From,
+		CreatedAt: time.Now(),
+	}
+
+	switch p.Type {
+	case ETHSignRequest:
+		tx, chainID, err := s.ethRequest(p)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := s.hd.ETHPrivateKey(s.Wallet.wallet(), p.Path, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if addr := crypto.PubkeyToAddress(priv.PublicKey).Hex(); addr != p.From {
+			return nil, fmt.Errorf("wallet key is %s, request is from %s", addr, p.From)
+		}
+		signed, err := chain.SignETHTx(priv, tx, chainID)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := signed.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		out.Type, out.Tx = ETHSigned, hexutil.Encode(raw)
+		return out, nil
+
+	case BTCSignRequest:
+		if s.Wallet.WalletType != utils.HdWalletType {
+			return nil, errors.New("btc requests need an hd wallet")
+		}
+		packet, err := chain.DecodePSBT([]byte(p.PSBT))
+		if err != nil {
+			return nil, fmt.Errorf("invalid psbt: %w", err)
+		}
+		seed, err := s.hd.DecryptSeed(s.Wallet.wallet(), passphrase)
+		if err != nil {
+			return nil, err
+		}
+		n, err := s.BTC.SignPSBT(packet, seed)
+		for i := range seed {
+			seed[i] = 0
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, errors.New("no psbt input belongs to this wallet")
+		}
+		if out.PSBT, err = chain.EncodePSBT(packet); err != nil {
+			return nil, err
+		}
+		out.Type = BTCSigned
+		return out, nil
+
+	default:
+		return nil, fmt.Errorf("%s is not a sign request", p.Type)
+	}
+}
+
+// ethRequest 解析未签名交易，chain id 和离线端配置的不一致时拒绝
+func (s *Signer) ethRequest(p *Payload) (*types.Transaction, *big.Int, error) {
+	chainID, ok := new(big.Int).SetString(p.ChainID, 10)
+	if !ok || chainID.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("invalid chain id %q", p.ChainID)
+	}
+	if s.ChainID != nil && s.ChainID.Sign() > 0 && s.ChainID.Cmp(chainID) != 0 {
+		return nil, nil, fmt.Errorf("request is for chain %s, signer is configured for chain %s", chainID, s.ChainID)
+	}
+	raw, err := utils.DecodeHexData(p.Tx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tx: %w", err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, nil, fmt.Errorf("invalid tx: %w", err)
+	}
+	if tx.Type() != types.LegacyTxType && tx.ChainId().Cmp(chainID) != 0 {
+		return nil, nil, fmt.Errorf("tx chain id %s does not match request chain id %s", tx.ChainId(), chainID)
+	}
+	return tx, chainID, nil
+}
+
+func ethSummary(p *Payload, tx *types.Transaction, chainID *big.Int) *Summary {
+	sum := &Summary{
+		Type:     p.Type,
+		Chain:    p.Chain,
+		From:     p.From,
+		ChainID:  chainID.String(),
+		Value:    utils.WeiToETH(tx.Value()),
+		Nonce:    tx.Nonce(),
+		Gas:      tx.Gas(),
+		MaxFee:   utils.WeiToETH(new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), tx.GasFeeCap())),
+		DataSize: len(tx.Data()),
+	}
+	if tx.To() == nil {
+		sum.To = "(contract deployment)"
+	} else {
+		sum.To = tx.To().Hex()
+		if to, amount, ok := chain.DecodeERC20Transfer(tx.Data()); ok {
+			sum.Token, sum.TokenTo, sum.Amount = sum.To, to, amount.String()
+		}
+	}
+	return sum
+}
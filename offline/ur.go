@@ -0,0 +1,250 @@
+package offline
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// UR (Uniform Resources, BCR-2020-005) 编码：内容是 CBOR，用 bytewords minimal 编码成适合 QR 字母数字模式的文本。
+// 超过 maxFragment 字节时按顺序拆成多段 ur:<type>/<seq>-<total>/...，只产生和接受顺序分段，不做 fountain 混合分段
+
+// DefaultURFragment 每段 UR 的最大字节数，单个 QR 码里放得下
+const DefaultURFragment = 200
+
+// maxURParts 分段数上限，分段头由扫码内容决定，不能信任
+const maxURParts = 10000
+
+const bytewords = "ableacidalsoapexaquaarchatomauntawayaxisbackbaldbarnbeltbetabiasbluebodybragbrewbulbbuzzcalmcashcatschefcityclawcodecolacookcostcruxcurlcuspcyandarkdatadaysdelidicedietdoordowndrawdropdrumdulldutyeacheasyechoedgeepicevenexamexiteyesfactfairfernfigsfilmfishfizzflapflewfluxfoxyfreefrogfuelfundgalagamegeargemsgiftgirlglowgoodgraygrimgurugushgyrohalfhanghardhawkheathelphighhillholyhopehornhutsicedideaidleinchinkyintoirisironitemjadejazzjoinjoltjowljudojugsjumpjunkjurykeepkenokeptkeyskickkilnkingkitekiwiknoblamblavalazyleaflegsliarlimplionlistlogoloudloveluaulucklungmainmanymathmazememomenumeowmildmintmissmonknailnavyneednewsnextnoonnotenumbobeyoboeomitonyxopenovalowlspaidpartpeckplaypluspoempoolposepuffpumapurrquadquizraceramprealredorichroadrockroofrubyruinrunsrustsafesagascarsetssilkskewslotsoapsolosongstubsurfswantacotasktaxitenttiedtimetinytoiltombtoystriptunatwinuglyundouniturgeuservastveryvetovialvibeviewvisavoidvowswallwandwarmwaspwavewaxywebswhatwhenwhizwolfworkyankyawnyellyogayurtzapszerozestzinczonezoom"
+
+// minimalWords 每个字节对应单词的首尾字母
+var minimalWords = func() map[string]byte {
+	m := make(map[string]byte, 256)
+	for i := 0; i < 256; i++ {
+		w := bytewords[i*4 : i*4+4]
+		m[w[:1]+w[3:]] = byte(i)
+	}
+	return m
+}()
+
+// EncodeUR cbor 编码成一段或多段 UR
+func EncodeUR(urType string, cbor []byte, maxFragment int) []string {
+	if maxFragment <= 0 {
+		maxFragment = DefaultURFragment
+	}
+	if len(cbor) <= maxFragment {
+		return []string{"ur:" + urType + "/" + encodeBytewords(cbor)}
+	}
+
+	count := (len(cbor) + maxFragment - 1) / maxFragment
+	size := (len(cbor) + count - 1) / count
+	checksum := crc32.ChecksumIEEE(cbor)
+	parts := make([]string, count)
+	for i := 0; i < count; i++ {
+		fragment := make([]byte, size) // 最后一段不足时补 0
+		if start := i * size; start < len(cbor) {
+			copy(fragment, cbor[start:])
+		}
+		var body []byte
+		body = cborHead(body, 4, 5)
+		body = cborHead(body, 0, uint64(i+1))
+		body = cborHead(body, 0, uint64(count))
+		body = cborHead(body, 0, uint64(len(cbor)))
+		body = cborHead(body, 0, uint64(checksum))
+		body = cborHead(body, 2, uint64(len(fragment)))
+		body = append(body, fragment...)
+		parts[i] = fmt.Sprintf("ur:%s/%d-%d/%s", urType, i+1, count, encodeBytewords(body))
+	}
+	return parts
+}
+
+// DecodeUR 拼回 UR 的 cbor 内容，多段时需要全部的顺序分段，顺序不限
+func DecodeUR(parts []string) (string, []byte, error) {
+	if len(parts) == 0 {
+		return "", nil, errors.New("ur: no parts")
+	}
+
+	var urType string
+	var total, msgLen, fragLen int
+	var checksum uint32
+	fragments := make(map[int][]byte)
+	for _, part := range parts {
+		fields := strings.Split(strings.ToLower(strings.TrimSpace(part)), "/")
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "ur:") {
+			return "", nil, fmt.Errorf("ur: invalid part %q", part)
+		}
+		typ := strings.TrimPrefix(fields[0], "ur:")
+		if urType != "" && typ != urType {
+			return "", nil, fmt.Errorf("ur: mixed types %s and %s", urType, typ)
+		}
+		urType = typ
+
+		body, err := decodeBytewords(fields[len(fields)-1])
+		if err != nil {
+			return "", nil, err
+		}
+		if len(fields) == 2 {
+			if len(parts) != 1 {
+				return "", nil, errors.New("ur: single-part ur mixed with other parts")
+			}
+			return urType, body, nil
+		}
+		if len(fields) != 3 {
+			return "", nil, fmt.Errorf("ur: invalid part %q", part)
+		}
+
+		seq, n, length, sum, fragment, err := decodeURPart(body)
+		if err != nil {
+			return "", nil, err
+		}
+		if fields[1] != strconv.Itoa(seq)+"-"+strconv.Itoa(n) {
+			return "", nil, fmt.Errorf("ur: part header %s does not match its content", fields[1])
+		}
+		if total == 0 {
+			total, msgLen, checksum, fragLen = n, length, sum, len(fragment)
+		} else if n != total || length != msgLen || sum != checksum || len(fragment) != fragLen {
+			return "", nil, errors.New("ur: parts belong to different messages")
+		}
+		if seq > total {
+			return "", nil, errors.New("ur: fountain-coded parts are not supported, scan the first parts again")
+		}
+		fragments[seq] = fragment
+	}
+
+	if len(fragments) != total {
+		var missing []string
+		for i := 1; i <= total; i++ {
+			if _, ok := fragments[i]; !ok {
+				missing = append(missing, strconv.Itoa(i))
+			}
+		}
+		return "", nil, fmt.Errorf("ur: missing parts %s of %d", strings.Join(missing, ","), total)
+	}
+	var msg []byte
+	for i := 1; i <= total; i++ {
+		msg = append(msg, fragments[i]...)
+	}
+	if len(msg) < msgLen {
+		return "", nil, errors.New("ur: message shorter than declared")
+	}
+	msg = msg[:msgLen]
+	if crc32.ChecksumIEEE(msg) != checksum {
+		return "", nil, errors.New("ur: message checksum mismatch")
+	}
+	return urType, msg, nil
+}
+
+// decodeURPart 解析分段 [seq, total, messageLen, checksum, fragment]
+func decodeURPart(body []byte) (seq, total, msgLen int, checksum uint32, fragment []byte, err error) {
+	major, n, rest, err := cborReadHead(body)
+	if err != nil || major != 4 || n != 5 {
+		return 0, 0, 0, 0, nil, errors.New("ur: invalid part body")
+	}
+	var nums [4]uint64
+	for i := range nums {
+		if major, nums[i], rest, err = cborReadHead(rest); err != nil || major != 0 {
+			return 0, 0, 0, 0, nil, errors.New("ur: invalid part body")
+		}
+	}
+	if major, n, rest, err = cborReadHead(rest); err != nil || major != 2 || uint64(len(rest)) != n {
+		return 0, 0, 0, 0, nil, errors.New("ur: invalid part fragment")
+	}
+	if nums[0] == 0 || nums[1] == 0 || nums[1] > maxURParts || nums[0] > 0xffffffff || nums[3] > 0xffffffff {
+		return 0, 0, 0, 0, nil, errors.New("ur: invalid part header")
+	}
+	// 所有分段拼起来要能装下声明的长度，否则截断时越界
+	if nums[2] == 0 || nums[2] > nums[1]*uint64(len(rest)) {
+		return 0, 0, 0, 0, nil, errors.New("ur: invalid message length")
+	}
+	return int(nums[0]), int(nums[1]), int(nums[2]), uint32(nums[3]), rest, nil
+}
+
+// encodeBytewords minimal 编码，末尾带 4 字节 CRC32
+func encodeBytewords(data []byte) string {
+	sum := make([]byte, 4)
+	binary.BigEndian.PutUint32(sum, crc32.ChecksumIEEE(data))
+	var b strings.Builder
+	for _, c := range append(append([]byte{}, data...), sum...) {
+		w := bytewords[int(c)*4 : int(c)*4+4]
+		b.WriteByte(w[0])
+		b.WriteByte(w[3])
+	}
+	return b.String()
+}
+
+func decodeBytewords(s string) ([]byte, error) {
+	if len(s)%2 != 0 || len(s) < 8 {
+		return nil, errors.New("ur: invalid bytewords length")
+	}
+	out := make([]byte, len(s)/2)
+	for i := range out {
+		c, ok := minimalWords[s[i*2:i*2+2]]
+		if !ok {
+			return nil, fmt.Errorf("ur: invalid byteword %q", s[i*2:i*2+2])
+		}
+		out[i] = c
+	}
+	data, sum := out[:len(out)-4], out[len(out)-4:]
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(sum) {
+		return nil, errors.New("ur: bytewords checksum mismatch, rescan the qr code")
+	}
+	return data, nil
+}
+
+// cborHead 写 CBOR 的类型头 (major type + 长度 / 数值)
+func cborHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= 0xff:
+		return append(b, m|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, m|27), n)
+	}
+}
+
+func cborReadHead(b []byte) (major byte, n uint64, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, 0, nil, errors.New("cbor: unexpected end")
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+	switch {
+	case info < 24:
+		return major, uint64(info), b, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(b) < size {
+			return 0, 0, nil, errors.New("cbor: unexpected end")
+		}
+		for _, c := range b[:size] {
+			n = n<<8 | uint64(c)
+		}
+		return major, n, b[size:], nil
+	}
+	return 0, 0, nil, errors.New("cbor: unsupported item")
+}
+
+// cborBytes / cborReadBytes: ur:bytes 的内容是一个 CBOR byte string
+func cborBytes(data []byte) []byte {
+	return append(cborHead(nil, 2, uint64(len(data))), data...)
+}
+
+func cborReadBytes(b []byte) ([]byte, error) {
+	major, n, rest, err := cborReadHead(b)
+	if err != nil {
+		return nil, err
+	}
+	if major != 2 || uint64(len(rest)) != n {
+		return nil, errors.New("cbor: expected a byte string")
+	}
+	return rest, nil
+}
@@ -0,0 +1,178 @@
+package offline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testMessage(n int) []byte {
+	msg := make([]byte, n)
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+	return msg
+}
+
+func TestURRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		fragment int
+		parts    int
+		reorder  func([]string) []string
+	}{
+		{name: "single part", size: 50, fragment: 200, parts: 1},
+		{name: "exactly one fragment", size: 200, fragment: 200, parts: 1},
+		{name: "one byte over", size: 201, fragment: 200, parts: 2},
+		{name: "multipart", size: 1000, fragment: 90, parts: 12},
+		{name: "multipart reversed", size: 1000, fragment: 90, parts: 12, reorder: func(p []string) []string {
+			out := make([]string, len(p))
+			for i := range p {
+				out[len(p)-1-i] = p[i]
+			}
+			return out
+		}},
+		{name: "uppercase qr alphanumeric mode", size: 500, fragment: 100, parts: 5, reorder: func(p []string) []string {
+			out := make([]string, len(p))
+			for i := range p {
+				out[i] = strings.ToUpper(p[i])
+			}
+			return out
+		}},
+		{name: "duplicate scans", size: 500, fragment: 100, parts: 5, reorder: func(p []string) []string {
+			return append(p, p[0], p[3])
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := testMessage(tt.size)
+			parts := EncodeUR("bytes", msg, tt.fragment)
+			if len(parts) != tt.parts {
+				t.Fatalf("got %d parts, want %d", len(parts), tt.parts)
+			}
+			if tt.reorder != nil {
+				parts = tt.reorder(parts)
+			}
+			typ, got, err := DecodeUR(parts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typ != "bytes" {
+				t.Errorf("type %q, want bytes", typ)
+			}
+			if !bytes.Equal(got, msg) {
+				t.Errorf("decoded message differs from the encoded one")
+			}
+		})
+	}
+}
+
+func TestPayloadURRoundTrip(t *testing.T) {
+	p := &Payload{Version: PayloadVersion, Type: BTCSignRequest, ID: "abc", WalletID: "w1", Chain: "btc", PSBT: strings.Repeat("cHNidP8B", 100)}
+	parts, err := p.UR(120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParsePayload(strings.Join(parts, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != p.ID || got.WalletID != p.WalletID || got.PSBT != p.PSBT {
+		t.Errorf("payload changed in the round trip: %+v", got)
+	}
+}
+
+// multipartBody 手工构造分段内容，用来模拟扫到的恶意 / 损坏的 QR 码
+func multipartBody(seq, total, msgLen, checksum uint64, fragment []byte) []byte {
+	var body []byte
+	body = cborHead(body, 4, 5)
+	body = cborHead(body, 0, seq)
+	body = cborHead(body, 0, total)
+	body = cborHead(body, 0, msgLen)
+	body = cborHead(body, 0, checksum)
+	body = cborHead(body, 2, uint64(len(fragment)))
+	return append(body, fragment...)
+}
+
+func TestURCorruption(t *testing.T) {
+	msg := testMessage(1000)
+	parts := EncodeUR("bytes", msg, 90)
+	other := EncodeUR("bytes", testMessage(900), 90)
+
+	flip := func(part string) string {
+		// 把最后一个 byteword 换成另一个合法的词，和校验和对不上
+		word := "ae"
+		if strings.HasSuffix(part, word) {
+			word = "ad"
+		}
+		return part[:len(part)-2] + word
+	}
+
+	tests := []struct {
+		name  string
+		parts []string
+		err   string
+	}{
+		{name: "no parts", parts: nil, err: "no parts"},
+		{name: "not a ur", parts: []string{"hello"}, err: "invalid part"},
+		{name: "bad bytewords checksum", parts: append([]string{flip(parts[0])}, parts[1:]...), err: "bytewords checksum mismatch"},
+		{name: "invalid byteword", parts: []string{"ur:bytes/zzzzzzzzzz"}, err: "invalid byteword"},
+		{name: "missing part", parts: append(append([]string{}, parts[:4]...), parts[5:]...), err: "missing parts 5 of 12"},
+		{name: "parts of different messages", parts: append(append([]string{}, parts[:6]...), other[6:]...), err: "different messages"},
+		{name: "single part mixed with multipart", parts: []string{EncodeUR("bytes", msg[:10], 90)[0], parts[0]}, err: "single-part"},
+		{name: "mixed types", parts: []string{parts[0], strings.Replace(parts[1], "ur:bytes", "ur:psbt", 1)}, err: "mixed types"},
+		{name: "header does not match content", parts: []string{strings.Replace(parts[0], "/1-12/", "/2-12/", 1)}, err: "does not match"},
+		{
+			name:  "huge message length",
+			parts: []string{"ur:bytes/1-1/" + encodeBytewords(multipartBody(1, 1, 1<<62, 0, []byte{1, 2, 3}))},
+			err:   "invalid message length",
+		},
+		{
+			name:  "message length overflows int",
+			parts: []string{"ur:bytes/1-1/" + encodeBytewords(multipartBody(1, 1, 1<<63+5, 0, []byte{1, 2, 3}))},
+			err:   "invalid message length",
+		},
+		{
+			name:  "zero message length",
+			parts: []string{"ur:bytes/1-1/" + encodeBytewords(multipartBody(1, 1, 0, 0, []byte{1, 2, 3}))},
+			err:   "invalid message length",
+		},
+		{
+			name:  "huge part count",
+			parts: []string{"ur:bytes/1-4611686018427387904/" + encodeBytewords(multipartBody(1, 1<<62, 3, 0, []byte{1, 2, 3}))},
+			err:   "invalid part header",
+		},
+		{
+			name:  "fountain part",
+			parts: []string{"ur:bytes/13-12/" + encodeBytewords(multipartBody(13, 12, 1000, 0, make([]byte, 84)))},
+			err:   "fountain",
+		},
+		{
+			name: "fragments of different lengths",
+			parts: []string{
+				"ur:bytes/1-2/" + encodeBytewords(multipartBody(1, 2, 4, 0, []byte{1, 2, 3})),
+				"ur:bytes/2-2/" + encodeBytewords(multipartBody(2, 2, 4, 0, []byte{4, 5})),
+			},
+			err: "different messages",
+		},
+		{
+			name:  "message checksum mismatch",
+			parts: []string{"ur:bytes/1-1/" + encodeBytewords(multipartBody(1, 1, 3, 12345, []byte{1, 2, 3}))},
+			err:   "message checksum mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := DecodeUR(tt.parts)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %q, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
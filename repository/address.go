@@ -19,6 +19,11 @@ func NewAddressRepo() *AddressRepo {
 	return &AddressRepo{col: db.MongoDB.AddrColl}
 }
 
+// CheckIndexes (chain, address) 唯一索引
+func (r *AddressRepo) CheckIndexes(ctx context.Context) error {
+	return requireUniqueIndex(ctx, r.col, "chain", "address")
+}
+
 func (r *AddressRepo) Create(ctx context.Context, addr *entity.Address) error {
 	_, err := r.col.InsertOne(ctx, addr)
 	return err
@@ -41,6 +46,36 @@ func (r *AddressRepo) GetByUserID(ctx context.Context, userID string) ([]*entity
 	return out, nil
 }
 
+// ListByWalletChain 查询钱包在某条链上的所有地址
+func (r *AddressRepo) ListByWalletChain(ctx context.Context, walletID, chain string) ([]*entity.Address, error) {
+	return r.find(ctx, bson.M{"wallet_id": walletID, "chain": chain})
+}
+
+// ListByChain 查询某条链上的所有受管地址（后台同步用）
+func (r *AddressRepo) ListByChain(ctx context.Context, chain string) ([]*entity.Address, error) {
+	return r.find(ctx, bson.M{"chain": chain})
+}
+
+func (r *AddressRepo) find(ctx context.Context, filter bson.M) ([]*entity.Address, error) {
+	cur, err := r.col.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*entity.Address
+	for cur.Next(ctx) {
+		var a entity.Address
+		if err := cur.Decode(&a); err == nil {
+			out = append(out, &a)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // 获取用户在某条链的最大 index (用于生成下一地址)
 func (r *AddressRepo) GetMaxIndex(ctx context.Context, walletID string, chain string) (int, error) {
 	opts := options.FindOne().SetSort(bson.M{"index": -1})
@@ -61,10 +96,39 @@ func (r *AddressRepo) GetMaxIndex(ctx context.Context, walletID string, chain st
 	return int(out.Index), nil
 }
 
-// GetByAddrID 根据链上的地址查找 Address
-func (r *AddressRepo) GetByAddrID(ctx context.Context, address string) (*entity.Address, error) {
+// GetMaxIndexByType 获取钱包在某条链、某种地址类型、某条 change 链下的最大 index
+// BTC 每种地址类型对应不同的 BIP purpose，接收/找零链也需要分别计数
+func (r *AddressRepo) GetMaxIndexByType(ctx context.Context, walletID, chain, addressType string, change uint32) (int, error) {
+	opts := options.FindOne().SetSort(bson.M{"index": -1})
+
+	filter := bson.M{
+		"wallet_id":    walletID,
+		"chain":        chain,
+		"address_type": addressType,
+		"change":       change,
+	}
+	if change == 0 {
+		// 外部链地址不写 change 字段
+		filter["change"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	var out entity.Address
+	err := r.col.FindOne(ctx, filter, opts).Decode(&out)
+
+	if err == mongo.ErrNoDocuments {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+
+	return int(out.Index), nil
+}
+
+// GetByAddrID 根据链上的地址查找 Address。不同链的地址可能相同 (例如 LTC 和 BTC 的 testnet)，必须带上 chain
+func (r *AddressRepo) GetByAddrID(ctx context.Context, chain, address string) (*entity.Address, error) {
 	var addr entity.Address
-	err := r.col.FindOne(ctx, bson.M{"address": address}).Decode(&addr)
+	err := r.col.FindOne(ctx, bson.M{"chain": chain, "address": address}).Decode(&addr)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil // 找不到返回 nil
 	}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/db"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type BTCPSBTRepo struct {
+	col *mongo.Collection
+}
+
+func NewBTCPSBTRepo() *BTCPSBTRepo {
+	return &BTCPSBTRepo{col: db.MongoDB.BTCPSBTColl}
+}
+
+// Create 写入 PSBT，返回 ID
+func (r *BTCPSBTRepo) Create(ctx context.Context, p *entity.BTCPSBT) (string, error) {
+	res, err := r.col.InsertOne(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// GetByID 找不到返回 nil
+func (r *BTCPSBTRepo) GetByID(ctx context.Context, id string) (*entity.BTCPSBT, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var p entity.BTCPSBT
+	err = r.col.FindOne(ctx, bson.M{"_id": oid}).Decode(&p)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Update 保存合并签名后的 PSBT 及签名进度
+func (r *BTCPSBTRepo) Update(ctx context.Context, p *entity.BTCPSBT) error {
+	oid, err := primitive.ObjectIDFromHex(p.ID)
+	if err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now()
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{
+		"psbt":       p.PSBT,
+		"signers":    p.Signers,
+		"status":     p.Status,
+		"txid":       p.TxID,
+		"updated_at": p.UpdatedAt,
+	}})
+	return err
+}
+
+// SetStatusFrom 只有当前状态为 from 时才改成 to，返回是否修改，防止和 finalize 并发覆盖
+func (r *BTCPSBTRepo) SetStatusFrom(ctx context.Context, id, from, to string) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": oid, "status": from}, bson.M{"$set": bson.M{
+		"status":     to,
+		"updated_at": time.Now(),
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
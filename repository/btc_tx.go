@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/db"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type BTCTxRepo struct {
+	col *mongo.Collection
+}
+
+func NewBTCTxRepo() *BTCTxRepo {
+	return &BTCTxRepo{col: db.MongoDB.BTCTxColl}
+}
+
+func (r *BTCTxRepo) Create(ctx context.Context, tx *entity.BTCTx) error {
+	_, err := r.col.InsertOne(ctx, tx)
+	return err
+}
+
+// GetByTxID 根据 txid 查找，找不到返回 nil
+func (r *BTCTxRepo) GetByTxID(ctx context.Context, txid string) (*entity.BTCTx, error) {
+	var tx entity.BTCTx
+	err := r.col.FindOne(ctx, bson.M{"txid": txid}).Decode(&tx)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// MarkReplaced 标记交易已被 RBF 替换
+func (r *BTCTxRepo) MarkReplaced(ctx context.Context, txid, replacedBy string) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"txid": txid},
+		bson.M{"$set": bson.M{
+			"status":           entity.BTCTxReplaced,
+			"replaced_by_txid": replacedBy,
+			"updated_at":       time.Now(),
+		}},
+	)
+	return err
+}
+
+// ListTracking 需要跟踪的交易：pending，以及确认数还没到 confirmations 的 confirmed。
+// btc 的老记录 chain 为空
+func (r *BTCTxRepo) ListTracking(ctx context.Context, chain string, confirmations int64) ([]*entity.BTCTx, error) {
+	chainFilter := interface{}(chain)
+	if chain == "btc" {
+		chainFilter = bson.M{"$in": []interface{}{"btc", "", nil}}
+	}
+	cur, err := r.col.Find(ctx, bson.M{
+		"chain": chainFilter,
+		"$or": []bson.M{
+			{"status": entity.BTCTxPending},
+			{"status": entity.BTCTxConfirmed, "confirmations": bson.M{"$lt": confirmations}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*entity.BTCTx
+	for cur.Next(ctx) {
+		var tx entity.BTCTx
+		if err := cur.Decode(&tx); err == nil {
+			out = append(out, &tx)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkConfirmed 记录上链高度和确认数
+func (r *BTCTxRepo) MarkConfirmed(ctx context.Context, txid string, height, confirmations int64) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"txid": txid},
+		bson.M{"$set": bson.M{
+			"status":        entity.BTCTxConfirmed,
+			"block_height":  height,
+			"confirmations": confirmations,
+			"updated_at":    time.Now(),
+		}},
+	)
+	return err
+}
+
+// SetStatus 更新状态，区块重组后已确认的交易回到 pending 时清掉高度
+func (r *BTCTxRepo) SetStatus(ctx context.Context, txid, status string) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"txid": txid},
+		bson.M{
+			"$set":   bson.M{"status": status, "confirmations": 0, "updated_at": time.Now()},
+			"$unset": bson.M{"block_height": ""},
+		},
+	)
+	return err
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// requireUniqueIndex 检查 collection 上有按 keys 顺序的唯一索引。索引由 script/mongodb 创建，
+// 依赖唯一索引保证正确性的 repo 在启动时检查，缺了直接报错，不要带着重复数据的风险跑起来
+func requireUniqueIndex(ctx context.Context, col *mongo.Collection, keys ...string) error {
+	cur, err := col.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var idx struct {
+			Key    bson.D `bson:"key"`
+			Unique bool   `bson:"unique"`
+		}
+		if err := cur.Decode(&idx); err != nil {
+			return err
+		}
+		if !idx.Unique || len(idx.Key) != len(keys) {
+			continue
+		}
+		match := true
+		for i, k := range idx.Key {
+			if k.Key != keys[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return nil
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("unique index (%s) on %s is missing, run script/mongodb first", strings.Join(keys, ", "), col.Name())
+}
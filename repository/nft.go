@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/db"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NFTRepo 持仓 (nft_holdings) 和每个合约的索引进度 (nft_sync)
+type NFTRepo struct {
+	col     *mongo.Collection
+	syncCol *mongo.Collection
+}
+
+func NewNFTRepo() *NFTRepo {
+	return &NFTRepo{col: db.MongoDB.NFTColl, syncCol: db.MongoDB.NFTSyncColl}
+}
+
+// CheckIndexes 持仓按 (collection, token_id, owner) upsert，索引进度按 collection upsert，都依赖唯一索引
+func (r *NFTRepo) CheckIndexes(ctx context.Context) error {
+	if err := requireUniqueIndex(ctx, r.col, "collection", "token_id", "owner"); err != nil {
+		return err
+	}
+	return requireUniqueIndex(ctx, r.syncCol, "collection")
+}
+
+// GetHolding 找不到返回 nil
+func (r *NFTRepo) GetHolding(ctx context.Context, collection, tokenID, owner string) (*entity.NFTHolding, error) {
+	var h entity.NFTHolding
+	err := r.col.FindOne(ctx, bson.M{"collection": collection, "token_id": tokenID, "owner": owner}).Decode(&h)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r *NFTRepo) SaveHolding(ctx context.Context, h *entity.NFTHolding) error {
+	h.UpdatedAt = time.Now()
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"collection": h.Collection, "token_id": h.TokenID, "owner": h.Owner},
+		bson.M{"$set": bson.M{
+			"user_id":          h.UserID,
+			"standard":         h.Standard,
+			"amount":           h.Amount,
+			"last_block":       h.LastBlock,
+			"last_log_index":   h.LastLogIndex,
+			"last_batch_index": h.LastBatchIndex,
+			"updated_at":       h.UpdatedAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *NFTRepo) DeleteHolding(ctx context.Context, collection, tokenID, owner string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"collection": collection, "token_id": tokenID, "owner": owner})
+	return err
+}
+
+func (r *NFTRepo) ListByUser(ctx context.Context, userID string) ([]*entity.NFTHolding, error) {
+	cur, err := r.col.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "collection", Value: 1}, {Key: "token_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*entity.NFTHolding
+	for cur.Next(ctx) {
+		var h entity.NFTHolding
+		if err := cur.Decode(&h); err == nil {
+			out = append(out, &h)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSyncState 还没索引过的合约返回 nil
+func (r *NFTRepo) GetSyncState(ctx context.Context, collection string) (*entity.NFTSyncState, error) {
+	var st entity.NFTSyncState
+	err := r.syncCol.FindOne(ctx, bson.M{"collection": collection}).Decode(&st)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (r *NFTRepo) SaveSyncState(ctx context.Context, collection string, lastBlock uint64) error {
+	_, err := r.syncCol.UpdateOne(ctx,
+		bson.M{"collection": collection},
+		bson.M{"$set": bson.M{"last_block": lastBlock, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
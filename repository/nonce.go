@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/db"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NonceRepo struct {
+	col *mongo.Collection
+}
+
+func NewNonceRepo() *NonceRepo {
+	return &NonceRepo{col: db.MongoDB.NonceColl}
+}
+
+// CheckIndexes (chain, address) 必须唯一，否则并发 TryLock 的 upsert 会插出两条记录，同一个 nonce 被分配两次
+func (r *NonceRepo) CheckIndexes(ctx context.Context) error {
+	return requireUniqueIndex(ctx, r.col, "chain", "address")
+}
+
+// TryLock 锁空闲或已过期时抢到 (chain, address) 的锁并返回当前状态，被别人占用时返回 nil。
+// 第一次使用的地址会先插入一条 next = 0、未对账的记录
+func (r *NonceRepo) TryLock(ctx context.Context, chain, address, owner string, ttl time.Duration) (*entity.NonceAccount, error) {
+	now := time.Now()
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": chain, "address": address},
+		bson.M{"$setOnInsert": bson.M{
+			"next":         uint64(0),
+			"pending":      []entity.NonceTx{},
+			"locked_until": time.Time{},
+			"created_at":   now,
+			"updated_at":   now,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	var acct entity.NonceAccount
+	err = r.col.FindOneAndUpdate(ctx,
+		bson.M{"chain": chain, "address": address, "locked_until": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"lock_owner": owner, "locked_until": now.Add(ttl)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&acct)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &acct, nil
+}
+
+// Unlock 释放锁，只有持有者能释放
+func (r *NonceRepo) Unlock(ctx context.Context, chain, address, owner string) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": chain, "address": address, "lock_owner": owner},
+		bson.M{
+			"$set":   bson.M{"locked_until": time.Time{}},
+			"$unset": bson.M{"lock_owner": ""},
+		},
+	)
+	return err
+}
+
+// Save 持锁时保存 next / pending / synced_at，锁已过期被别人拿走时返回 mongo.ErrNoDocuments
+func (r *NonceRepo) Save(ctx context.Context, acct *entity.NonceAccount, owner string) error {
+	acct.UpdatedAt = time.Now()
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": acct.Chain, "address": acct.Address, "lock_owner": owner},
+		bson.M{"$set": bson.M{
+			"next":       acct.Next,
+			"pending":    acct.Pending,
+			"synced_at":  acct.SyncedAt,
+			"updated_at": acct.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Get 找不到返回 nil
+func (r *NonceRepo) Get(ctx context.Context, chain, address string) (*entity.NonceAccount, error) {
+	var acct entity.NonceAccount
+	err := r.col.FindOne(ctx, bson.M{"chain": chain, "address": address}).Decode(&acct)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &acct, nil
+}
+
+// ListByChain 某条链上所有分配过 nonce 的地址
+func (r *NonceRepo) ListByChain(ctx context.Context, chain string) ([]*entity.NonceAccount, error) {
+	cur, err := r.col.Find(ctx, bson.M{"chain": chain})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*entity.NonceAccount
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/linlinbupt123-crypto/wallet_service/db"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TokenRepo struct {
+	col *mongo.Collection
+}
+
+func NewTokenRepo() *TokenRepo {
+	return &TokenRepo{col: db.MongoDB.TokenColl}
+}
+
+// CheckIndexes 同一个网络上一个合约只登记一次，Upsert 依赖 (chain_id, address) 唯一
+func (r *TokenRepo) CheckIndexes(ctx context.Context) error {
+	return requireUniqueIndex(ctx, r.col, "chain_id", "address")
+}
+
+// Upsert 按 (chain_id, address) 写入；配置里的 token 覆盖之前自动发现的元数据
+func (r *TokenRepo) Upsert(ctx context.Context, token *entity.Token) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain_id": token.ChainID, "address": token.Address},
+		bson.M{
+			"$set": bson.M{
+				"symbol":   token.Symbol,
+				"name":     token.Name,
+				"decimals": token.Decimals,
+				"source":   token.Source,
+			},
+			"$setOnInsert": bson.M{"created_at": token.CreatedAt},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetByAddress 找不到返回 nil
+func (r *TokenRepo) GetByAddress(ctx context.Context, chainID int64, address string) (*entity.Token, error) {
+	var token entity.Token
+	err := r.col.FindOne(ctx, bson.M{"chain_id": chainID, "address": address}).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListBySymbol symbol 不区分大小写，不同合约可能同名
+func (r *TokenRepo) ListBySymbol(ctx context.Context, chainID int64, symbol string) ([]*entity.Token, error) {
+	return r.find(ctx, bson.M{
+		"chain_id": chainID,
+		"symbol":   primitive.Regex{Pattern: "^" + regexp.QuoteMeta(symbol) + "$", Options: "i"},
+	})
+}
+
+func (r *TokenRepo) ListByChain(ctx context.Context, chainID int64) ([]*entity.Token, error) {
+	return r.find(ctx, bson.M{"chain_id": chainID}, options.Find().SetSort(bson.D{{Key: "symbol", Value: 1}}))
+}
+
+func (r *TokenRepo) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*entity.Token, error) {
+	cur, err := r.col.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*entity.Token
+	for cur.Next(ctx) {
+		var token entity.Token
+		if err := cur.Decode(&token); err == nil {
+			out = append(out, &token)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
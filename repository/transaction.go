@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/db"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TransactionRepo struct {
+	col *mongo.Collection
+}
+
+func NewTransactionRepo() *TransactionRepo {
+	return &TransactionRepo{col: db.MongoDB.TxColl}
+}
+
+// Create 写入交易记录；同一 (chain, hash) 已经记录过 (例如同一笔签名交易被并发广播两次) 时不报错
+func (r *TransactionRepo) Create(ctx context.Context, tx *entity.Transaction) error {
+	_, err := r.col.InsertOne(ctx, tx)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// CheckIndexes (chain, hash) 必须唯一，否则重复广播会插出两条记录，tracker 和 watchdog 会各处理一遍
+func (r *TransactionRepo) CheckIndexes(ctx context.Context) error {
+	return requireUniqueIndex(ctx, r.col, "chain", "hash")
+}
+
+// GetByHash 根据 hash 查找，找不到返回 nil
+func (r *TransactionRepo) GetByHash(ctx context.Context, chain, hash string) (*entity.Transaction, error) {
+	var tx entity.Transaction
+	err := r.col.FindOne(ctx, bson.M{"chain": chain, "hash": hash}).Decode(&tx)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// ListTracking 还需要跟踪的交易：pending，以及确认数不足 confirmations 的已上链交易 (可能被 reorg)
+func (r *TransactionRepo) ListTracking(ctx context.Context, chain string, confirmations uint64) ([]*entity.Transaction, error) {
+	return r.find(ctx, bson.M{
+		"chain": chain,
+		"$or": bson.A{
+			bson.M{"status": entity.TxPending},
+			bson.M{
+				"status":        bson.M{"$in": bson.A{entity.TxConfirmed, entity.TxReverted}},
+				"confirmations": bson.M{"$lt": confirmations},
+			},
+		},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+// UpdateReceipt 写入回执、状态和确认数；回执被 reorg 掉时 tx 的回执字段为空，一并清掉
+func (r *TransactionRepo) UpdateReceipt(ctx context.Context, tx *entity.Transaction) error {
+	tx.UpdatedAt = time.Now()
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": tx.Chain, "hash": tx.Hash},
+		bson.M{"$set": bson.M{
+			"status":              tx.Status,
+			"block_number":        tx.BlockNumber,
+			"block_hash":          tx.BlockHash,
+			"gas_used":            tx.GasUsed,
+			"effective_gas_price": tx.EffectiveGasPrice,
+			"fee":                 tx.Fee,
+			"confirmations":       tx.Confirmations,
+			"confirmed_at":        tx.ConfirmedAt,
+			"updated_at":          tx.UpdatedAt,
+		}},
+	)
+	return err
+}
+
+// SetReplacedBy 记录 speed-up / cancel 发出的替换交易，原交易继续跟踪，哪一笔上链由 tracker 决定
+func (r *TransactionRepo) SetReplacedBy(ctx context.Context, chain, hash, replacedBy string) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": chain, "hash": hash},
+		bson.M{"$set": bson.M{"replaced_by_hash": replacedBy, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// MarkBroadcast 只签名的交易广播出去后转成 pending，交给 tracker 和 watchdog
+func (r *TransactionRepo) MarkBroadcast(ctx context.Context, chain, hash string, at time.Time) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": chain, "hash": hash, "status": entity.TxSigned},
+		bson.M{"$set": bson.M{"status": entity.TxPending, "broadcast_at": at, "updated_at": at}},
+	)
+	return err
+}
+
+// ListWatching 还在等待上链、没有被替换的交易，也就是每个 nonce 上最新的一笔
+func (r *TransactionRepo) ListWatching(ctx context.Context, chain string) ([]*entity.Transaction, error) {
+	return r.find(ctx, bson.M{
+		"chain":            chain,
+		"status":           entity.TxPending,
+		"replaced_by_hash": bson.M{"$in": bson.A{nil, ""}},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+// UpdateWatchdog 写入 watchdog 的处理进度
+func (r *TransactionRepo) UpdateWatchdog(ctx context.Context, tx *entity.Transaction) error {
+	tx.UpdatedAt = time.Now()
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": tx.Chain, "hash": tx.Hash},
+		bson.M{"$set": bson.M{
+			"broadcast_at":   tx.BroadcastAt,
+			"stuck_reported": tx.StuckReported,
+			"updated_at":     tx.UpdatedAt,
+		}},
+	)
+	return err
+}
+
+func (r *TransactionRepo) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*entity.Transaction, error) {
+	cur, err := r.col.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*entity.Transaction
+	for cur.Next(ctx) {
+		var tx entity.Transaction
+		if err := cur.Decode(&tx); err == nil {
+			out = append(out, &tx)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
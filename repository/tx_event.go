@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/linlinbupt123-crypto/wallet_service/db"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TxEventRepo struct {
+	col *mongo.Collection
+}
+
+func NewTxEventRepo() *TxEventRepo {
+	return &TxEventRepo{col: db.MongoDB.TxEventColl}
+}
+
+func (r *TxEventRepo) Create(ctx context.Context, ev *entity.TxEvent) error {
+	_, err := r.col.InsertOne(ctx, ev)
+	return err
+}
+
+// ListByNonce 同一个 nonce 上所有交易 (原交易和它的替换交易) 的事件，按时间排序
+func (r *TxEventRepo) ListByNonce(ctx context.Context, chain, from string, nonce uint64) ([]*entity.TxEvent, error) {
+	cur, err := r.col.Find(ctx,
+		bson.M{"chain": chain, "from": from, "nonce": nonce},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*entity.TxEvent
+	for cur.Next(ctx) {
+		var ev entity.TxEvent
+		if err := cur.Decode(&ev); err == nil {
+			out = append(out, &ev)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
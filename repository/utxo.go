@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/db"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UTXORepo struct {
+	col *mongo.Collection
+}
+
+func NewUTXORepo() *UTXORepo {
+	return &UTXORepo{col: db.MongoDB.UTXOColl}
+}
+
+// Upsert 按 (chain, txid, vout) 写入或更新 UTXO，只刷新链上状态字段；
+// 冻结和花费状态只在首次写入时生效，之后由 SetFrozen / MarkSpent / MarkUnspent 维护。
+// scantxoutset 看不到 mempool 里的花费，这里不能把 spent 重置回 false。返回写入后的记录
+func (r *UTXORepo) Upsert(ctx context.Context, u *entity.UTXO) (*entity.UTXO, error) {
+	now := time.Now()
+	onInsert := bson.M{
+		"user_id":    u.UserID,
+		"wallet_id":  u.WalletID,
+		"address":    u.Address,
+		"frozen":     u.Frozen,
+		"spent":      false,
+		"created_at": now,
+	}
+	if u.Frozen {
+		onInsert["frozen_reason"] = u.FrozenReason
+		onInsert["frozen_at"] = now
+	}
+	filter := bson.M{"chain": u.Chain, "txid": u.TxID, "vout": u.Vout}
+	update := bson.M{
+		"$set": bson.M{
+			"value":         u.Value,
+			"pk_script":     u.PkScript,
+			"height":        u.Height,
+			"confirmations": u.Confirmations,
+			"updated_at":    now,
+		},
+		"$setOnInsert": onInsert,
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var out entity.UTXO
+	if err := r.col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkSpent 标记某个输出已花费，已经标记过的不改 spent_at
+func (r *UTXORepo) MarkSpent(ctx context.Context, chain, txid string, vout uint32) error {
+	now := time.Now()
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": chain, "txid": txid, "vout": vout, "spent": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"spent": true, "spent_at": now, "updated_at": now}},
+	)
+	return err
+}
+
+// Reserve 发送前占用一个输出：只有仍未花费时才标记为已花费，返回是否占用成功。
+// 并发的两次发送选中同一个输出时只有一个能成功，失败的一方需要放弃本次发送
+func (r *UTXORepo) Reserve(ctx context.Context, chain, txid string, vout uint32) (bool, error) {
+	now := time.Now()
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": chain, "txid": txid, "vout": vout, "spent": false},
+		bson.M{"$set": bson.M{"spent": true, "spent_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// MarkUnspent 花费它的交易被丢弃 / 替换掉后，输出重新变为未花费
+func (r *UTXORepo) MarkUnspent(ctx context.Context, chain, txid string, vout uint32) error {
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"chain": chain, "txid": txid, "vout": vout, "spent": true},
+		bson.M{"$set": bson.M{"spent": false, "updated_at": time.Now()}, "$unset": bson.M{"spent_at": ""}},
+	)
+	return err
+}
+
+// Get 按 outpoint 查找，找不到返回 nil
+func (r *UTXORepo) Get(ctx context.Context, chain, txid string, vout uint32) (*entity.UTXO, error) {
+	var u entity.UTXO
+	err := r.col.FindOne(ctx, bson.M{"chain": chain, "txid": txid, "vout": vout}).Decode(&u)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// SetFrozen 冻结 / 解冻某个输出
+func (r *UTXORepo) SetFrozen(ctx context.Context, chain, txid string, vout uint32, frozen bool, reason string) error {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"frozen": frozen, "frozen_reason": reason, "frozen_at": now, "updated_at": now}}
+	if !frozen {
+		update = bson.M{
+			"$set":   bson.M{"frozen": false, "updated_at": now},
+			"$unset": bson.M{"frozen_reason": "", "frozen_at": ""},
+		}
+	}
+	_, err := r.col.UpdateOne(ctx, bson.M{"chain": chain, "txid": txid, "vout": vout}, update)
+	return err
+}
+
+// ListUnspentByAddresses 查询一组地址上未花费的 UTXO
+func (r *UTXORepo) ListUnspentByAddresses(ctx context.Context, chain string, addresses []string) ([]*entity.UTXO, error) {
+	return r.find(ctx, bson.M{
+		"chain":   chain,
+		"address": bson.M{"$in": addresses},
+		"spent":   false,
+	})
+}
+
+// ListUnspentByWallet 查询钱包在某条链上所有未花费的 UTXO，按金额从大到小
+func (r *UTXORepo) ListUnspentByWallet(ctx context.Context, walletID, chain string) ([]*entity.UTXO, error) {
+	return r.find(ctx, bson.M{
+		"wallet_id": walletID,
+		"chain":     chain,
+		"spent":     false,
+	}, options.Find().SetSort(bson.D{{Key: "value", Value: -1}}))
+}
+
+// ListUnspentByUser 查询用户在某条链上所有未花费的 UTXO
+func (r *UTXORepo) ListUnspentByUser(ctx context.Context, userID, chain string) ([]*entity.UTXO, error) {
+	return r.find(ctx, bson.M{
+		"user_id": userID,
+		"chain":   chain,
+		"spent":   false,
+	})
+}
+
+// ListByUser 查询用户在某条链上的 UTXO，includeSpent 为 false 时只返回未花费的，按高度倒序
+func (r *UTXORepo) ListByUser(ctx context.Context, userID, chain string, includeSpent bool) ([]*entity.UTXO, error) {
+	filter := bson.M{"user_id": userID, "chain": chain}
+	if !includeSpent {
+		filter["spent"] = false
+	}
+	return r.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "height", Value: -1}, {Key: "txid", Value: 1}, {Key: "vout", Value: 1}}))
+}
+
+func (r *UTXORepo) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*entity.UTXO, error) {
+	cur, err := r.col.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []*entity.UTXO
+	for cur.Next(ctx) {
+		var u entity.UTXO
+		if err := cur.Decode(&u); err == nil {
+			out = append(out, &u)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
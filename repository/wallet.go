@@ -1,5 +1,5 @@
 /*
-user_id → 唯一索引
+user_id + wallet_type → 索引 (HD 钱包每个用户一个，multisig 钱包可以有多个)
 XPub / EncryptedSeed / XPrvEncrypted / SaltHex / CreatedAt
 */
 package repository
@@ -72,3 +72,27 @@ func (r *Wallet) GetByID(ctx context.Context, walletID string) (*entity.Wallet,
 	}
 	return &w, nil
 }
+
+// UpdateFeePolicy 设置钱包的 ETH 自动加价策略，policy 为 nil 时清除
+func (r *Wallet) UpdateFeePolicy(ctx context.Context, walletID string, policy *entity.FeePolicy) error {
+	oid, err := primitive.ObjectIDFromHex(walletID)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{"fee_policy": policy}}
+	if policy == nil {
+		update = bson.M{"$unset": bson.M{"fee_policy": ""}}
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": oid}, update)
+	return err
+}
+
+// SetBTCAccountXPubs 保存 HD 钱包的 BTC 账户扩展公钥
+func (r *Wallet) SetBTCAccountXPubs(ctx context.Context, walletID string, xpubs map[string]string) error {
+	oid, err := primitive.ObjectIDFromHex(walletID)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{"btc_account_xpubs": xpubs}})
+	return err
+}
@@ -1,21 +1,26 @@
 package request
 
+import "encoding/json"
+
 type GetBalanceReq struct {
 	Chain string `json:"chain" binding:"required"`
+	Asset string `json:"asset"` // 可选，eth: 不填或 ETH 为原生币，否则为 token symbol 或合约地址
 }
 
 // --- 请求结构 ---
 type CreateWalletReq struct {
-	UserID     string `json:"user_id" binding:"required"`
-	Passphrase string `json:"passphrase" binding:"required"`
-	ChainName  string `json:"chain_name" binding:"required"`
+	UserID      string `json:"user_id" binding:"required"`
+	Passphrase  string `json:"passphrase" binding:"required"`
+	ChainName   string `json:"chain_name" binding:"required"`
+	AddressType string `json:"address_type"` // 可选，钱包默认 BTC 地址类型: p2pkh / p2sh-p2wpkh / p2wpkh / p2tr
 }
 
 type DeriveAddressRequst struct {
-	WalletID   string `json:"wallet_id" binding:"required"`
-	UserID     string `json:"user_id" binding:"required"`
-	Passphrase string `json:"passphrase" binding:"required"`
-	ChainName  string `json:"chain_name" binding:"required"`
+	WalletID    string `json:"wallet_id" binding:"required"`
+	UserID      string `json:"user_id" binding:"required"`
+	Passphrase  string `json:"passphrase" binding:"required"`
+	ChainName   string `json:"chain_name" binding:"required"`
+	AddressType string `json:"address_type"` // 可选，不填则使用钱包的默认 BTC 地址类型
 }
 
 type SendTxReq struct {
@@ -24,4 +29,249 @@ type SendTxReq struct {
 	To         string `json:"to" binding:"required"`
 	Amount     string `json:"amount" binding:"required"`
 	Passphrase string `json:"passphrase" binding:"required"`
+	FeeRate    int64  `json:"fee_rate"`    // 可选，btc 费率 sat/vB，不填则向节点估算
+	ConfTarget int    `json:"conf_target"` // 可选，btc 估算费率的确认目标 (区块数)
+	// 可选，btc 选币策略: bnb / largest_first / oldest_first / avoid_mixing
+	CoinSelection string `json:"coin_selection"`
+	Data          string `json:"data"`      // 可选，eth calldata (hex)
+	GasLimit      uint64 `json:"gas_limit"` // 可选，eth gas limit，不填则 EstimateGas 加余量
+	// 可选，eth: 不填或 ETH 为原生币，否则为 token symbol 或合约地址，amount 按 token 的 decimals 换算
+	Asset string `json:"asset"`
+	// 可选，eth: false 时只签名不广播，返回 raw_tx，之后可以走 /wallet/:userID/eth/tx/broadcast 发出
+	Broadcast *bool `json:"broadcast"`
+	// 可选，eth: 只能和 broadcast=false 一起用，按指定的 nonce 签名，不占用 nonce 管理器的 nonce
+	Nonce *uint64 `json:"nonce"`
+}
+
+// ReplaceETHTxReq speed-up / cancel 一笔 pending 的 ETH 交易
+type ReplaceETHTxReq struct {
+	Passphrase  string `json:"passphrase" binding:"required"`
+	BumpPercent uint64 `json:"bump_percent"` // 可选，费用上调百分比，低于 eth.replacement_bump 时按 replacement_bump
+}
+
+// ETHFeePolicyReq 设置钱包的卡住交易自动加价策略，只对之后发送的交易生效
+type ETHFeePolicyReq struct {
+	AutoBump     bool   `json:"auto_bump"`
+	MaxFeePerGas string `json:"max_fee_per_gas"` // wei，auto_bump 为 true 时必填
+	BumpPercent  uint64 `json:"bump_percent"`    // 可选，默认 eth.replacement_bump
+	MaxBumps     int    `json:"max_bumps"`       // 可选，默认 3
+}
+
+// AddTokenReq 按合约地址登记 ERC-20 token，元数据从链上读取
+type AddTokenReq struct {
+	Address string `json:"address" binding:"required"`
+}
+
+// SendNFTReq 用 safeTransferFrom 转出 ERC-721 / ERC-1155
+type SendNFTReq struct {
+	From       string `json:"from" binding:"required"`
+	To         string `json:"to" binding:"required"`
+	Collection string `json:"collection" binding:"required"` // 合约地址
+	TokenID    string `json:"token_id" binding:"required"`   // 十进制或 0x 十六进制
+	Amount     string `json:"amount"`                        // 可选，erc1155 数量，默认 1；erc721 只能是 1
+	Passphrase string `json:"passphrase" binding:"required"`
+	GasLimit   uint64 `json:"gas_limit"` // 可选，不填则 EstimateGas 加余量
+}
+
+// ContractCallReq 用 eth_call 只读调用合约方法
+type ContractCallReq struct {
+	Contract string            `json:"contract" binding:"required"`
+	ABI      json.RawMessage   `json:"abi" binding:"required"`    // JSON ABI 数组或单个方法 fragment
+	Method   string            `json:"method" binding:"required"` // 方法名，重载时用签名，如 transfer(address,uint256)
+	Args     []json.RawMessage `json:"args"`                      // 整数可以用数字或字符串，bytes / address 用 0x hex，tuple 用对象或数组
+	From     string            `json:"from"`                      // 可选，作为 msg.sender
+}
+
+// ContractWriteReq 从托管地址签名发送合约调用
+type ContractWriteReq struct {
+	From       string            `json:"from" binding:"required"`
+	Contract   string            `json:"contract" binding:"required"`
+	ABI        json.RawMessage   `json:"abi" binding:"required"`
+	Method     string            `json:"method" binding:"required"`
+	Args       []json.RawMessage `json:"args"`
+	Value      string            `json:"value"` // 可选，payable 方法附带的 ETH
+	Passphrase string            `json:"passphrase" binding:"required"`
+	GasLimit   uint64            `json:"gas_limit"` // 可选，不填则 EstimateGas 加余量
+}
+
+// ContractDeployReq 部署合约，构造参数按 abi 里的 constructor 编码后拼在 bytecode 后面
+type ContractDeployReq struct {
+	From       string            `json:"from" binding:"required"`
+	Bytecode   string            `json:"bytecode" binding:"required"` // 0x hex
+	ABI        json.RawMessage   `json:"abi"`                         // 有构造参数时必填
+	Args       []json.RawMessage `json:"args"`
+	Value      string            `json:"value"` // 可选，payable 构造函数附带的 ETH
+	Passphrase string            `json:"passphrase" binding:"required"`
+	GasLimit   uint64            `json:"gas_limit"`
+}
+
+// FillNonceGapReq 在空洞的 nonce 上发 0 ETH 自转账，需要解密私钥
+type FillNonceGapReq struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// BumpBTCFeeReq 加速未确认的 BTC 交易
+type BumpBTCFeeReq struct {
+	Method     string `json:"method" binding:"required"` // rbf / cpfp
+	Passphrase string `json:"passphrase" binding:"required"`
+	FeeRate    int64  `json:"fee_rate"`    // 可选，目标费率 sat/vB；cpfp 时为父子交易整体费率
+	ConfTarget int    `json:"conf_target"` // 可选，不填 fee_rate 时按该确认目标估算
+}
+
+// ExportBTCReq 导出 BTC 账户 descriptor / xpub
+type ExportBTCReq struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// CosignerReq multisig 的外部 cosigner
+type CosignerReq struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint" binding:"required"` // master fingerprint, 8 位 hex
+	Path        string `json:"path" binding:"required"`        // 账户路径，例如 m/48'/0'/0'/2'
+	XPub        string `json:"xpub" binding:"required"`        // xpub/tpub 或 Zpub/Vpub
+}
+
+// CreateMultisigReq 用本地 HD 账户 + 外部 cosigner 组建 m-of-n multisig 钱包
+type CreateMultisigReq struct {
+	Name       string        `json:"name" binding:"required"`
+	Threshold  int           `json:"threshold" binding:"required"`
+	Account    uint32        `json:"account"` // 本地 cosigner 的 BIP48 account
+	Passphrase string        `json:"passphrase" binding:"required"`
+	Cosigners  []CosignerReq `json:"cosigners" binding:"required"`
+}
+
+// MultisigSpendReq 从 multisig 钱包发起转账，本地先签自己那一份
+type MultisigSpendReq struct {
+	To            string `json:"to" binding:"required"`
+	Amount        string `json:"amount" binding:"required"`
+	Passphrase    string `json:"passphrase" binding:"required"`
+	FeeRate       int64  `json:"fee_rate"`
+	ConfTarget    int    `json:"conf_target"`
+	CoinSelection string `json:"coin_selection"`
+}
+
+// CreatePSBTReq 从 HD 钱包构造未签名 PSBT，交给离线 / 第三方签名
+type CreatePSBTReq struct {
+	To            string `json:"to" binding:"required"`
+	Amount        string `json:"amount" binding:"required"`
+	Passphrase    string `json:"passphrase" binding:"required"`
+	FeeRate       int64  `json:"fee_rate"`
+	ConfTarget    int    `json:"conf_target"`
+	CoinSelection string `json:"coin_selection"`
+}
+
+// FreezeUTXOReq 冻结 UTXO 的原因
+type FreezeUTXOReq struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ImportPSBTReq 导入其他签名方签过的 PSBT (base64)，也可以用 multipart 的 file 字段上传 .psbt 文件
+type ImportPSBTReq struct {
+	PSBT string `json:"psbt" binding:"required"`
+}
+
+// CreateTimelockReq 本地 HD 账户为 primary key，recovery key 在 older 个区块后也能花费
+type CreateTimelockReq struct {
+	Name       string      `json:"name" binding:"required"`
+	ScriptType string      `json:"script_type"` // p2wsh (默认) / p2tr
+	Older      uint32      `json:"older" binding:"required"`
+	Account    uint32      `json:"account"` // primary key 的 BIP48 account
+	Passphrase string      `json:"passphrase" binding:"required"`
+	Recovery   CosignerReq `json:"recovery" binding:"required"`
+}
+
+// TimelockSpendReq 从时间锁钱包走 primary 分支转账
+type TimelockSpendReq struct {
+	To            string `json:"to" binding:"required"`
+	Amount        string `json:"amount" binding:"required"`
+	Passphrase    string `json:"passphrase" binding:"required"`
+	FeeRate       int64  `json:"fee_rate"`
+	ConfTarget    int    `json:"conf_target"`
+	CoinSelection string `json:"coin_selection"`
+}
+
+// TimelockRecoveryReq 把时间锁已到期的输出全部转到 to。
+// passphrase 可选：recovery key 也来自本地 HD 钱包时直接签名广播，否则返回 PSBT 交给 recovery key 持有人签名
+type TimelockRecoveryReq struct {
+	To         string `json:"to" binding:"required"`
+	FeeRate    int64  `json:"fee_rate"`
+	ConfTarget int    `json:"conf_target"`
+	Passphrase string `json:"passphrase"`
+}
+
+// SignMessageReq 用受管的 BTC / LTC / DOGE 地址签名消息，证明地址归属，chain 为空时按 btc 处理
+type SignMessageReq struct {
+	Chain      string `json:"chain"`
+	Address    string `json:"address" binding:"required"`
+	Message    string `json:"message"`
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// VerifyMessageReq 验证任意地址的消息签名，chain 为空时按 btc 处理
+type VerifyMessageReq struct {
+	Chain     string `json:"chain"`
+	Address   string `json:"address" binding:"required"`
+	Message   string `json:"message"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// SignETHMessageReq personal_sign，message 为 0x hex 时按原始字节签名，否则按 UTF-8 文本
+type SignETHMessageReq struct {
+	Address    string `json:"address" binding:"required"`
+	Message    string `json:"message"`
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// SignTypedDataReq eth_signTypedData_v4，typed_data 可以是 JSON 对象，也可以是 JSON 字符串
+type SignTypedDataReq struct {
+	Address    string          `json:"address" binding:"required"`
+	TypedData  json.RawMessage `json:"typed_data" binding:"required"`
+	Passphrase string          `json:"passphrase" binding:"required"`
+}
+
+// VerifyETHMessageReq message 和 typed_data 二选一；address 为空时只返回恢复出的签名地址
+type VerifyETHMessageReq struct {
+	Address   string          `json:"address"`
+	Message   *string         `json:"message"`
+	TypedData json.RawMessage `json:"typed_data"`
+	Signature string          `json:"signature" binding:"required"`
+}
+
+// BroadcastETHTxReq 已签名交易的 RLP hex (eth_sendRawTransaction 的参数)
+type BroadcastETHTxReq struct {
+	RawTx string `json:"raw_tx" binding:"required"`
+}
+
+// ExportOfflineWalletReq 导出前核对 passphrase，离线端用同一个 passphrase 解密
+type ExportOfflineWalletReq struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// OfflineETHReq 构造未签名的 ETH 交易交给离线端签名，字段含义同 SendTxReq，不需要 passphrase
+type OfflineETHReq struct {
+	From     string `json:"from" binding:"required"`
+	To       string `json:"to" binding:"required"`
+	Amount   string `json:"amount" binding:"required"`
+	Asset    string `json:"asset"`
+	Data     string `json:"data"`
+	GasLimit uint64 `json:"gas_limit"`
+	Fragment int    `json:"fragment"` // 可选，每段 UR 的最大字节数，默认 200
+}
+
+// OfflineBTCReq 构造未签名的 PSBT 交给离线端签名，不需要 passphrase：
+// 用导出离线钱包时保存的账户 xpub 派生输入和找零的公钥
+type OfflineBTCReq struct {
+	WalletID      string `json:"wallet_id" binding:"required"`
+	To            string `json:"to" binding:"required"`
+	Amount        string `json:"amount" binding:"required"`
+	FeeRate       int64  `json:"fee_rate"`
+	ConfTarget    int    `json:"conf_target"`
+	CoinSelection string `json:"coin_selection"`
+	Fragment      int    `json:"fragment"`
+}
+
+// ImportOfflineReq 离线端签名结果，payload 为 JSON，或者 ur 为扫到的全部 UR 分段，二选一
+type ImportOfflineReq struct {
+	Payload string   `json:"payload"`
+	UR      []string `json:"ur"`
 }
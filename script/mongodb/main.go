@@ -52,16 +52,32 @@ func createIndexSafe(ctx context.Context, col *mongo.Collection, index mongo.Ind
 	return nil
 }
 
+// dropIndexSafe 删除旧版本留下的索引，不存在时忽略
+func dropIndexSafe(ctx context.Context, col *mongo.Collection, name string) error {
+	_, err := col.Indexes().DropOne(ctx, name)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 // 初始化所有 collection 索引
 func initIndexes(ctx context.Context, db *mongo.Database) error {
-	// addresses
+	// addresses: (chain, address) 唯一，LTC 和 BTC 的 testnet 地址会相同，旧的 address 唯一索引要删掉
 	addrCol := db.Collection("addresses")
+	if err := dropIndexSafe(ctx, addrCol, "address_1"); err != nil {
+		return fmt.Errorf("addresses index error: %w", err)
+	}
 	addrIndexes := []mongo.IndexModel{
-		{Keys: bson.M{"address": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "chain", Value: 1}, {Key: "address", Value: 1}}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.M{"user_id": 1}},
 		{Keys: bson.M{"wallet_id": 1}},
 		{Keys: bson.M{"chain": 1}},
 		{Keys: bson.D{{Key: "wallet_id", Value: 1}, {Key: "chain", Value: 1}, {Key: "index", Value: -1}}},
+		{Keys: bson.D{{Key: "wallet_id", Value: 1}, {Key: "chain", Value: 1}, {Key: "address_type", Value: 1}, {Key: "index", Value: -1}}},
 	}
 	for _, idx := range addrIndexes {
 		if err := createIndexSafe(ctx, addrCol, idx); err != nil {
@@ -82,10 +98,110 @@ func initIndexes(ctx context.Context, db *mongo.Database) error {
 		}
 	}
 
-	// wallets
+	// utxos
+	utxoCol := db.Collection("utxos")
+	utxoIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "chain", Value: 1}, {Key: "txid", Value: 1}, {Key: "vout", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "wallet_id", Value: 1}, {Key: "chain", Value: 1}, {Key: "spent", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "chain", Value: 1}, {Key: "spent", Value: 1}}},
+		{Keys: bson.M{"address": 1}},
+	}
+	for _, idx := range utxoIndexes {
+		if err := createIndexSafe(ctx, utxoCol, idx); err != nil {
+			return fmt.Errorf("utxos index error: %w", err)
+		}
+	}
+
+	// btc_txs
+	btcTxCol := db.Collection("btc_txs")
+	btcTxIndexes := []mongo.IndexModel{
+		{Keys: bson.M{"txid": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "wallet_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	}
+	for _, idx := range btcTxIndexes {
+		if err := createIndexSafe(ctx, btcTxCol, idx); err != nil {
+			return fmt.Errorf("btc_txs index error: %w", err)
+		}
+	}
+
+	// btc_psbts
+	btcPSBTCol := db.Collection("btc_psbts")
+	btcPSBTIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}}},
+	}
+	for _, idx := range btcPSBTIndexes {
+		if err := createIndexSafe(ctx, btcPSBTCol, idx); err != nil {
+			return fmt.Errorf("btc_psbts index error: %w", err)
+		}
+	}
+
+	// nonces: (chain, address) 唯一，nonce 锁依赖它
+	nonceCol := db.Collection("nonces")
+	nonceIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "chain", Value: 1}, {Key: "address", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}
+	for _, idx := range nonceIndexes {
+		if err := createIndexSafe(ctx, nonceCol, idx); err != nil {
+			return fmt.Errorf("nonces index error: %w", err)
+		}
+	}
+
+	// tokens: 同一个网络上一个合约只登记一次
+	tokenCol := db.Collection("tokens")
+	tokenIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "chain_id", Value: 1}, {Key: "address", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}
+	for _, idx := range tokenIndexes {
+		if err := createIndexSafe(ctx, tokenCol, idx); err != nil {
+			return fmt.Errorf("tokens index error: %w", err)
+		}
+	}
+
+	// nft_holdings / nft_sync
+	nftCol := db.Collection("nft_holdings")
+	nftIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "collection", Value: 1}, {Key: "token_id", Value: 1}, {Key: "owner", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "collection", Value: 1}, {Key: "token_id", Value: 1}}},
+	}
+	for _, idx := range nftIndexes {
+		if err := createIndexSafe(ctx, nftCol, idx); err != nil {
+			return fmt.Errorf("nft_holdings index error: %w", err)
+		}
+	}
+	if err := createIndexSafe(ctx, db.Collection("nft_sync"), mongo.IndexModel{
+		Keys: bson.M{"collection": 1}, Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("nft_sync index error: %w", err)
+	}
+
+	// transactions: (chain, hash) 唯一；tracker / watchdog 按 chain + status 扫描
+	txCol := db.Collection("transactions")
+	txIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "chain", Value: 1}, {Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "chain", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	}
+	for _, idx := range txIndexes {
+		if err := createIndexSafe(ctx, txCol, idx); err != nil {
+			return fmt.Errorf("transactions index error: %w", err)
+		}
+	}
+
+	// tx_events: 按交易和按 nonce 查事件
+	txEventCol := db.Collection("tx_events")
+	txEventIndexes := []mongo.IndexModel{
+		{Keys: bson.M{"tx_hash": 1}},
+		{Keys: bson.D{{Key: "chain", Value: 1}, {Key: "from", Value: 1}, {Key: "nonce", Value: 1}, {Key: "created_at", Value: 1}}},
+	}
+	for _, idx := range txEventIndexes {
+		if err := createIndexSafe(ctx, txEventCol, idx); err != nil {
+			return fmt.Errorf("tx_events index error: %w", err)
+		}
+	}
+
+	// wallets: 一个用户可以有一个 HD 钱包和多个 multisig 钱包
 	walletCol := db.Collection("wallets")
 	walletIndexes := []mongo.IndexModel{
-		{Keys: bson.M{"user_id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "wallet_type", Value: 1}}},
 	}
 	for _, idx := range walletIndexes {
 		if err := createIndexSafe(ctx, walletCol, idx); err != nil {
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	walletErr "github.com/linlinbupt123-crypto/wallet_service/errors"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// BTC 交易加速方式
+const (
+	BumpRBF  = "rbf"  // BIP125 替换：同样的输入，从找零里多付手续费
+	BumpCPFP = "cpfp" // child-pays-for-parent：花掉父交易的找零，让父子整体达到目标费率
+)
+
+// BumpBTCFee 加速本服务发出、尚未确认的 BTC 交易，返回新交易的 txid。
+// 已确认的交易做 RBF 会被节点以输入已花费拒绝
+func (s *WalletService) BumpBTCFee(ctx context.Context, userID, txid string, req *request.BumpBTCFeeReq) (string, error) {
+	if req.Method != BumpRBF && req.Method != BumpCPFP {
+		return "", fmt.Errorf("unsupported bump method: %s", req.Method)
+	}
+	record, err := s.BTCTxRepo.GetByTxID(ctx, txid)
+	if err != nil {
+		return "", err
+	}
+	if record == nil || record.UserID != userID {
+		return "", fmt.Errorf("btc tx %s not found", txid)
+	}
+	if record.Chain != "" && record.Chain != string(chain.BTC) {
+		return "", fmt.Errorf("fee bumping is not supported on %s", record.Chain)
+	}
+	if record.Status != entity.BTCTxPending {
+		return "", fmt.Errorf("btc tx %s is %s", txid, record.Status)
+	}
+
+	current := effectiveFeeRate(record)
+	feeRate := req.FeeRate
+	if feeRate <= 0 {
+		feeRate = s.BtcChain.EstimateFeeRate(ctx, req.ConfTarget)
+		// 估算值不比当前高时，至少提高 MinRelayFeeRate
+		if feeRate <= current {
+			feeRate = current + chain.MinRelayFeeRate
+		}
+	}
+	if feeRate <= current {
+		return "", fmt.Errorf("fee rate %d sat/vB must exceed current %d sat/vB", feeRate, current)
+	}
+
+	wallet, err := s.WalletRepo.GetByID(ctx, record.WalletID)
+	if err != nil {
+		return "", err
+	}
+	if wallet == nil {
+		return "", errors.New("wallet not found")
+	}
+	// multisig 需要其他 cosigner 重新签名，不能在服务端直接替换
+	if wallet.WalletType != utils.HdWalletType {
+		return "", errors.New("fee bumping requires an hd wallet")
+	}
+	seed, err := s.HDWalletDomain.DecryptSeed(wallet, req.Passphrase)
+	if err != nil {
+		return "", err
+	}
+	owners, err := s.btcAddressMap(ctx, s.BtcChain, wallet.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if req.Method == BumpRBF {
+		return s.bumpRBF(ctx, wallet, seed, owners, record, feeRate)
+	}
+	return s.bumpCPFP(ctx, wallet, seed, owners, record, feeRate)
+}
+
+// bumpRBF 用相同输入重新签名，差额从找零扣；找零低于 dust 时整个并入手续费
+func (s *WalletService) bumpRBF(
+	ctx context.Context,
+	wallet *entity.Wallet,
+	seed []byte,
+	owners map[string]*entity.Address,
+	record *entity.BTCTx,
+	feeRate int64,
+) (string, error) {
+	changeIdx := changeOutputIndex(record)
+	if changeIdx < 0 {
+		return "", errors.New("tx has no change output to pay for replacement")
+	}
+
+	// ECDSA 签名长度可能多 1 字节，每个输入留 1 vB 余量
+	vsize := record.VSize + int64(len(record.Inputs))
+	newFee := feeRate * vsize
+	// BIP125 rule 4: 新交易至少为自己的带宽多付 MinRelayFeeRate
+	if minFee := record.Fee + chain.MinRelayFeeRate*vsize; newFee < minFee {
+		newFee = minFee
+	}
+	newChange := record.Outputs[changeIdx].Value - (newFee - record.Fee)
+	if newChange < 0 {
+		return "", fmt.Errorf("%w: change %d sat cannot cover fee %d sat, try cpfp",
+			chain.ErrInsufficientFunds, record.Outputs[changeIdx].Value, newFee)
+	}
+
+	outs := append([]entity.BTCTxOutput{}, record.Outputs...)
+	if newChange < s.BtcChain.DustLimit {
+		outs = append(outs[:changeIdx], outs[changeIdx+1:]...)
+	} else {
+		outs[changeIdx].Value = newChange
+	}
+	outputs, err := s.btcRecordOutputs(seed, outs, owners)
+	if err != nil {
+		return "", err
+	}
+	utxos, err := s.btcRecordUTXOs(record.Inputs)
+	if err != nil {
+		return "", err
+	}
+	inputs, err := s.btcInputs(ctx, s.BtcChain, seedPubKeys(s.BtcChain, seed), utxos, owners)
+	if err != nil {
+		return "", err
+	}
+
+	tx, txid, err := s.signAndBroadcastBTC(ctx, s.BtcChain, seed, inputs, outputs)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.BTCTxRepo.MarkReplaced(ctx, record.TxID, txid); err != nil {
+		return txid, err
+	}
+	replacement := newBTCTxRecord(s.BtcChain, wallet, txid, tx, utxos, outputs, feeRate)
+	replacement.ReplacesTxID = record.TxID
+	if err := s.BTCTxRepo.Create(ctx, replacement); err != nil {
+		return txid, err
+	}
+	return txid, nil
+}
+
+// bumpCPFP 把父交易的找零全部转到新的找零地址，子交易手续费 = 父子整体按 feeRate 计算 - 父交易已付
+func (s *WalletService) bumpCPFP(
+	ctx context.Context,
+	wallet *entity.Wallet,
+	seed []byte,
+	owners map[string]*entity.Address,
+	record *entity.BTCTx,
+	feeRate int64,
+) (string, error) {
+	changeIdx := changeOutputIndex(record)
+	if changeIdx < 0 {
+		return "", errors.New("tx has no change output to spend")
+	}
+	vout := uint32(changeIdx)
+	parentChange := record.Outputs[changeIdx]
+	owner, ok := owners[parentChange.Address]
+	if !ok {
+		return "", fmt.Errorf("change address %s not found", parentChange.Address)
+	}
+	spent, err := s.BtcChain.IsSpent(ctx, record.TxID, vout)
+	if err != nil {
+		return "", err
+	}
+	if spent {
+		return "", fmt.Errorf("change output %s:%d already spent", record.TxID, vout)
+	}
+
+	dest, err := s.nextBTCChangeAddress(ctx, s.BtcChain, wallet, seedPubKeys(s.BtcChain, seed))
+	if err != nil {
+		return "", err
+	}
+	childVSize := chain.EstimateVSize([]chain.BTCAddressType{addressBTCType(owner)}, [][]byte{dest.pkScript})
+	childFee := feeRate*(record.VSize+childVSize) - record.Fee
+	value := parentChange.Value - childFee
+	if value < s.BtcChain.DustLimit {
+		return "", fmt.Errorf("%w: change %d sat cannot cover child fee %d sat",
+			chain.ErrInsufficientFunds, parentChange.Value, childFee)
+	}
+
+	utxos, err := s.btcRecordUTXOs([]entity.BTCTxInput{{
+		TxID:    record.TxID,
+		Vout:    vout,
+		Address: parentChange.Address,
+		Value:   parentChange.Value,
+	}})
+	if err != nil {
+		return "", err
+	}
+	inputs, err := s.btcInputs(ctx, s.BtcChain, seedPubKeys(s.BtcChain, seed), utxos, owners)
+	if err != nil {
+		return "", err
+	}
+	outputs := []chain.BTCOutput{{
+		Address:     dest.address.Address,
+		Value:       value,
+		AddressType: chain.BTCAddressType(dest.address.AddressType),
+		Path:        dest.path,
+		PubKey:      dest.pubKey,
+	}}
+
+	tx, txid, err := s.signAndBroadcastBTC(ctx, s.BtcChain, seed, inputs, outputs)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.AddressRepo.Create(ctx, dest.address); err != nil {
+		return txid, err
+	}
+	if err := s.UTXORepo.MarkSpent(ctx, "btc", record.TxID, vout); err != nil {
+		return txid, err
+	}
+	child := newBTCTxRecord(s.BtcChain, wallet, txid, tx, utxos, outputs, feeRate)
+	child.ParentTxID = record.TxID
+	if err := s.BTCTxRepo.Create(ctx, child); err != nil {
+		return txid, err
+	}
+	return txid, nil
+}
+
+// effectiveFeeRate 交易实际费率 (sat/vB，向上取整)
+func effectiveFeeRate(record *entity.BTCTx) int64 {
+	if record.VSize <= 0 {
+		return record.FeeRate
+	}
+	return (record.Fee + record.VSize - 1) / record.VSize
+}
+
+// changeOutputIndex 找零输出的 vout，没有时返回 -1
+func changeOutputIndex(record *entity.BTCTx) int {
+	for i, o := range record.Outputs {
+		if o.Change {
+			return i
+		}
+	}
+	return -1
+}
+
+// btcRecordUTXOs 把交易记录里的输入还原成 UTXO，脚本由地址重新计算
+func (s *WalletService) btcRecordUTXOs(ins []entity.BTCTxInput) ([]*entity.UTXO, error) {
+	out := make([]*entity.UTXO, 0, len(ins))
+	for _, in := range ins {
+		pkScript, err := s.BtcChain.PkScript(in.Address)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &entity.UTXO{
+			Chain:    "btc",
+			Address:  in.Address,
+			TxID:     in.TxID,
+			Vout:     in.Vout,
+			Value:    in.Value,
+			PkScript: hex.EncodeToString(pkScript),
+			Height:   in.Height,
+		})
+	}
+	return out, nil
+}
+
+// btcRecordOutputs 把交易记录里的输出还原成 BTCOutput，找零输出补齐派生信息
+func (s *WalletService) btcRecordOutputs(
+	seed []byte,
+	outs []entity.BTCTxOutput,
+	owners map[string]*entity.Address,
+) ([]chain.BTCOutput, error) {
+	outputs := make([]chain.BTCOutput, 0, len(outs))
+	for _, o := range outs {
+		out := chain.BTCOutput{Address: o.Address, Value: o.Value}
+		if o.Change {
+			owner, ok := owners[o.Address]
+			if !ok {
+				return nil, fmt.Errorf("change address %s not found", o.Address)
+			}
+			out.AddressType = addressBTCType(owner)
+			out.Path = btcAddressPath(s.BtcChain, owner)
+			pubKey, err := s.BtcChain.DerivePubKey(seed, out.Path)
+			if err != nil {
+				return nil, walletErr.WrapWithCode(walletErr.DeriveErr, "DerivePubKey", err)
+			}
+			out.PubKey = pubKey
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
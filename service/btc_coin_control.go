@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+)
+
+// ListBTCUTXOs 用户在 coin 链 (btc / ltc / doge，空为 btc) 上的 UTXO（含冻结状态），先同步再查询
+func (s *WalletService) ListBTCUTXOs(ctx context.Context, userID, coinName string, includeSpent bool) ([]*entity.UTXO, error) {
+	coin, err := s.coinControlChain(coinName)
+	if err != nil {
+		return nil, err
+	}
+	wallets, err := s.WalletRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range wallets {
+		if err := s.syncUTXOWallet(ctx, coin, w.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.UTXORepo.ListByUser(ctx, userID, string(coin.Name), includeSpent)
+}
+
+// FreezeBTCUTXO 冻结用户的某个输出，冻结后任何选币策略都不会花它
+func (s *WalletService) FreezeBTCUTXO(ctx context.Context, userID, coinName, txid string, vout uint32, reason string) error {
+	coin, err := s.coinControlChain(coinName)
+	if err != nil {
+		return err
+	}
+	if _, err := s.userBTCUTXO(ctx, coin, userID, txid, vout); err != nil {
+		return err
+	}
+	return s.UTXORepo.SetFrozen(ctx, string(coin.Name), txid, vout, true, reason)
+}
+
+// UnfreezeBTCUTXO 解冻用户的某个输出
+func (s *WalletService) UnfreezeBTCUTXO(ctx context.Context, userID, coinName, txid string, vout uint32) error {
+	coin, err := s.coinControlChain(coinName)
+	if err != nil {
+		return err
+	}
+	if _, err := s.userBTCUTXO(ctx, coin, userID, txid, vout); err != nil {
+		return err
+	}
+	return s.UTXORepo.SetFrozen(ctx, string(coin.Name), txid, vout, false, "")
+}
+
+// coinControlChain 按链名取 UTXO 链，为空时为 btc
+func (s *WalletService) coinControlChain(name string) (*chain.BTCChain, error) {
+	if name == "" {
+		name = string(chain.BTC)
+	}
+	coin := s.UTXOChains[chain.ChainType(name)]
+	if coin == nil {
+		return nil, fmt.Errorf("coin control is not supported on %s", name)
+	}
+	return coin, nil
+}
+
+// userBTCUTXO 查找属于用户的输出，不存在或不属于该用户时返回错误
+func (s *WalletService) userBTCUTXO(ctx context.Context, coin *chain.BTCChain, userID, txid string, vout uint32) (*entity.UTXO, error) {
+	u, err := s.UTXORepo.Get(ctx, string(coin.Name), txid, vout)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.UserID != userID {
+		return nil, fmt.Errorf("utxo %s not found", outpointKey(txid, vout))
+	}
+	return u, nil
+}
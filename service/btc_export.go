@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// btcExportTypes 导出顺序固定，方便外部钱包逐个导入
+var btcExportTypes = []chain.BTCAddressType{chain.P2PKH, chain.P2SHP2WPKH, chain.P2WPKH, chain.P2TR}
+
+// ExportBTCAccounts 导出用户 HD 钱包账户 0 在各地址类型下的 descriptor 和 SLIP-132 xpub，
+// 用于在 Sparrow / Bitcoin Core 中 watch-only 监控。master fingerprint 取自钱包已保存的 XPub，
+// 账户路径是 hardened 的，仍需要 passphrase 解密 seed 派生
+func (s *WalletService) ExportBTCAccounts(ctx context.Context, userID, passphrase string) ([]*chain.BTCAccountExport, error) {
+	wallets, err := s.WalletRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*chain.BTCAccountExport
+	for _, w := range wallets {
+		if w.WalletType != utils.HdWalletType {
+			continue
+		}
+		fingerprint, err := chain.XPubFingerprint(w.XPub)
+		if err != nil {
+			return nil, err
+		}
+		seed, err := s.HDWalletDomain.DecryptSeed(w, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range btcExportTypes {
+			export, err := s.BtcChain.AccountExport(seed, fingerprint, t, 0)
+			if err != nil {
+				return nil, err
+			}
+			export.WalletID = w.ID
+			out = append(out, export)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("hd wallet not found")
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	walletErr "github.com/linlinbupt123-crypto/wallet_service/errors"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// SignBTCMessage 用用户 HD 钱包里的地址签名消息，返回 base64 签名和签名格式
+// (P2PKH legacy signmessage，SegWit / Taproot 为 BIP322)
+func (s *WalletService) SignBTCMessage(ctx context.Context, userID string, req *request.SignMessageReq) (string, string, error) {
+	name := req.Chain
+	if name == "" {
+		name = string(chain.BTC)
+	}
+	coin := s.UTXOChains[chain.ChainType(name)]
+	if coin == nil {
+		return "", "", fmt.Errorf("message signing is not supported on %s", name)
+	}
+	addr, err := s.AddressRepo.GetByAddrID(ctx, name, req.Address)
+	if err != nil {
+		return "", "", err
+	}
+	if addr == nil || addr.UserID != userID {
+		return "", "", fmt.Errorf("address: %s not found or not belongs to user", req.Address)
+	}
+	wallet, err := s.WalletRepo.GetByID(ctx, addr.WalletID)
+	if err != nil {
+		return "", "", err
+	}
+	// multisig 地址没有单独的私钥
+	if wallet == nil || wallet.WalletType != utils.HdWalletType {
+		return "", "", errors.New("message signing requires an hd wallet address")
+	}
+	seed, err := s.HDWalletDomain.DecryptSeed(wallet, req.Passphrase)
+	if err != nil {
+		return "", "", err
+	}
+
+	key, err := coin.DeriveKey(seed, btcAddressPath(coin, addr))
+	if err != nil {
+		return "", "", walletErr.WrapWithCode(walletErr.DeriveErr, "DeriveKey", err)
+	}
+	defer key.Zero()
+	return coin.SignMessage(key, addressBTCType(addr), req.Message)
+}
+
+// VerifyBTCMessage 验证消息签名，签名不匹配时返回 chain.ErrInvalidMessageSignature
+func (s *WalletService) VerifyBTCMessage(req *request.VerifyMessageReq) (string, error) {
+	name := req.Chain
+	if name == "" {
+		name = string(chain.BTC)
+	}
+	coin := s.UTXOChains[chain.ChainType(name)]
+	if coin == nil {
+		return "", fmt.Errorf("message verification is not supported on %s", name)
+	}
+	return coin.VerifyMessage(req.Address, req.Message, req.Signature)
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	walletErr "github.com/linlinbupt123-crypto/wallet_service/errors"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// CreateMultisigWallet 用用户 HD 钱包的 BIP48 账户和外部 cosigner xpub 组建 m-of-n P2WSH 钱包，
+// 并派生第一个收款地址
+func (s *WalletService) CreateMultisigWallet(ctx context.Context, userID string, req *request.CreateMultisigReq) (*entity.Wallet, *entity.Address, error) {
+	signer, err := s.userHDWallet(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	seed, err := s.HDWalletDomain.DecryptSeed(signer, req.Passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	local, err := s.BtcChain.MultisigAccount(seed, req.Account)
+	if err != nil {
+		return nil, nil, walletErr.WrapWithCode(walletErr.DeriveErr, "MultisigAccount", err)
+	}
+
+	policy := &entity.MultisigPolicy{
+		Threshold:      req.Threshold,
+		SignerWalletID: signer.ID,
+		Account:        req.Account,
+		Cosigners: []entity.Cosigner{{
+			Name:        "local",
+			Fingerprint: local.Fingerprint,
+			Path:        local.Path,
+			XPub:        local.XPub,
+			Local:       true,
+		}},
+	}
+	for _, c := range req.Cosigners {
+		policy.Cosigners = append(policy.Cosigners, entity.Cosigner{
+			Name:        c.Name,
+			Fingerprint: c.Fingerprint,
+			Path:        chain.NormalizeDerivationPath(c.Path),
+			XPub:        c.XPub,
+		})
+	}
+	if err := s.BtcChain.ValidateMultisig(multisigPolicy(policy)); err != nil {
+		return nil, nil, err
+	}
+
+	wallet := &entity.Wallet{
+		UserID:         userID,
+		WalletName:     req.Name,
+		WalletType:     utils.MultisigWalletType,
+		BTCAddressType: string(chain.P2WSH),
+		Multisig:       policy,
+		CreatedAt:      time.Now(),
+	}
+	walletID, err := s.WalletRepo.Create(ctx, wallet)
+	if err != nil {
+		return nil, nil, err
+	}
+	wallet.ID = walletID
+
+	addr, _, err := s.nextMultisigAddress(ctx, wallet, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.AddressRepo.Create(ctx, addr); err != nil {
+		return nil, nil, err
+	}
+	return wallet, addr, nil
+}
+
+// DeriveMultisigAddress 派生 multisig 钱包的下一个收款地址，全部是公钥派生，不需要 passphrase
+func (s *WalletService) DeriveMultisigAddress(ctx context.Context, userID, walletID string) (*entity.Address, error) {
+	wallet, err := s.userMultisigWallet(ctx, userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	addr, _, err := s.nextMultisigAddress(ctx, wallet, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.AddressRepo.Create(ctx, addr); err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
+
+// MultisigDescriptors multisig 钱包收款 / 找零链的 wsh(sortedmulti) descriptor，用于导入其他钱包
+func (s *WalletService) MultisigDescriptors(ctx context.Context, userID, walletID string) (*entity.Wallet, map[string]string, error) {
+	wallet, err := s.userMultisigWallet(ctx, userID, walletID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ms := multisigPolicy(wallet.Multisig)
+	receive, err := s.BtcChain.MultisigDescriptor(ms, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	change, err := s.BtcChain.MultisigDescriptor(ms, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return wallet, map[string]string{"receive": receive, "change": change}, nil
+}
+
+// CreateMultisigSpend 从 multisig 钱包选币构造 PSBT，本地 cosigner 先签名后保存，
+// 选中的 UTXO 冻结起来避免被其他交易重复花费；阈值为 1 时直接广播
+func (s *WalletService) CreateMultisigSpend(ctx context.Context, userID, walletID string, req *request.MultisigSpendReq) (*entity.BTCPSBT, error) {
+	wallet, err := s.userMultisigWallet(ctx, userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	policy := wallet.Multisig
+	ms := multisigPolicy(policy)
+
+	amountSat, err := utils.BTCToSatoshi(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	toScript, err := s.BtcChain.PkScript(req.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid btc address: %w", err)
+	}
+	feeRate := req.FeeRate
+	if feeRate <= 0 {
+		feeRate = s.BtcChain.EstimateFeeRate(ctx, req.ConfTarget)
+	}
+	strategy := req.CoinSelection
+	if strategy == "" {
+		strategy = s.BtcChain.CoinSelection
+	}
+	selector, err := chain.NewCoinSelector(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := s.WalletRepo.GetByID(ctx, policy.SignerWalletID)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, errors.New("signer wallet not found")
+	}
+	seed, err := s.HDWalletDomain.DecryptSeed(signer, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 同步并选币，multisig 输入按策略估算 vsize
+	if err := s.SyncBTCWallet(ctx, wallet.ID); err != nil {
+		return nil, err
+	}
+	utxos, err := s.UTXORepo.ListUnspentByWallet(ctx, wallet.ID, "btc")
+	if err != nil {
+		return nil, err
+	}
+	owners, err := s.btcAddressMap(ctx, s.BtcChain, wallet.ID)
+	if err != nil {
+		return nil, err
+	}
+	coins := btcCoins(utxos, owners)
+	inputVSize := chain.MultisigInputVSize(policy.Threshold, len(policy.Cosigners))
+	for i := range coins {
+		coins[i].InputVSize = inputVSize
+	}
+
+	change, changeInfo, err := s.nextMultisigAddress(ctx, wallet, 1)
+	if err != nil {
+		return nil, err
+	}
+	selection, err := selector.Select(coins, chain.CoinSelectionParams{
+		Target:        amountSat,
+		FeeRate:       feeRate,
+		OutputScripts: [][]byte{toScript},
+		ChangeScript:  changeInfo.PkScript,
+		DustLimit:     s.BtcChain.DustLimit,
+		// 找零也是同一个策略的 multisig 地址
+		ChangeInputVSize: inputVSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	selected := selectedUTXOs(utxos, selection.Coins)
+
+	// 2. 构造 PSBT: 每个输入带 witness script 和所有 cosigner 的派生信息
+	inputs := make([]chain.BTCInput, 0, len(selected))
+	for _, u := range selected {
+		info, err := s.BtcChain.MultisigAddress(ms, owners[u.Address].Change, owners[u.Address].Index)
+		if err != nil {
+			return nil, err
+		}
+		pkScript, err := hex.DecodeString(u.PkScript)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, chain.BTCInput{
+			TxID:          u.TxID,
+			Vout:          u.Vout,
+			Value:         u.Value,
+			PkScript:      pkScript,
+			AddressType:   chain.P2WSH,
+			WitnessScript: info.WitnessScript,
+			KeyOrigins:    info.KeyOrigins,
+		})
+	}
+	outputs := []chain.BTCOutput{{Address: req.To, Value: amountSat}}
+	if selection.Change > 0 {
+		outputs = append(outputs, chain.BTCOutput{
+			Address:       change.Address,
+			Value:         selection.Change,
+			AddressType:   chain.P2WSH,
+			WitnessScript: changeInfo.WitnessScript,
+			KeyOrigins:    changeInfo.KeyOrigins,
+		})
+	}
+	fingerprint, err := s.BtcChain.Fingerprint(seed)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := s.BtcChain.BuildPSBT(fingerprint, inputs, outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 本地 cosigner 签名
+	if _, err := s.BtcChain.SignPSBT(packet, seed); err != nil {
+		return nil, err
+	}
+
+	// 4. 保存 PSBT、找零地址，冻结选中的输入
+	record, err := s.saveBTCPSBT(ctx, wallet, packet, selected, outputs, selection.Fee, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if selection.Change > 0 {
+		if err := s.AddressRepo.Create(ctx, change); err != nil {
+			return record, err
+		}
+	}
+
+	if chain.PSBTStatus(packet).Complete {
+		if err := s.broadcastBTCPSBT(ctx, record, packet); err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// nextMultisigAddress 派生 change 链上的下一个 multisig 地址，返回尚未落库的 Address
+func (s *WalletService) nextMultisigAddress(ctx context.Context, wallet *entity.Wallet, change uint32) (*entity.Address, *chain.BTCMultisigAddress, error) {
+	maxIndex, err := s.AddressRepo.GetMaxIndexByType(ctx, wallet.ID, "btc", string(chain.P2WSH), change)
+	if err != nil {
+		return nil, nil, err
+	}
+	index := uint32(maxIndex + 1)
+	info, err := s.BtcChain.MultisigAddress(multisigPolicy(wallet.Multisig), change, index)
+	if err != nil {
+		return nil, nil, walletErr.WrapWithCode(walletErr.DeriveErr, "MultisigAddress", err)
+	}
+	return &entity.Address{
+		UserID:      wallet.UserID,
+		WalletID:    wallet.ID,
+		Chain:       "btc",
+		Address:     info.Address,
+		Index:       index,
+		Change:      change,
+		AddressType: string(chain.P2WSH),
+		CreatedAt:   time.Now(),
+	}, info, nil
+}
+
+// multisigPolicy entity 策略转成 chain 层的 BTCMultisig
+func multisigPolicy(p *entity.MultisigPolicy) chain.BTCMultisig {
+	ms := chain.BTCMultisig{Threshold: p.Threshold}
+	for _, c := range p.Cosigners {
+		ms.Cosigners = append(ms.Cosigners, chain.BTCCosigner{
+			Fingerprint: c.Fingerprint,
+			Path:        c.Path,
+			XPub:        c.XPub,
+		})
+	}
+	return ms
+}
+
+// userHDWallet 用户的 HD 钱包
+func (s *WalletService) userHDWallet(ctx context.Context, userID string) (*entity.Wallet, error) {
+	wallets, err := s.WalletRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range wallets {
+		if w.WalletType == utils.HdWalletType {
+			return w, nil
+		}
+	}
+	return nil, errors.New("hd wallet not found")
+}
+
+// userMultisigWallet 查找属于用户的 multisig 钱包
+func (s *WalletService) userMultisigWallet(ctx context.Context, userID, walletID string) (*entity.Wallet, error) {
+	wallet, err := s.WalletRepo.GetByID(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil || wallet.UserID != userID || wallet.WalletType != utils.MultisigWalletType || wallet.Multisig == nil {
+		return nil, errors.New("multisig wallet not found")
+	}
+	return wallet, nil
+}
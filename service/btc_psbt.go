@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+)
+
+// CreateBTCPSBT 从用户 HD 钱包选币构造未签名的 PSBT 并保存，交给冷钱包 / 硬件钱包离线签名。
+// 输入带本钱包的 BIP32 派生信息，选中的 UTXO 冻结直到 PSBT 广播
+func (s *WalletService) CreateBTCPSBT(ctx context.Context, userID string, req *request.CreatePSBTReq) (*entity.BTCPSBT, error) {
+	wallet, err := s.userHDWallet(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := s.HDWalletDomain.DecryptSeed(wallet, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := s.BtcChain.Fingerprint(seed)
+	if err != nil {
+		return nil, err
+	}
+	return s.createBTCPSBT(ctx, wallet, fingerprint, seedPubKeys(s.BtcChain, seed), btcSpendParams{
+		coin:       s.BtcChain,
+		to:         req.To,
+		amount:     req.Amount,
+		feeRate:    req.FeeRate,
+		confTarget: req.ConfTarget,
+		strategy:   req.CoinSelection,
+	})
+}
+
+// createBTCPSBT 选币、构造并保存 PSBT；fingerprint 和 pubKeys 决定输入和找零带的派生信息
+func (s *WalletService) createBTCPSBT(ctx context.Context, wallet *entity.Wallet, fingerprint uint32, pubKeys btcPubKeys, params btcSpendParams) (*entity.BTCPSBT, error) {
+	spend, err := s.prepareBTCSpend(ctx, wallet, pubKeys, params)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := s.BtcChain.BuildPSBT(fingerprint, spend.inputs, spend.outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.saveBTCPSBT(ctx, wallet, packet, spend.selected, spend.outputs, spend.fee, spend.feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if spend.change != nil {
+		if err := s.AddressRepo.Create(ctx, spend.change.address); err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// ExportBTCPSBT 导出保存的 PSBT，二进制格式用于 .psbt 文件下载
+func (s *WalletService) ExportBTCPSBT(ctx context.Context, userID, id string) (*entity.BTCPSBT, []byte, error) {
+	record, packet, err := s.userBTCPSBT(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := chain.SerializePSBT(packet)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, raw, nil
+}
+
+// GetBTCPSBT 查询 PSBT 及其签名进度
+func (s *WalletService) GetBTCPSBT(ctx context.Context, userID, id string) (*entity.BTCPSBT, *chain.BTCPSBTStatus, error) {
+	record, packet, err := s.userBTCPSBT(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, chain.PSBTStatus(packet), nil
+}
+
+// ImportBTCPSBT 导入其他签名方签过的 PSBT (base64 或二进制)：先核对交易内容与发起时一致，
+// 再合并签名。签名够了之后通过 FinalizeBTCPSBT 广播
+func (s *WalletService) ImportBTCPSBT(ctx context.Context, userID, id string, data []byte) (*entity.BTCPSBT, *chain.BTCPSBTStatus, error) {
+	record, packet, err := s.userBTCPSBT(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record.Status != entity.BTCPSBTSigning {
+		return nil, nil, fmt.Errorf("psbt %s is %s", id, record.Status)
+	}
+	other, err := chain.DecodePSBT(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid psbt: %w", err)
+	}
+	if err := s.validatePSBTIntent(record, other); err != nil {
+		return nil, nil, err
+	}
+	if _, err := chain.CombinePSBT(packet, other); err != nil {
+		return nil, nil, err
+	}
+
+	status := chain.PSBTStatus(packet)
+	if record.PSBT, err = chain.EncodePSBT(packet); err != nil {
+		return nil, nil, err
+	}
+	record.Signers = status.Signers
+	if err := s.BTCPSBTRepo.Update(ctx, record); err != nil {
+		return nil, nil, err
+	}
+	return record, status, nil
+}
+
+// FinalizeBTCPSBT 签名达到阈值后 finalize 并广播
+func (s *WalletService) FinalizeBTCPSBT(ctx context.Context, userID, id string) (*entity.BTCPSBT, error) {
+	record, packet, err := s.userBTCPSBT(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != entity.BTCPSBTSigning {
+		return nil, fmt.Errorf("psbt %s is %s", id, record.Status)
+	}
+	if err := s.validatePSBTIntent(record, packet); err != nil {
+		return nil, err
+	}
+	status := chain.PSBTStatus(packet)
+	if !status.Complete {
+		return nil, fmt.Errorf("psbt %s is not fully signed", id)
+	}
+	if err := s.broadcastBTCPSBT(ctx, record, packet); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+// validatePSBTIntent 核对 PSBT 花费的输入、输出地址和金额与发起时记录的一致，
+// 防止签名方或传输过程篡改收款方 / 找零
+func (s *WalletService) validatePSBTIntent(record *entity.BTCPSBT, p *psbt.Packet) error {
+	txIns := p.UnsignedTx.TxIn
+	if len(txIns) != len(record.Inputs) {
+		return fmt.Errorf("psbt spends %d inputs, expected %d", len(txIns), len(record.Inputs))
+	}
+	for i, in := range record.Inputs {
+		prev := txIns[i].PreviousOutPoint
+		if prev.Hash.String() != in.TxID || prev.Index != in.Vout {
+			return fmt.Errorf("psbt input %d spends %s, expected %s", i, prev, outpointKey(in.TxID, in.Vout))
+		}
+	}
+
+	outputs := s.BtcChain.PSBTOutputs(p)
+	if len(outputs) != len(record.Outputs) {
+		return fmt.Errorf("psbt has %d outputs, expected %d", len(outputs), len(record.Outputs))
+	}
+	for i, want := range record.Outputs {
+		got := outputs[i]
+		if got.Address != want.Address || got.Value != want.Value {
+			return fmt.Errorf("psbt output %d pays %d sat to %s, expected %d sat to %s",
+				i, got.Value, got.Address, want.Value, want.Address)
+		}
+	}
+	return nil
+}
+
+// saveBTCPSBT 保存待签名的 PSBT，并冻结它花费的 UTXO
+func (s *WalletService) saveBTCPSBT(
+	ctx context.Context,
+	wallet *entity.Wallet,
+	packet *psbt.Packet,
+	selected []*entity.UTXO,
+	outputs []chain.BTCOutput,
+	fee, feeRate int64,
+) (*entity.BTCPSBT, error) {
+	encoded, err := chain.EncodePSBT(packet)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	record := &entity.BTCPSBT{
+		UserID:    wallet.UserID,
+		WalletID:  wallet.ID,
+		PSBT:      encoded,
+		Fee:       fee,
+		FeeRate:   feeRate,
+		Signers:   chain.PSBTStatus(packet).Signers,
+		Status:    entity.BTCPSBTSigning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, u := range selected {
+		record.Inputs = append(record.Inputs, entity.BTCTxInput{
+			TxID:    u.TxID,
+			Vout:    u.Vout,
+			Address: u.Address,
+			Value:   u.Value,
+			Height:  u.Height,
+		})
+	}
+	for _, o := range outputs {
+		record.Outputs = append(record.Outputs, entity.BTCTxOutput{
+			Address: o.Address,
+			Value:   o.Value,
+			Change:  o.IsChange(),
+		})
+	}
+	if record.ID, err = s.BTCPSBTRepo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+	for _, u := range selected {
+		if err := s.UTXORepo.SetFrozen(ctx, "btc", u.TxID, u.Vout, true, "psbt "+record.ID); err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// CancelBTCPSBT 放弃签名中的 PSBT，解冻为它冻结的输入。手动冻结或被别的 PSBT 冻结的输入不动
+func (s *WalletService) CancelBTCPSBT(ctx context.Context, userID, id string) (*entity.BTCPSBT, error) {
+	record, _, err := s.userBTCPSBT(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != entity.BTCPSBTSigning {
+		return nil, fmt.Errorf("psbt %s is %s", id, record.Status)
+	}
+	ok, err := s.BTCPSBTRepo.SetStatusFrom(ctx, id, entity.BTCPSBTSigning, entity.BTCPSBTAbandoned)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("psbt %s is no longer signing", id)
+	}
+	record.Status = entity.BTCPSBTAbandoned
+
+	reason := "psbt " + record.ID
+	for _, in := range record.Inputs {
+		u, err := s.UTXORepo.Get(ctx, "btc", in.TxID, in.Vout)
+		if err != nil {
+			return record, err
+		}
+		if u == nil || !u.Frozen || u.FrozenReason != reason {
+			continue
+		}
+		if err := s.UTXORepo.SetFrozen(ctx, "btc", in.TxID, in.Vout, false, ""); err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// broadcastBTCPSBT finalize 并广播，然后标记输入已花费、写交易记录
+func (s *WalletService) broadcastBTCPSBT(ctx context.Context, record *entity.BTCPSBT, packet *psbt.Packet) error {
+	tx, err := chain.FinalizePSBT(packet)
+	if err != nil {
+		return err
+	}
+	txid, err := s.BtcChain.Broadcast(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	record.Status = entity.BTCPSBTBroadcast
+	record.TxID = txid
+	if record.PSBT, err = chain.EncodePSBT(packet); err != nil {
+		return err
+	}
+	if err := s.BTCPSBTRepo.Update(ctx, record); err != nil {
+		return err
+	}
+	for _, in := range record.Inputs {
+		if err := s.UTXORepo.MarkSpent(ctx, "btc", in.TxID, in.Vout); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	return s.BTCTxRepo.Create(ctx, &entity.BTCTx{
+		Chain:     string(chain.BTC),
+		UserID:    record.UserID,
+		WalletID:  record.WalletID,
+		TxID:      txid,
+		Inputs:    record.Inputs,
+		Outputs:   record.Outputs,
+		Fee:       record.Fee,
+		FeeRate:   record.FeeRate,
+		VSize:     chain.TxVSize(tx),
+		RawTx:     encodeBTCTx(tx),
+		Status:    entity.BTCTxPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// userBTCPSBT 查找属于用户的 PSBT 并解码
+func (s *WalletService) userBTCPSBT(ctx context.Context, userID, id string) (*entity.BTCPSBT, *psbt.Packet, error) {
+	record, err := s.BTCPSBTRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record == nil || record.UserID != userID {
+		return nil, nil, fmt.Errorf("psbt %s not found", id)
+	}
+	packet, err := chain.DecodePSBT([]byte(record.PSBT))
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, packet, nil
+}
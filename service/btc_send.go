@@ -0,0 +1,462 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	walletErr "github.com/linlinbupt123-crypto/wallet_service/errors"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// sendBTC 从钱包在 coin 链 (btc / ltc / doge) 上的所有地址中选 UTXO，构造 PSBT（找零走 change=1 内部链），
+// 用 HD seed 签名、finalize 后通过后端广播，返回 txid
+func (s *WalletService) sendBTC(
+	ctx context.Context,
+	coin *chain.BTCChain,
+	wallet *entity.Wallet,
+	toAddress string,
+	req *request.SendTxReq,
+) (string, error) {
+	if wallet.WalletType != utils.HdWalletType {
+		return "", fmt.Errorf("%s send requires an hd wallet", coin.Name)
+	}
+	seed, err := s.HDWalletDomain.DecryptSeed(wallet, req.Passphrase)
+	if err != nil {
+		return "", err
+	}
+	spend, err := s.prepareBTCSpend(ctx, wallet, seedPubKeys(coin, seed), btcSpendParams{
+		coin:       coin,
+		to:         toAddress,
+		amount:     req.Amount,
+		feeRate:    req.FeeRate,
+		confTarget: req.ConfTarget,
+		strategy:   req.CoinSelection,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// 签名前先占用选中的输出，并发发送不会花同一个 UTXO；签名或广播失败时放回
+	if err := s.reserveUTXOs(ctx, coin, spend.selected); err != nil {
+		return "", err
+	}
+	tx, txid, err := s.signAndBroadcastBTC(ctx, coin, seed, spend.inputs, spend.outputs)
+	if err != nil {
+		// 广播超时的交易可能已经进了 mempool，放回后下次同步会用 IsSpent 重新标记
+		s.releaseUTXOs(ctx, coin, spend.selected)
+		return "", err
+	}
+
+	// 广播成功后再落库：找零地址、交易记录。交易已经发出去了，落库失败只记日志，照常返回 txid
+	if spend.change != nil {
+		if err := s.AddressRepo.Create(ctx, spend.change.address); err != nil {
+			log.Printf("%s tx %s: save change address %s: %v", coin.Name, txid, spend.change.address.Address, err)
+		}
+	}
+	if err := s.BTCTxRepo.Create(ctx, newBTCTxRecord(coin, wallet, txid, tx, spend.selected, spend.outputs, spend.feeRate)); err != nil {
+		log.Printf("%s tx %s: save tx record: %v", coin.Name, txid, err)
+	}
+	return txid, nil
+}
+
+// reserveUTXOs 逐个占用输出，有一个已被占用 (并发发送或刚同步到已花费) 就放回已占用的并报错
+func (s *WalletService) reserveUTXOs(ctx context.Context, coin *chain.BTCChain, utxos []*entity.UTXO) error {
+	for i, u := range utxos {
+		ok, err := s.UTXORepo.Reserve(ctx, string(coin.Name), u.TxID, u.Vout)
+		if err == nil && !ok {
+			err = fmt.Errorf("utxo %s:%d is already being spent, retry", u.TxID, u.Vout)
+		}
+		if err != nil {
+			s.releaseUTXOs(ctx, coin, utxos[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseUTXOs 发送失败后放回占用的输出，放回失败只记日志
+func (s *WalletService) releaseUTXOs(ctx context.Context, coin *chain.BTCChain, utxos []*entity.UTXO) {
+	for _, u := range utxos {
+		if err := s.UTXORepo.MarkUnspent(ctx, string(coin.Name), u.TxID, u.Vout); err != nil {
+			log.Printf("%s release utxo %s:%d: %v", coin.Name, u.TxID, u.Vout, err)
+		}
+	}
+}
+
+// btcSpendParams 一次 UTXO 转账的参数，费率/策略为空时使用估算值和配置默认值
+type btcSpendParams struct {
+	coin       *chain.BTCChain
+	to         string
+	amount     string // BTC / LTC / DOGE
+	feeRate    int64  // sat/vB
+	confTarget int
+	strategy   string
+}
+
+// btcSpend 选币完成、尚未签名的转账
+type btcSpend struct {
+	selected []*entity.UTXO
+	inputs   []chain.BTCInput
+	outputs  []chain.BTCOutput
+	change   *btcChange // 没有找零输出时为 nil
+	fee      int64
+	feeRate  int64
+}
+
+// prepareBTCSpend 同步钱包 UTXO、派生找零地址、选币，并补齐构造 PSBT 需要的输入输出信息
+func (s *WalletService) prepareBTCSpend(ctx context.Context, wallet *entity.Wallet, pubKeys btcPubKeys, params btcSpendParams) (*btcSpend, error) {
+	coin := params.coin
+	amountSat, err := utils.BTCToSatoshi(params.amount)
+	if err != nil {
+		return nil, err
+	}
+	toScript, err := coin.PkScript(params.to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s address: %w", coin.Name, err)
+	}
+	feeRate := params.feeRate
+	if feeRate <= 0 {
+		feeRate = coin.EstimateFeeRate(ctx, params.confTarget)
+	}
+	strategy := params.strategy
+	if strategy == "" {
+		strategy = coin.CoinSelection
+	}
+	selector, err := chain.NewCoinSelector(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 同步并取出钱包所有未花费输出
+	if err := s.syncUTXOWallet(ctx, coin, wallet.ID); err != nil {
+		return nil, err
+	}
+	utxos, err := s.UTXORepo.ListUnspentByWallet(ctx, wallet.ID, string(coin.Name))
+	if err != nil {
+		return nil, err
+	}
+	owners, err := s.btcAddressMap(ctx, coin, wallet.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 预先派生找零地址，估算手续费时需要它的脚本
+	change, err := s.nextBTCChangeAddress(ctx, coin, wallet, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 选币
+	selection, err := selector.Select(btcCoins(utxos, owners), chain.CoinSelectionParams{
+		Target:        amountSat,
+		FeeRate:       feeRate,
+		OutputScripts: [][]byte{toScript},
+		ChangeScript:  change.pkScript,
+		DustLimit:     coin.DustLimit,
+		ChangeType:    chain.BTCAddressType(change.address.AddressType),
+	})
+	if err != nil {
+		return nil, err
+	}
+	selected := selectedUTXOs(utxos, selection.Coins)
+
+	// 4. 输入输出
+	inputs, err := s.btcInputs(ctx, coin, pubKeys, selected, owners)
+	if err != nil {
+		return nil, err
+	}
+	spend := &btcSpend{
+		selected: selected,
+		inputs:   inputs,
+		outputs:  []chain.BTCOutput{{Address: params.to, Value: amountSat}},
+		fee:      selection.Fee,
+		feeRate:  feeRate,
+	}
+	if selection.Change > 0 {
+		spend.change = change
+		spend.outputs = append(spend.outputs, chain.BTCOutput{
+			Address:     change.address.Address,
+			Value:       selection.Change,
+			AddressType: chain.BTCAddressType(change.address.AddressType),
+			Path:        change.path,
+			PubKey:      change.pubKey,
+		})
+	}
+	return spend, nil
+}
+
+// signAndBroadcastBTC 构造 PSBT，用 seed 签名、finalize 后广播
+func (s *WalletService) signAndBroadcastBTC(
+	ctx context.Context,
+	coin *chain.BTCChain,
+	seed []byte,
+	inputs []chain.BTCInput,
+	outputs []chain.BTCOutput,
+) (*wire.MsgTx, string, error) {
+	fingerprint, err := coin.Fingerprint(seed)
+	if err != nil {
+		return nil, "", err
+	}
+	packet, err := coin.BuildPSBT(fingerprint, inputs, outputs)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := coin.SignPSBT(packet, seed); err != nil {
+		return nil, "", err
+	}
+	tx, err := chain.FinalizePSBT(packet)
+	if err != nil {
+		return nil, "", err
+	}
+	txid, err := coin.Broadcast(ctx, tx)
+	if err != nil {
+		return nil, "", err
+	}
+	return tx, txid, nil
+}
+
+// newBTCTxRecord 由已广播的交易生成交易记录，手续费按实际输入输出差额计算
+func newBTCTxRecord(
+	coin *chain.BTCChain,
+	wallet *entity.Wallet,
+	txid string,
+	tx *wire.MsgTx,
+	inputs []*entity.UTXO,
+	outputs []chain.BTCOutput,
+	feeRate int64,
+) *entity.BTCTx {
+	var fee int64
+	record := &entity.BTCTx{
+		Chain:    string(coin.Name),
+		UserID:   wallet.UserID,
+		WalletID: wallet.ID,
+		TxID:     txid,
+		FeeRate:  feeRate,
+		VSize:    chain.TxVSize(tx),
+		Status:   entity.BTCTxPending,
+	}
+	for _, u := range inputs {
+		record.Inputs = append(record.Inputs, entity.BTCTxInput{
+			TxID:    u.TxID,
+			Vout:    u.Vout,
+			Address: u.Address,
+			Value:   u.Value,
+			Height:  u.Height,
+		})
+		fee += u.Value
+	}
+	for _, o := range outputs {
+		// 只有找零输出带派生信息
+		change := o.IsChange()
+		record.Outputs = append(record.Outputs, entity.BTCTxOutput{Address: o.Address, Value: o.Value, Change: change})
+		fee -= o.Value
+	}
+	record.Fee = fee
+	record.RawTx = encodeBTCTx(tx)
+
+	now := time.Now()
+	record.CreatedAt, record.UpdatedAt = now, now
+	return record
+}
+
+// encodeBTCTx 交易序列化成 hex
+func encodeBTCTx(tx *wire.MsgTx) string {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// btcChange 预先派生、尚未落库的找零地址
+type btcChange struct {
+	address  *entity.Address
+	path     string
+	pubKey   []byte
+	pkScript []byte
+}
+
+// nextBTCChangeAddress 在内部链 (change=1) 上派生下一个找零地址，类型跟随钱包在该链的默认地址类型
+func (s *WalletService) nextBTCChangeAddress(ctx context.Context, coin *chain.BTCChain, wallet *entity.Wallet, pubKeys btcPubKeys) (*btcChange, error) {
+	addrType := walletAddressType(coin, wallet)
+	maxIndex, err := s.AddressRepo.GetMaxIndexByType(ctx, wallet.ID, string(coin.Name), string(addrType), 1)
+	if err != nil {
+		return nil, err
+	}
+	index := maxIndex + 1
+
+	path := generatePath(addrType.Purpose(), coin.CoinType(), 0, 1, index)
+	pubKey, err := pubKeys(path)
+	if err != nil {
+		return nil, walletErr.WrapWithCode(walletErr.DeriveErr, "derive change pubkey", err)
+	}
+	addr, err := coin.PubKeyAddress(pubKey, addrType)
+	if err != nil {
+		return nil, walletErr.WrapWithCode(walletErr.DeriveErr, "derive change address", err)
+	}
+	pkScript, err := coin.PkScript(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &btcChange{
+		address: &entity.Address{
+			UserID:      wallet.UserID,
+			WalletID:    wallet.ID,
+			Chain:       string(coin.Name),
+			Address:     addr,
+			Index:       uint32(index),
+			Change:      1,
+			AddressType: string(addrType),
+			CreatedAt:   time.Now(),
+		},
+		path:     path,
+		pubKey:   pubKey,
+		pkScript: pkScript,
+	}, nil
+}
+
+// btcPubKeys 按派生路径取压缩公钥：有 seed 时直接派生，构造离线签名请求时从保存的账户 xpub 派生
+type btcPubKeys func(path string) ([]byte, error)
+
+func seedPubKeys(coin *chain.BTCChain, seed []byte) btcPubKeys {
+	return func(path string) ([]byte, error) {
+		return coin.DerivePubKey(seed, path)
+	}
+}
+
+// accountXPubKeys 路径拆成账户路径和 change/index，账户 xpub 取自 wallet.BTCAccountXPubs
+func accountXPubKeys(coin *chain.BTCChain, wallet *entity.Wallet) btcPubKeys {
+	return func(path string) ([]byte, error) {
+		parts := strings.Split(path, "/")
+		if len(parts) != 6 {
+			return nil, fmt.Errorf("invalid derivation path %s", path)
+		}
+		account := strings.Join(parts[:4], "/")
+		xpub, ok := wallet.BTCAccountXPubs[account]
+		if !ok {
+			return nil, fmt.Errorf("no account xpub saved for %s, export the wallet for offline signing first", account)
+		}
+		change, err := strconv.ParseUint(parts[4], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %s", path)
+		}
+		index, err := strconv.ParseUint(parts[5], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %s", path)
+		}
+		return coin.DeriveXPubChild(xpub, uint32(change), uint32(index))
+	}
+}
+
+// btcAddressMap 钱包在 coin 链上的地址，按地址字符串索引
+func (s *WalletService) btcAddressMap(ctx context.Context, coin *chain.BTCChain, walletID string) (map[string]*entity.Address, error) {
+	addrs, err := s.AddressRepo.ListByWalletChain(ctx, walletID, string(coin.Name))
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]*entity.Address, len(addrs))
+	for _, a := range addrs {
+		out[a.Address] = a
+	}
+	return out, nil
+}
+
+// addressBTCType 地址的 BTC 类型，老数据没有该字段时按 legacy 处理
+func addressBTCType(a *entity.Address) chain.BTCAddressType {
+	if a.AddressType == "" {
+		return chain.P2PKH
+	}
+	return chain.BTCAddressType(a.AddressType)
+}
+
+// btcAddressPath 地址对应的完整派生路径
+func btcAddressPath(coin *chain.BTCChain, a *entity.Address) string {
+	return generatePath(addressBTCType(a).Purpose(), coin.CoinType(), 0, int(a.Change), int(a.Index))
+}
+
+// btcCoins 把钱包的 UTXO 转成选币候选，不属于钱包地址的输出跳过
+func btcCoins(utxos []*entity.UTXO, owners map[string]*entity.Address) []chain.Coin {
+	coins := make([]chain.Coin, 0, len(utxos))
+	for _, u := range utxos {
+		owner, ok := owners[u.Address]
+		if !ok {
+			continue
+		}
+		coins = append(coins, chain.Coin{
+			TxID:        u.TxID,
+			Vout:        u.Vout,
+			Value:       u.Value,
+			Address:     u.Address,
+			AddressType: addressBTCType(owner),
+			Height:      u.Height,
+			Frozen:      u.Frozen,
+		})
+	}
+	return coins
+}
+
+// selectedUTXOs 按选币结果的顺序取回对应的 UTXO
+func selectedUTXOs(utxos []*entity.UTXO, coins []chain.Coin) []*entity.UTXO {
+	byOutpoint := make(map[string]*entity.UTXO, len(utxos))
+	for _, u := range utxos {
+		byOutpoint[outpointKey(u.TxID, u.Vout)] = u
+	}
+	out := make([]*entity.UTXO, 0, len(coins))
+	for _, c := range coins {
+		out = append(out, byOutpoint[outpointKey(c.TxID, c.Vout)])
+	}
+	return out
+}
+
+// btcInputs 为选中的 UTXO 补齐派生路径、公钥，legacy 输入还需要前序交易
+func (s *WalletService) btcInputs(
+	ctx context.Context,
+	coin *chain.BTCChain,
+	pubKeys btcPubKeys,
+	utxos []*entity.UTXO,
+	owners map[string]*entity.Address,
+) ([]chain.BTCInput, error) {
+	inputs := make([]chain.BTCInput, 0, len(utxos))
+	for _, u := range utxos {
+		owner := owners[u.Address]
+		addrType := addressBTCType(owner)
+		path := btcAddressPath(coin, owner)
+
+		pubKey, err := pubKeys(path)
+		if err != nil {
+			return nil, walletErr.WrapWithCode(walletErr.DeriveErr, "DerivePubKey", err)
+		}
+		pkScript, err := hex.DecodeString(u.PkScript)
+		if err != nil {
+			return nil, err
+		}
+		in := chain.BTCInput{
+			TxID:        u.TxID,
+			Vout:        u.Vout,
+			Value:       u.Value,
+			PkScript:    pkScript,
+			AddressType: addrType,
+			Path:        path,
+			PubKey:      pubKey,
+		}
+		if addrType == chain.P2PKH {
+			in.PrevTx, err = coin.GetRawTransaction(ctx, u.TxID, u.Height)
+			if err != nil {
+				return nil, err
+			}
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
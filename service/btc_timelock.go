@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	walletErr "github.com/linlinbupt123-crypto/wallet_service/errors"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// CreateTimelockWallet 用户 HD 钱包的 BIP48 账户做 primary key，外部 recovery key 在 older 个区块后可花，
+// 并派生第一个收款地址
+func (s *WalletService) CreateTimelockWallet(ctx context.Context, userID string, req *request.CreateTimelockReq) (*entity.Wallet, *entity.Address, error) {
+	scriptType := chain.P2WSH
+	if req.ScriptType != "" {
+		scriptType = chain.BTCAddressType(req.ScriptType)
+	}
+	if scriptType != chain.P2WSH && scriptType != chain.P2TR {
+		return nil, nil, fmt.Errorf("unsupported timelock script type: %s", req.ScriptType)
+	}
+
+	signer, err := s.userHDWallet(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	seed, err := s.HDWalletDomain.DecryptSeed(signer, req.Passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	local, err := s.BtcChain.TimelockAccount(seed, scriptType, req.Account)
+	if err != nil {
+		return nil, nil, walletErr.WrapWithCode(walletErr.DeriveErr, "TimelockAccount", err)
+	}
+
+	policy := &entity.TimelockPolicy{
+		ScriptType:     string(scriptType),
+		Older:          req.Older,
+		SignerWalletID: signer.ID,
+		Account:        req.Account,
+		Primary: entity.Cosigner{
+			Name:        "local",
+			Fingerprint: local.Fingerprint,
+			Path:        local.Path,
+			XPub:        local.XPub,
+			Local:       true,
+		},
+		Recovery: entity.Cosigner{
+			Name:        req.Recovery.Name,
+			Fingerprint: req.Recovery.Fingerprint,
+			Path:        chain.NormalizeDerivationPath(req.Recovery.Path),
+			XPub:        req.Recovery.XPub,
+		},
+	}
+	if err := s.BtcChain.ValidateTimelock(timelockPolicy(policy)); err != nil {
+		return nil, nil, err
+	}
+
+	wallet := &entity.Wallet{
+		UserID:         userID,
+		WalletName:     req.Name,
+		WalletType:     utils.TimelockWalletType,
+		BTCAddressType: string(scriptType),
+		Timelock:       policy,
+		CreatedAt:      time.Now(),
+	}
+	walletID, err := s.WalletRepo.Create(ctx, wallet)
+	if err != nil {
+		return nil, nil, err
+	}
+	wallet.ID = walletID
+
+	addr, _, err := s.nextTimelockAddress(ctx, wallet, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.AddressRepo.Create(ctx, addr); err != nil {
+		return nil, nil, err
+	}
+	return wallet, addr, nil
+}
+
+// DeriveTimelockAddress 派生时间锁钱包的下一个收款地址
+func (s *WalletService) DeriveTimelockAddress(ctx context.Context, userID, walletID string) (*entity.Address, error) {
+	wallet, err := s.userTimelockWallet(ctx, userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	addr, _, err := s.nextTimelockAddress(ctx, wallet, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.AddressRepo.Create(ctx, addr); err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
+
+// TimelockDescriptors 时间锁钱包的 miniscript 策略和收款 / 找零链 descriptor
+func (s *WalletService) TimelockDescriptors(ctx context.Context, userID, walletID string) (*entity.Wallet, string, map[string]string, error) {
+	wallet, err := s.userTimelockWallet(ctx, userID, walletID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	t := timelockPolicy(wallet.Timelock)
+	receive, err := s.BtcChain.TimelockDescriptor(t, 0)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	change, err := s.BtcChain.TimelockDescriptor(t, 1)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	miniscript := chain.TimelockMiniscript(t.ScriptType, t.Older)
+	return wallet, miniscript, map[string]string{"receive": receive, "change": change}, nil
+}
+
+// CreateTimelockSpend 走 primary 分支转账：本地 key 一个签名就够，签完直接广播
+func (s *WalletService) CreateTimelockSpend(ctx context.Context, userID, walletID string, req *request.TimelockSpendReq) (*entity.BTCPSBT, error) {
+	wallet, err := s.userTimelockWallet(ctx, userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	policy := wallet.Timelock
+	t := timelockPolicy(policy)
+
+	amountSat, err := utils.BTCToSatoshi(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	toScript, err := s.BtcChain.PkScript(req.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid btc address: %w", err)
+	}
+	feeRate := req.FeeRate
+	if feeRate <= 0 {
+		feeRate = s.BtcChain.EstimateFeeRate(ctx, req.ConfTarget)
+	}
+	strategy := req.CoinSelection
+	if strategy == "" {
+		strategy = s.BtcChain.CoinSelection
+	}
+	selector, err := chain.NewCoinSelector(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := s.timelockSignerSeed(ctx, policy, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 同步并选币
+	if err := s.SyncBTCWallet(ctx, wallet.ID); err != nil {
+		return nil, err
+	}
+	utxos, err := s.UTXORepo.ListUnspentByWallet(ctx, wallet.ID, "btc")
+	if err != nil {
+		return nil, err
+	}
+	owners, err := s.btcAddressMap(ctx, s.BtcChain, wallet.ID)
+	if err != nil {
+		return nil, err
+	}
+	coins := btcCoins(utxos, owners)
+	inputVSize := chain.TimelockInputVSize(t.ScriptType, false)
+	for i := range coins {
+		coins[i].InputVSize = inputVSize
+	}
+
+	change, changeInfo, err := s.nextTimelockAddress(ctx, wallet, 1)
+	if err != nil {
+		return nil, err
+	}
+	selection, err := selector.Select(coins, chain.CoinSelectionParams{
+		Target:        amountSat,
+		FeeRate:       feeRate,
+		OutputScripts: [][]byte{toScript},
+		ChangeScript:  changeInfo.PkScript,
+		DustLimit:     s.BtcChain.DustLimit,
+		// 找零也是时间锁地址，按 primary 分支花费估算
+		ChangeInputVSize: inputVSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	selected := selectedUTXOs(utxos, selection.Coins)
+
+	// 2. 构造 PSBT 并用 primary key 签名
+	inputs, err := s.timelockInputs(t, selected, owners, false)
+	if err != nil {
+		return nil, err
+	}
+	outputs := []chain.BTCOutput{{Address: req.To, Value: amountSat}}
+	if selection.Change > 0 {
+		outputs = append(outputs, chain.BTCOutput{
+			Address:     change.Address,
+			Value:       selection.Change,
+			AddressType: t.ScriptType,
+			Timelock:    changeInfo,
+		})
+	}
+	fingerprint, err := s.BtcChain.Fingerprint(seed)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := s.BtcChain.BuildPSBT(fingerprint, inputs, outputs)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.BtcChain.SignPSBT(packet, seed); err != nil {
+		return nil, err
+	}
+
+	// 3. 保存 PSBT 和找零地址后广播
+	record, err := s.saveBTCPSBT(ctx, wallet, packet, selected, outputs, selection.Fee, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if selection.Change > 0 {
+		if err := s.AddressRepo.Create(ctx, change); err != nil {
+			return record, err
+		}
+	}
+	if !chain.PSBTStatus(packet).Complete {
+		return record, errors.New("primary key signature missing")
+	}
+	if err := s.broadcastBTCPSBT(ctx, record, packet); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+// CreateTimelockRecovery 走 recovery 分支把时间锁已到期的输出全部转走。
+// recovery key 来自本用户 HD 钱包且给了 passphrase 时直接签名广播，否则保存 PSBT 等 recovery key 持有人导入签名
+func (s *WalletService) CreateTimelockRecovery(ctx context.Context, userID, walletID string, req *request.TimelockRecoveryReq) (*entity.BTCPSBT, error) {
+	wallet, err := s.userTimelockWallet(ctx, userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	policy := wallet.Timelock
+	t := timelockPolicy(policy)
+
+	toScript, err := s.BtcChain.PkScript(req.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid btc address: %w", err)
+	}
+	feeRate := req.FeeRate
+	if feeRate <= 0 {
+		feeRate = s.BtcChain.EstimateFeeRate(ctx, req.ConfTarget)
+	}
+
+	// 1. 同步，只取已确认且过了相对时间锁的输出
+	if err := s.SyncBTCWallet(ctx, wallet.ID); err != nil {
+		return nil, err
+	}
+	tip, err := s.BtcChain.BlockHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := s.UTXORepo.ListUnspentByWallet(ctx, wallet.ID, "btc")
+	if err != nil {
+		return nil, err
+	}
+	owners, err := s.btcAddressMap(ctx, s.BtcChain, wallet.ID)
+	if err != nil {
+		return nil, err
+	}
+	var matured []*entity.UTXO
+	for _, u := range utxos {
+		if _, ok := owners[u.Address]; !ok || u.Frozen || u.Height <= 0 {
+			continue
+		}
+		// 下一个区块里 nSequence = older 的输入要求确认数 >= older
+		if tip+1 >= u.Height+int64(policy.Older) {
+			matured = append(matured, u)
+		}
+	}
+	if len(matured) == 0 {
+		return nil, fmt.Errorf("no outputs past the %d block timelock", policy.Older)
+	}
+
+	// 2. 扣掉手续费后全部转给 to
+	coins := btcCoins(matured, owners)
+	inputVSize := chain.TimelockInputVSize(t.ScriptType, true)
+	var total int64
+	for i := range coins {
+		coins[i].InputVSize = inputVSize
+		total += coins[i].Value
+	}
+	fee := chain.EstimateCoinsVSize(coins, [][]byte{toScript}) * feeRate
+	amount := total - fee
+	if amount < s.BtcChain.DustLimit {
+		return nil, fmt.Errorf("matured balance %d sat does not cover fee %d sat", total, fee)
+	}
+
+	inputs, err := s.timelockInputs(t, matured, owners, true)
+	if err != nil {
+		return nil, err
+	}
+	outputs := []chain.BTCOutput{{Address: req.To, Value: amount}}
+
+	// 3. 有 passphrase 时用本地 HD 钱包尝试签 recovery key
+	var seed []byte
+	var fingerprint uint32
+	if req.Passphrase != "" {
+		seed, err = s.timelockSignerSeed(ctx, policy, req.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		fingerprint, err = s.BtcChain.Fingerprint(seed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	packet, err := s.BtcChain.BuildPSBT(fingerprint, inputs, outputs)
+	if err != nil {
+		return nil, err
+	}
+	if seed != nil {
+		if _, err := s.BtcChain.SignPSBT(packet, seed); err != nil {
+			return nil, err
+		}
+	}
+
+	record, err := s.saveBTCPSBT(ctx, wallet, packet, matured, outputs, fee, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if chain.PSBTStatus(packet).Complete {
+		if err := s.broadcastBTCPSBT(ctx, record, packet); err != nil {
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// timelockInputs 时间锁输入带上脚本和两把 key 的派生信息
+func (s *WalletService) timelockInputs(t chain.BTCTimelock, utxos []*entity.UTXO, owners map[string]*entity.Address, recovery bool) ([]chain.BTCInput, error) {
+	inputs := make([]chain.BTCInput, 0, len(utxos))
+	for _, u := range utxos {
+		info, err := s.BtcChain.TimelockAddress(t, owners[u.Address].Change, owners[u.Address].Index)
+		if err != nil {
+			return nil, err
+		}
+		pkScript, err := hex.DecodeString(u.PkScript)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, chain.BTCInput{
+			TxID:        u.TxID,
+			Vout:        u.Vout,
+			Value:       u.Value,
+			PkScript:    pkScript,
+			AddressType: t.ScriptType,
+			Timelock:    info,
+			Recovery:    recovery,
+		})
+	}
+	return inputs, nil
+}
+
+// timelockSignerSeed 解密创建时间锁钱包用的 HD 钱包 seed
+func (s *WalletService) timelockSignerSeed(ctx context.Context, policy *entity.TimelockPolicy, passphrase string) ([]byte, error) {
+	signer, err := s.WalletRepo.GetByID(ctx, policy.SignerWalletID)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, errors.New("signer wallet not found")
+	}
+	return s.HDWalletDomain.DecryptSeed(signer, passphrase)
+}
+
+// nextTimelockAddress 派生 change 链上的下一个时间锁地址，返回尚未落库的 Address
+func (s *WalletService) nextTimelockAddress(ctx context.Context, wallet *entity.Wallet, change uint32) (*entity.Address, *chain.BTCTimelockAddress, error) {
+	scriptType := wallet.Timelock.ScriptType
+	maxIndex, err := s.AddressRepo.GetMaxIndexByType(ctx, wallet.ID, "btc", scriptType, change)
+	if err != nil {
+		return nil, nil, err
+	}
+	index := uint32(maxIndex + 1)
+	info, err := s.BtcChain.TimelockAddress(timelockPolicy(wallet.Timelock), change, index)
+	if err != nil {
+		return nil, nil, walletErr.WrapWithCode(walletErr.DeriveErr, "TimelockAddress", err)
+	}
+	return &entity.Address{
+		UserID:      wallet.UserID,
+		WalletID:    wallet.ID,
+		Chain:       "btc",
+		Address:     info.Address,
+		Index:       index,
+		Change:      change,
+		AddressType: scriptType,
+		CreatedAt:   time.Now(),
+	}, info, nil
+}
+
+// timelockPolicy entity 策略转成 chain 层的 BTCTimelock
+func timelockPolicy(p *entity.TimelockPolicy) chain.BTCTimelock {
+	return chain.BTCTimelock{
+		ScriptType: chain.BTCAddressType(p.ScriptType),
+		Older:      p.Older,
+		Primary: chain.BTCCosigner{
+			Fingerprint: p.Primary.Fingerprint,
+			Path:        p.Primary.Path,
+			XPub:        p.Primary.XPub,
+		},
+		Recovery: chain.BTCCosigner{
+			Fingerprint: p.Recovery.Fingerprint,
+			Path:        p.Recovery.Path,
+			XPub:        p.Recovery.XPub,
+		},
+	}
+}
+
+// userTimelockWallet 查找属于用户的时间锁钱包
+func (s *WalletService) userTimelockWallet(ctx context.Context, userID, walletID string) (*entity.Wallet, error) {
+	wallet, err := s.WalletRepo.GetByID(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil || wallet.UserID != userID || wallet.WalletType != utils.TimelockWalletType || wallet.Timelock == nil {
+		return nil, errors.New("timelock wallet not found")
+	}
+	return wallet, nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+)
+
+// SyncBTCWallet 同步钱包所有 BTC 地址的 UTXO
+func (s *WalletService) SyncBTCWallet(ctx context.Context, walletID string) error {
+	return s.syncUTXOWallet(ctx, s.BtcChain, walletID)
+}
+
+// syncUTXOWallet 同步钱包在 coin 链上所有地址的 UTXO
+func (s *WalletService) syncUTXOWallet(ctx context.Context, coin *chain.BTCChain, walletID string) error {
+	addrs, err := s.AddressRepo.ListByWalletChain(ctx, walletID, string(coin.Name))
+	if err != nil {
+		return err
+	}
+	return s.syncBTCAddresses(ctx, coin, addrs)
+}
+
+// StartBTCUTXOTracker 后台定时同步 coin 链上所有受管地址的 UTXO 和本服务发出的交易状态，
+// 没有配置后端的链不启动，可通过 ctx 停止
+func (s *WalletService) StartBTCUTXOTracker(ctx context.Context, name chain.ChainType, interval time.Duration) {
+	coin := s.UTXOChains[name]
+	if coin == nil || coin.Backend == nil {
+		log.Printf("%s utxo tracker: backend not configured, not started", name)
+		return
+	}
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Printf("%s utxo tracker stopped", name)
+				return
+
+			case <-ticker.C:
+				addrs, err := s.AddressRepo.ListByChain(ctx, string(name))
+				if err != nil {
+					log.Printf("%s utxo tracker: list addresses: %v", name, err)
+					continue
+				}
+				if err := s.syncBTCAddresses(ctx, coin, addrs); err != nil {
+					log.Printf("%s utxo tracker: sync: %v", name, err)
+				}
+				if err := s.syncBTCTxs(ctx, coin); err != nil {
+					log.Printf("%s utxo tracker: sync txs: %v", name, err)
+				}
+			}
+		}
+	}()
+}
+
+// syncBTCTxs 更新本服务发出的交易状态：上链后记录高度和确认数，直到 BTCTxFinalConfirmations；
+// 节点上找不到的 pending 交易，输入被别的交易花掉了视为被替换，输入都没花掉视为被丢弃并把输入放回可用。
+// 没有 txindex 的 bitcoind 查不到输出全部花掉的已确认交易，这种情况保持原状态不动
+func (s *WalletService) syncBTCTxs(ctx context.Context, coin *chain.BTCChain) error {
+	txs, err := s.BTCTxRepo.ListTracking(ctx, string(coin.Name), entity.BTCTxFinalConfirmations)
+	if err != nil || len(txs) == 0 {
+		return err
+	}
+	tip, err := coin.BlockHeight(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		height, found, err := coin.TxHeight(ctx, tx.TxID, len(tx.Outputs))
+		if err != nil {
+			return err
+		}
+		switch {
+		case found && height > 0:
+			err = s.BTCTxRepo.MarkConfirmed(ctx, tx.TxID, height, tip-height+1)
+		case found:
+			// 区块重组后回到 mempool
+			if tx.Status == entity.BTCTxConfirmed {
+				err = s.BTCTxRepo.SetStatus(ctx, tx.TxID, entity.BTCTxPending)
+			}
+		case tx.Status == entity.BTCTxPending:
+			err = s.markBTCTxGone(ctx, coin, tx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markBTCTxGone pending 交易从节点上消失：输入被另一笔交易花掉说明被替换，输入都没被花掉说明被 mempool 丢弃；
+// 输入已花费但查不到花费方 (例如 bitcoind 上已经上链的花费)，无法区分是被替换还是自己已确认，保持原状态
+func (s *WalletService) markBTCTxGone(ctx context.Context, coin *chain.BTCChain, tx *entity.BTCTx) error {
+	for _, in := range tx.Inputs {
+		spent, err := coin.IsSpent(ctx, in.TxID, in.Vout)
+		if err != nil {
+			return err
+		}
+		if !spent {
+			continue
+		}
+		spender, err := coin.SpendingTxID(ctx, in.TxID, in.Vout)
+		if err != nil {
+			return err
+		}
+		if spender == "" || spender == tx.TxID {
+			return nil
+		}
+		log.Printf("%s tx %s: input %s:%d spent by %s, marking replaced", coin.Name, tx.TxID, in.TxID, in.Vout, spender)
+		return s.BTCTxRepo.SetStatus(ctx, tx.TxID, entity.BTCTxReplaced)
+	}
+	log.Printf("%s tx %s: dropped from the mempool", coin.Name, tx.TxID)
+	if err := s.BTCTxRepo.SetStatus(ctx, tx.TxID, entity.BTCTxDropped); err != nil {
+		return err
+	}
+	for _, in := range tx.Inputs {
+		if err := s.UTXORepo.MarkUnspent(ctx, string(coin.Name), in.TxID, in.Vout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncBTCAddresses 拉取地址的未花费输出写入 utxos。scantxoutset 只看已确认的 UTXO 集，
+// 后端返回、本地还未标记花费的输出要用 IsSpent (含 mempool) 确认一次，否则刚发出去的交易花掉的输出会被重新选中；
+// 本地已标记花费的不再查询，花费交易被丢弃时由 markBTCTxGone 放回。
+// 本地仍标记为未花费但后端已不再返回的输出，同样逐个确认是否已被花费
+func (s *WalletService) syncBTCAddresses(ctx context.Context, coin *chain.BTCChain, addrs []*entity.Address) error {
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	tip, err := coin.BlockHeight(ctx)
+	if err != nil {
+		return err
+	}
+
+	byAddr := make(map[string]*entity.Address, len(addrs))
+	list := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		byAddr[a.Address] = a
+		list = append(list, a.Address)
+	}
+
+	unspent, err := coin.ListUnspent(ctx, list)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(unspent))
+	for _, u := range unspent {
+		owner, ok := byAddr[u.Address]
+		if !ok {
+			continue
+		}
+		pkScript, err := coin.PkScript(u.Address)
+		if err != nil {
+			return err
+		}
+		var confirmations int64
+		if u.Height > 0 {
+			confirmations = tip - u.Height + 1
+		}
+		// 收款地址 (change=0) 上的小额输出首次出现时自动冻结，找零不冻结
+		frozen := owner.Change == 0 && u.Value < coin.FreezeDustBelow
+		var frozenReason string
+		if frozen {
+			frozenReason = entity.FrozenReasonDust
+		}
+		stored, err := s.UTXORepo.Upsert(ctx, &entity.UTXO{
+			UserID:        owner.UserID,
+			WalletID:      owner.WalletID,
+			Chain:         string(coin.Name),
+			Address:       u.Address,
+			TxID:          u.TxID,
+			Vout:          u.Vout,
+			Value:         u.Value,
+			PkScript:      hex.EncodeToString(pkScript),
+			Height:        u.Height,
+			Confirmations: confirmations,
+			Frozen:        frozen,
+			FrozenReason:  frozenReason,
+		})
+		if err != nil {
+			return err
+		}
+		seen[outpointKey(u.TxID, u.Vout)] = true
+		if stored.Spent {
+			continue
+		}
+		spent, err := coin.IsSpent(ctx, u.TxID, u.Vout)
+		if err != nil {
+			return err
+		}
+		if spent {
+			if err := s.UTXORepo.MarkSpent(ctx, string(coin.Name), u.TxID, u.Vout); err != nil {
+				return err
+			}
+		}
+	}
+
+	stored, err := s.UTXORepo.ListUnspentByAddresses(ctx, string(coin.Name), list)
+	if err != nil {
+		return err
+	}
+	for _, u := range stored {
+		if seen[outpointKey(u.TxID, u.Vout)] {
+			continue
+		}
+		spent, err := coin.IsSpent(ctx, u.TxID, u.Vout)
+		if err != nil {
+			return err
+		}
+		if spent {
+			if err := s.UTXORepo.MarkSpent(ctx, string(coin.Name), u.TxID, u.Vout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// btcBalance 汇总 utxos 里用户在 coin 链上所有未花费输出 (satoshi)，数据由后台同步维护；
+// refresh 为 true 时先同步一次用户的地址，会对后端发起 scantxoutset，不要每次查询都带上
+func (s *WalletService) btcBalance(ctx context.Context, coin *chain.BTCChain, userID string, refresh bool) (int64, error) {
+	addrs, err := s.AddressRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	var btcAddrs []*entity.Address
+	for _, a := range addrs {
+		if a.Chain == string(coin.Name) {
+			btcAddrs = append(btcAddrs, a)
+		}
+	}
+	if len(btcAddrs) == 0 {
+		return 0, errors.New("address not found")
+	}
+	if refresh {
+		if err := s.syncBTCAddresses(ctx, coin, btcAddrs); err != nil {
+			return 0, err
+		}
+	}
+
+	utxos, err := s.UTXORepo.ListUnspentByUser(ctx, userID, string(coin.Name))
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, u := range utxos {
+		total += u.Value
+	}
+	return total, nil
+}
+
+func outpointKey(txid string, vout uint32) string {
+	return fmt.Sprintf("%s:%d", txid, vout)
+}
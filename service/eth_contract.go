@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// CallContract eth_call 只读调用，返回解码后的输出
+func (s *WalletService) CallContract(ctx context.Context, req *request.ContractCallReq) ([]chain.ContractValue, error) {
+	contract, err := utils.NormalizeETHAddress(req.Contract)
+	if err != nil {
+		return nil, err
+	}
+	var from string
+	if req.From != "" {
+		if from, err = utils.NormalizeETHAddress(req.From); err != nil {
+			return nil, err
+		}
+	}
+	method, data, err := packContractCall(req.ABI, req.Method, req.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.EthChain.CallContract(ctx, from, contract, data)
+	if err != nil {
+		return nil, err
+	}
+	return chain.UnpackContractOutputs(method.Outputs, out)
+}
+
+// WriteContract 从托管地址签名发送合约调用，走和 ETH 转账相同的 nonce 和交易记录流程
+func (s *WalletService) WriteContract(ctx context.Context, userID string, req *request.ContractWriteReq) (string, error) {
+	contract, err := utils.NormalizeETHAddress(req.Contract)
+	if err != nil {
+		return "", err
+	}
+	method, data, err := packContractCall(req.ABI, req.Method, req.Args)
+	if err != nil {
+		return "", err
+	}
+	value, err := contractValue(req.Value, method.IsPayable())
+	if err != nil {
+		return "", err
+	}
+
+	wallet, priv, err := s.contractSigner(ctx, userID, req.From, req.Passphrase)
+	if err != nil {
+		return "", err
+	}
+	return s.sendETH(ctx, wallet, priv, chain.ETHSendParams{
+		To:       contract,
+		Value:    value,
+		Data:     data,
+		GasLimit: req.GasLimit,
+	})
+}
+
+// DeployContract 部署合约，返回交易 hash 和按 from + nonce 算出的合约地址
+func (s *WalletService) DeployContract(ctx context.Context, userID string, req *request.ContractDeployReq) (string, string, error) {
+	code, err := utils.DecodeHexData(req.Bytecode)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid bytecode: %w", err)
+	}
+	if len(code) == 0 {
+		return "", "", errors.New("bytecode is empty")
+	}
+
+	payable := false
+	if len(req.ABI) > 0 {
+		parsed, err := parseABIParam(req.ABI)
+		if err != nil {
+			return "", "", err
+		}
+		args, err := chain.PackContractArgs(parsed.Constructor.Inputs, req.Args)
+		if err != nil {
+			return "", "", fmt.Errorf("constructor: %w", err)
+		}
+		code = append(code, args...)
+		payable = parsed.Constructor.IsPayable()
+	} else if len(req.Args) > 0 {
+		return "", "", errors.New("abi is required to encode constructor arguments")
+	}
+	value, err := contractValue(req.Value, payable)
+	if err != nil {
+		return "", "", err
+	}
+
+	wallet, priv, err := s.contractSigner(ctx, userID, req.From, req.Passphrase)
+	if err != nil {
+		return "", "", err
+	}
+	hash, err := s.sendETH(ctx, wallet, priv, chain.ETHSendParams{
+		Value:    value,
+		Data:     code,
+		GasLimit: req.GasLimit,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	record, err := s.TransactionRepo.GetByHash(ctx, "eth", hash)
+	if err != nil || record == nil {
+		return hash, "", err
+	}
+	return hash, record.ContractAddress, nil
+}
+
+// contractSigner 托管地址所在的钱包和解密出的私钥
+func (s *WalletService) contractSigner(ctx context.Context, userID, from, passphrase string) (*entity.Wallet, *ecdsa.PrivateKey, error) {
+	address, err := utils.NormalizeETHAddress(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	wallet, addr, err := s.userETHAddress(ctx, userID, address)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv, err := s.ethPrivateKey(wallet, addr, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return wallet, priv, nil
+}
+
+// packContractCall 方法 selector + 编码后的参数
+func packContractCall(abiDef json.RawMessage, name string, rawArgs []json.RawMessage) (abi.Method, []byte, error) {
+	parsed, err := parseABIParam(abiDef)
+	if err != nil {
+		return abi.Method{}, nil, err
+	}
+	method, err := chain.FindContractMethod(parsed, name)
+	if err != nil {
+		return abi.Method{}, nil, err
+	}
+	args, err := chain.PackContractArgs(method.Inputs, rawArgs)
+	if err != nil {
+		return abi.Method{}, nil, fmt.Errorf("%s: %w", method.Sig, err)
+	}
+	return method, append(common.CopyBytes(method.ID), args...), nil
+}
+
+// parseABIParam abi 可以直接是 JSON，也可以是 JSON 字符串 (比如从文件里读出来原样放进请求)
+func parseABIParam(raw json.RawMessage) (abi.ABI, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		raw = json.RawMessage(s)
+	}
+	return chain.ParseContractABI(raw)
+}
+
+// contractValue 附带的 ETH，非 payable 方法不能带
+func contractValue(value string, payable bool) (*big.Int, error) {
+	if value == "" {
+		return big.NewInt(0), nil
+	}
+	wei, err := utils.ETHToWei(value)
+	if err != nil {
+		return nil, err
+	}
+	if wei.Sign() < 0 {
+		return nil, errors.New("value must not be negative")
+	}
+	if wei.Sign() > 0 && !payable {
+		return nil, errors.New("method is not payable")
+	}
+	return wei, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// ETHMessageSignature 签名结果和签名内容的解码，方便调用方展示给用户确认
+type ETHMessageSignature struct {
+	Address   string                    `json:"address"`
+	Signature string                    `json:"signature"`
+	Message   *chain.ETHPersonalMessage `json:"message,omitempty"`
+	TypedData *chain.ETHTypedData       `json:"typed_data,omitempty"`
+}
+
+// ETHMessageVerification 恢复出的签名地址；请求带 address 时 Valid 表示是否一致
+type ETHMessageVerification struct {
+	Signer    string                    `json:"signer"`
+	Valid     bool                      `json:"valid"`
+	Message   *chain.ETHPersonalMessage `json:"message,omitempty"`
+	TypedData *chain.ETHTypedData       `json:"typed_data,omitempty"`
+}
+
+// SignETHMessage personal_sign，私钥和 ETH 转账一样按钱包类型解密 / 派生
+func (s *WalletService) SignETHMessage(ctx context.Context, userID string, req *request.SignETHMessageReq) (*ETHMessageSignature, error) {
+	_, priv, err := s.contractSigner(ctx, userID, req.Address, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	msg, hash := chain.ParsePersonalMessage(req.Message)
+	sig, err := chain.SignETHHash(priv, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &ETHMessageSignature{Address: crypto.PubkeyToAddress(priv.PublicKey).Hex(), Signature: sig, Message: msg}, nil
+}
+
+// SignETHTypedData eth_signTypedData_v4；domain 带 chainId 时必须是当前网络，防止签到别的链上用
+func (s *WalletService) SignETHTypedData(ctx context.Context, userID string, req *request.SignTypedDataReq) (*ETHMessageSignature, error) {
+	typed, hash, err := chain.ParseTypedData(typedDataParam(req.TypedData))
+	if err != nil {
+		return nil, err
+	}
+	if typed.ChainID != nil {
+		chainID, err := s.EthChain.NetworkChainID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if typed.ChainID.Cmp(chainID) != 0 {
+			return nil, fmt.Errorf("typed data is for chain %s, but this service is on chain %s", typed.ChainID, chainID)
+		}
+	}
+
+	_, priv, err := s.contractSigner(ctx, userID, req.Address, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := chain.SignETHHash(priv, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &ETHMessageSignature{Address: crypto.PubkeyToAddress(priv.PublicKey).Hex(), Signature: sig, TypedData: typed}, nil
+}
+
+// VerifyETHMessage 从 personal_sign 或 EIP-712 签名恢复签名地址，不需要访问节点
+func (s *WalletService) VerifyETHMessage(req *request.VerifyETHMessageReq) (*ETHMessageVerification, error) {
+	out := &ETHMessageVerification{}
+	var hash []byte
+	switch {
+	case req.Message != nil && len(req.TypedData) > 0:
+		return nil, errors.New("message and typed_data are mutually exclusive")
+	case req.Message != nil:
+		out.Message, hash = chain.ParsePersonalMessage(*req.Message)
+	case len(req.TypedData) > 0:
+		var err error
+		if out.TypedData, hash, err = chain.ParseTypedData(typedDataParam(req.TypedData)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("message or typed_data is required")
+	}
+
+	signer, err := chain.RecoverETHSigner(hash, req.Signature)
+	if err != nil {
+		return nil, err
+	}
+	out.Signer = signer
+	if req.Address != "" {
+		expected, err := utils.NormalizeETHAddress(req.Address)
+		if err != nil {
+			return nil, err
+		}
+		out.Valid = expected == signer
+	}
+	return out, nil
+}
+
+// typedDataParam typed_data 可以直接是 JSON 对象，也可以是 dapp 传给 eth_signTypedData_v4 的 JSON 字符串
+func typedDataParam(raw json.RawMessage) []byte {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []byte(s)
+	}
+	return raw
+}
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+const (
+	nftLogRange       = 2000    // 每次 eth_getLogs 的区块数，大多数节点限制在几千个区块以内
+	nftMetadataMaxLen = 1 << 20 // 元数据 JSON 最大 1MB
+)
+
+var (
+	// nftHTTPClient 读取合约给出的 http(s) 元数据 URI。URI 由任意合约控制，
+	// 只允许连公网地址，在建立连接时检查解析后的 IP，重定向和 DNS rebinding 也会被拦住
+	nftHTTPClient = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy: nil, // 走代理时检查到的是代理的地址
+			DialContext: (&net.Dialer{
+				Timeout: 5 * time.Second,
+				Control: publicAddressOnly,
+			}).DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+	}
+	// nftGatewayClient 访问配置的 eth.ipfs_gateway，网关由运维配置，可以是内网节点
+	nftGatewayClient = &http.Client{Timeout: 10 * time.Second}
+
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10") // RFC 6598 运营商 NAT
+)
+
+// publicAddressOnly 拒绝连接回环、内网、链路本地、未指定和组播地址
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("metadata host %s is not a public address", ip)
+	}
+	return nil
+}
+
+// NFTMetadata tokenURI / uri 以及解析出的元数据 JSON
+type NFTMetadata struct {
+	Collection string                 `json:"collection"`
+	Standard   string                 `json:"standard"`
+	TokenID    string                 `json:"token_id"`
+	URI        string                 `json:"uri"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Error      string                 `json:"error,omitempty"` // 读取元数据失败的原因，uri 仍然返回
+}
+
+// StartNFTIndexer 后台按 eth.nft_collections 索引转移日志，维护托管地址的 NFT 持仓。
+// 只索引到 tip - eth.confirmations，不用处理 reorg
+func (s *WalletService) StartNFTIndexer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("nft indexer stopped")
+				return
+
+			case <-ticker.C:
+				if err := s.syncNFTs(ctx); err != nil {
+					log.Printf("nft indexer: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// ListNFTs 用户所有 ETH 地址上的 NFT
+func (s *WalletService) ListNFTs(ctx context.Context, userID string) ([]*entity.NFTHolding, error) {
+	return s.NFTRepo.ListByUser(ctx, userID)
+}
+
+// GetNFTMetadata 读取 tokenURI (ERC-1155 为 uri) 并解析元数据，支持 http(s)、ipfs:// 和 data: URI
+func (s *WalletService) GetNFTMetadata(ctx context.Context, collection, tokenID string) (*NFTMetadata, error) {
+	contract, err := utils.NormalizeETHAddress(collection)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseTokenID(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	standard, err := s.nftStandard(ctx, contract)
+	if err != nil {
+		return nil, err
+	}
+	uri, err := s.EthChain.NFTTokenURI(ctx, contract, standard, id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &NFTMetadata{Collection: contract, Standard: standard, TokenID: id.String(), URI: uri}
+	if out.Metadata, err = s.fetchNFTMetadata(ctx, uri); err != nil {
+		out.Error = err.Error()
+	}
+	return out, nil
+}
+
+// SendNFT 走和 ETH 转账相同的签名、nonce 和交易记录流程，calldata 为 safeTransferFrom
+func (s *WalletService) SendNFT(ctx context.Context, userID string, req *request.SendNFTReq) (string, error) {
+	from, err := utils.NormalizeETHAddress(req.From)
+	if err != nil {
+		return "", err
+	}
+	to, err := utils.NormalizeETHAddress(req.To)
+	if err != nil {
+		return "", err
+	}
+	contract, err := utils.NormalizeETHAddress(req.Collection)
+	if err != nil {
+		return "", err
+	}
+	id, err := parseTokenID(req.TokenID)
+	if err != nil {
+		return "", err
+	}
+	standard, err := s.nftStandard(ctx, contract)
+	if err != nil {
+		return "", err
+	}
+
+	var data []byte
+	switch standard {
+	case chain.ERC721:
+		if req.Amount != "" && req.Amount != "1" {
+			return "", errors.New("erc721 tokens can only be sent one at a time")
+		}
+		data, err = chain.ERC721TransferData(from, to, id)
+	default:
+		amount := big.NewInt(1)
+		if req.Amount != "" {
+			if _, ok := amount.SetString(req.Amount, 10); !ok || amount.Sign() <= 0 {
+				return "", errors.New("amount must be a positive integer")
+			}
+		}
+		data, err = chain.ERC1155TransferData(from, to, id, amount)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	wallet, addr, err := s.userETHAddress(ctx, userID, from)
+	if err != nil {
+		return "", err
+	}
+	priv, err := s.ethPrivateKey(wallet, addr, req.Passphrase)
+	if err != nil {
+		return "", err
+	}
+	return s.sendETH(ctx, wallet, priv, chain.ETHSendParams{
+		To:       contract,
+		Value:    big.NewInt(0),
+		Data:     data,
+		GasLimit: req.GasLimit,
+	})
+}
+
+func (s *WalletService) syncNFTs(ctx context.Context) error {
+	if len(s.EthChain.NFTCollections) == 0 {
+		return nil
+	}
+	tip, err := s.EthChain.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if tip < s.EthChain.Confirmations {
+		return nil
+	}
+	safe := tip - s.EthChain.Confirmations
+
+	addrs, err := s.AddressRepo.ListByChain(ctx, "eth")
+	if err != nil {
+		return err
+	}
+	owners := make(map[string]string, len(addrs)) // address -> user id
+	for _, a := range addrs {
+		owners[a.Address] = a.UserID
+	}
+
+	for _, c := range s.EthChain.NFTCollections {
+		if err := s.syncNFTCollection(ctx, c, safe, owners); err != nil {
+			log.Printf("nft indexer %s: %v", c.Address, err)
+		}
+	}
+	return nil
+}
+
+// syncNFTCollection 从上次的进度开始按 nftLogRange 分段拉日志，每段处理完再保存进度
+func (s *WalletService) syncNFTCollection(ctx context.Context, c chain.NFTCollection, safe uint64, owners map[string]string) error {
+	st, err := s.NFTRepo.GetSyncState(ctx, c.Address)
+	if err != nil {
+		return err
+	}
+	start := c.FromBlock
+	if st != nil {
+		start = st.LastBlock + 1
+	}
+
+	for from := start; from <= safe; from += nftLogRange {
+		to := from + nftLogRange - 1
+		if to > safe {
+			to = safe
+		}
+		transfers, err := s.EthChain.NFTTransfers(ctx, c, from, to)
+		if err != nil {
+			return err
+		}
+		for _, t := range transfers {
+			if err := s.applyNFTTransfer(ctx, c, t, owners); err != nil {
+				return err
+			}
+		}
+		if err := s.NFTRepo.SaveSyncState(ctx, c.Address, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyNFTTransfer 只记录托管地址的持仓；自己转给自己持仓不变
+func (s *WalletService) applyNFTTransfer(ctx context.Context, c chain.NFTCollection, t chain.NFTTransfer, owners map[string]string) error {
+	if t.From == t.To {
+		return nil
+	}
+	if userID, ok := owners[t.From]; ok {
+		if err := s.adjustNFTHolding(ctx, c, t, t.From, userID, new(big.Int).Neg(t.Amount)); err != nil {
+			return err
+		}
+	}
+	if userID, ok := owners[t.To]; ok {
+		if err := s.adjustNFTHolding(ctx, c, t, t.To, userID, t.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adjustNFTHolding 持仓加减 delta，减到 0 删除；日志位置不晚于上次生效位置的跳过 (重放)
+func (s *WalletService) adjustNFTHolding(ctx context.Context, c chain.NFTCollection, t chain.NFTTransfer, owner, userID string, delta *big.Int) error {
+	tokenID := t.TokenID.String()
+	h, err := s.NFTRepo.GetHolding(ctx, c.Address, tokenID, owner)
+	if err != nil {
+		return err
+	}
+	amount := new(big.Int)
+	if h != nil {
+		if !nftLogAfter(t, h) {
+			return nil
+		}
+		amount.SetString(h.Amount, 10)
+	}
+	amount.Add(amount, delta)
+	if amount.Sign() <= 0 {
+		if h == nil {
+			return nil
+		}
+		return s.NFTRepo.DeleteHolding(ctx, c.Address, tokenID, owner)
+	}
+
+	return s.NFTRepo.SaveHolding(ctx, &entity.NFTHolding{
+		UserID:         userID,
+		Owner:          owner,
+		Collection:     c.Address,
+		Standard:       c.Standard,
+		TokenID:        tokenID,
+		Amount:         amount.String(),
+		LastBlock:      t.BlockNumber,
+		LastLogIndex:   t.LogIndex,
+		LastBatchIndex: t.BatchIndex,
+	})
+}
+
+func nftLogAfter(t chain.NFTTransfer, h *entity.NFTHolding) bool {
+	if t.BlockNumber != h.LastBlock {
+		return t.BlockNumber > h.LastBlock
+	}
+	if t.LogIndex != h.LastLogIndex {
+		return t.LogIndex > h.LastLogIndex
+	}
+	return t.BatchIndex > h.LastBatchIndex
+}
+
+// nftStandard 配置里的合约直接用配置的标准，其他合约用 ERC-165 判断
+func (s *WalletService) nftStandard(ctx context.Context, contract string) (string, error) {
+	for _, c := range s.EthChain.NFTCollections {
+		if c.Address == contract {
+			return c.Standard, nil
+		}
+	}
+	return s.EthChain.NFTStandard(ctx, contract)
+}
+
+// fetchNFTMetadata 解析元数据 URI，ipfs:// 走 eth.ipfs_gateway
+func (s *WalletService) fetchNFTMetadata(ctx context.Context, uri string) (map[string]interface{}, error) {
+	var body []byte
+	switch {
+	case strings.HasPrefix(uri, "data:"):
+		meta, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+		if !ok {
+			return nil, errors.New("invalid data uri")
+		}
+		if strings.HasSuffix(meta, ";base64") {
+			decoded, err := base64.StdEncoding.DecodeString(payload)
+			if err != nil {
+				return nil, err
+			}
+			body = decoded
+		} else {
+			unescaped, err := url.PathUnescape(payload)
+			if err != nil {
+				return nil, err
+			}
+			body = []byte(unescaped)
+		}
+
+	case strings.HasPrefix(uri, "ipfs://"), strings.HasPrefix(uri, "http://"), strings.HasPrefix(uri, "https://"):
+		target, client := uri, nftHTTPClient
+		if strings.HasPrefix(uri, "ipfs://") {
+			path := strings.TrimPrefix(strings.TrimPrefix(uri, "ipfs://"), "ipfs/")
+			target, client = strings.TrimRight(s.IPFSGateway, "/")+"/"+path, nftGatewayClient
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch %s: %s", target, resp.Status)
+		}
+		if body, err = io.ReadAll(io.LimitReader(resp.Body, nftMetadataMaxLen)); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported metadata uri %q", uri)
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("metadata is not a json object: %w", err)
+	}
+	return metadata, nil
+}
+
+// parseTokenID 十进制或 0x 开头的十六进制
+func parseTokenID(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	}
+	id, ok := new(big.Int).SetString(s, base)
+	if !ok || id.Sign() < 0 {
+		return nil, fmt.Errorf("invalid token id %q", s)
+	}
+	return id, nil
+}
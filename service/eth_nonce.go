@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	nonceLockTTL  = time.Minute      // 一次分配 + 签名 + 广播最长持锁时间，进程挂掉后锁自动过期
+	nonceLockWait = 30 * time.Second // 等待同地址其他发送释放锁的最长时间
+	// 持锁期间的操作必须在锁过期前结束，留出余量给时钟误差和最后一次保存
+	nonceLockMargin = 10 * time.Second
+	// 单次检查 nonce 空洞的最大范围
+	maxNonceGapScan = 256
+)
+
+// ETHNonceStatus 本地 nonce 分配和节点状态的对比
+type ETHNonceStatus struct {
+	Address     string           `json:"address"`
+	Next        uint64           `json:"next"`         // 本地下一个分配的 nonce
+	Latest      uint64           `json:"latest"`       // 链上已确认的 nonce
+	NodePending uint64           `json:"node_pending"` // 节点算上 mempool 后的 nonce
+	Pending     []entity.NonceTx `json:"pending"`
+	Gaps        []uint64         `json:"gaps"` // 已分配但节点上找不到交易的 nonce，之后的交易都会卡住
+}
+
+// sendETH 从 nonce 管理器分配 nonce 后签名广播，同一地址的并发发送在数据库锁上排队；
+// 广播成功的交易写入 transactions 交给 tracker 跟踪回执；SignOnly 时只签名，记录为 signed。
+// SignOnly 且指定了 Nonce 时不经过 nonce 管理器，广播时由 BroadcastETHTx 同步
+func (s *WalletService) sendETH(ctx context.Context, wallet *entity.Wallet, priv *ecdsa.PrivateKey, params chain.ETHSendParams) (string, error) {
+	address := crypto.PubkeyToAddress(priv.PublicKey).Hex()
+
+	if params.SignOnly && params.Nonce != nil {
+		tx, err := s.EthChain.SendETH(ctx, priv, params)
+		if err != nil {
+			return "", err
+		}
+		record := signedETHTxRecord(wallet, address, tx)
+		record.Escalations = s.escalationLadder(wallet, priv, tx)
+		return tx.Hash().Hex(), s.TransactionRepo.Create(ctx, record)
+	}
+
+	var hash string
+	err := s.withNonceLock(ctx, address, func(acct *entity.NonceAccount, owner string) error {
+		for attempt := 0; ; attempt++ {
+			nonce := acct.Next
+			params.Nonce = &nonce
+
+			tx, err := s.EthChain.SendETH(ctx, priv, params)
+			if err == nil {
+				hash = tx.Hash().Hex()
+				acct.Next = nonce + 1
+				acct.Pending = appendNonceTx(acct.Pending, nonce, hash)
+				if err := s.NonceRepo.Save(ctx, acct, owner); err != nil {
+					return err
+				}
+				record := newETHTxRecord(wallet, address, tx)
+				if params.SignOnly {
+					// nonce 照样占用，交易没发出去之前在 nonce status 里显示为空洞
+					record = signedETHTxRecord(wallet, address, tx)
+				}
+				record.Escalations = s.escalationLadder(wallet, priv, tx)
+				return s.TransactionRepo.Create(ctx, record)
+			}
+
+			// 广播失败时交易可能已经到了节点，或者同一个 key 在别处发过交易，和节点对账一次
+			if rerr := s.reconcileNonce(ctx, acct, owner); rerr != nil {
+				log.Printf("eth nonce %s: reconcile after failed send: %v", address, rerr)
+				return err
+			}
+			// nonce too low 说明本地落后了，对账后重试一次
+			if attempt > 0 || !isNonceTooLow(err) || acct.Next == nonce {
+				return err
+			}
+		}
+	})
+	return hash, err
+}
+
+// signedETHTxRecord 只签名未广播的交易记录
+func signedETHTxRecord(wallet *entity.Wallet, from string, tx *types.Transaction) *entity.Transaction {
+	record := newETHTxRecord(wallet, from, tx)
+	record.Status = entity.TxSigned
+	record.BroadcastAt = time.Time{}
+	return record
+}
+
+// withNonceLock 拿到地址的 nonce 锁后执行 fn，第一次使用的地址先和节点对账。
+// fn 的 ctx 在锁过期之前超时，避免锁过期后别的发送拿到同一个 nonce
+func (s *WalletService) withNonceLock(ctx context.Context, address string, fn func(acct *entity.NonceAccount, owner string) error) error {
+	owner := primitive.NewObjectID().Hex()
+	acct, lockedAt, err := s.lockNonce(ctx, address, owner)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithDeadline(ctx, lockedAt.Add(nonceLockTTL-nonceLockMargin))
+	defer cancel()
+	defer func() {
+		if err := s.NonceRepo.Unlock(context.WithoutCancel(ctx), "eth", address, owner); err != nil {
+			log.Printf("eth nonce %s: unlock: %v", address, err)
+		}
+	}()
+
+	if acct.SyncedAt == nil {
+		if err := s.reconcileNonce(ctx, acct, owner); err != nil {
+			return err
+		}
+	}
+	return fn(acct, owner)
+}
+
+// lockNonce 轮询抢锁，直到拿到或超时；返回发起加锁的时间，锁的过期时间不早于它加上 TTL
+func (s *WalletService) lockNonce(ctx context.Context, address, owner string) (*entity.NonceAccount, time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, nonceLockWait)
+	defer cancel()
+
+	for {
+		lockedAt := time.Now()
+		acct, err := s.NonceRepo.TryLock(ctx, "eth", address, owner, nonceLockTTL)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if acct != nil {
+			return acct, lockedAt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, time.Time{}, fmt.Errorf("nonce of %s is locked by another send: %w", address, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// reconcileNonce 和节点对账：去掉已确认的 pending 记录；节点的 nonce 更高 (别处用同一个 key 发过交易) 时把 next 调上去。
+// next 比节点高说明有交易丢了，这里不自动回退，由 ETHNonceStatus 报告空洞，再显式 fill / cancel
+func (s *WalletService) reconcileNonce(ctx context.Context, acct *entity.NonceAccount, owner string) error {
+	latest, pending, err := s.EthChain.Nonces(ctx, acct.Address)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]entity.NonceTx, 0, len(acct.Pending))
+	for _, p := range acct.Pending {
+		if p.Nonce >= latest {
+			kept = append(kept, p)
+		}
+	}
+	acct.Pending = kept
+	if pending > acct.Next {
+		acct.Next = pending
+	}
+	now := time.Now()
+	acct.SyncedAt = &now
+	return s.NonceRepo.Save(ctx, acct, owner)
+}
+
+// nonceStatus 找出 [节点 pending nonce, next) 里节点上没有交易的 nonce
+func (s *WalletService) nonceStatus(ctx context.Context, acct *entity.NonceAccount) (*ETHNonceStatus, error) {
+	latest, pending, err := s.EthChain.Nonces(ctx, acct.Address)
+	if err != nil {
+		return nil, err
+	}
+	status := &ETHNonceStatus{
+		Address:     acct.Address,
+		Next:        acct.Next,
+		Latest:      latest,
+		NodePending: pending,
+		Pending:     acct.Pending,
+		Gaps:        []uint64{},
+	}
+
+	hashes := make(map[uint64]string, len(acct.Pending))
+	for _, p := range acct.Pending {
+		hashes[p.Nonce] = p.TxHash
+	}
+	for n := pending; n < acct.Next && n < pending+maxNonceGapScan; n++ {
+		// 节点 pending nonce 之后还可能有排队 (queued) 的交易
+		if hash, ok := hashes[n]; ok {
+			known, err := s.EthChain.TransactionKnown(ctx, hash)
+			if err != nil {
+				return nil, err
+			}
+			if known {
+				continue
+			}
+		}
+		status.Gaps = append(status.Gaps, n)
+	}
+	return status, nil
+}
+
+// ETHNonceStatus 地址的 nonce 分配状态和空洞，只读不加锁
+func (s *WalletService) ETHNonceStatus(ctx context.Context, userID, address string) (*ETHNonceStatus, error) {
+	if _, _, err := s.userETHAddress(ctx, userID, address); err != nil {
+		return nil, err
+	}
+	acct, err := s.NonceRepo.Get(ctx, "eth", address)
+	if err != nil {
+		return nil, err
+	}
+	if acct == nil {
+		// 还没从本服务发过交易
+		_, pending, err := s.EthChain.Nonces(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		acct = &entity.NonceAccount{Chain: "eth", Address: address, Next: pending}
+	}
+	return s.nonceStatus(ctx, acct)
+}
+
+// FillETHNonceGaps 在每个空洞的 nonce 上发一笔给自己的 0 ETH 交易，让后面卡住的交易可以上链
+func (s *WalletService) FillETHNonceGaps(ctx context.Context, userID, address, passphrase string) (*ETHNonceStatus, []string, error) {
+	wallet, addr, err := s.userETHAddress(ctx, userID, address)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv, err := s.ethPrivateKey(wallet, addr, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var status *ETHNonceStatus
+	var hashes []string
+	err = s.withNonceLock(ctx, address, func(acct *entity.NonceAccount, owner string) error {
+		if err := s.reconcileNonce(ctx, acct, owner); err != nil {
+			return err
+		}
+		gaps, err := s.nonceStatus(ctx, acct)
+		if err != nil {
+			return err
+		}
+		for _, n := range gaps.Gaps {
+			nonce := n
+			tx, err := s.EthChain.SendETH(ctx, priv, chain.ETHSendParams{
+				To:       address,
+				Value:    big.NewInt(0),
+				GasLimit: 21000,
+				Nonce:    &nonce,
+			})
+			if err != nil {
+				// 已经发出去的先记下来
+				if serr := s.NonceRepo.Save(ctx, acct, owner); serr != nil {
+					log.Printf("eth nonce %s: save after failed fill: %v", address, serr)
+				}
+				return fmt.Errorf("fill nonce %d: %w", nonce, err)
+			}
+			hash := tx.Hash().Hex()
+			acct.Pending = appendNonceTx(acct.Pending, nonce, hash)
+			hashes = append(hashes, hash)
+			if err := s.TransactionRepo.Create(ctx, newETHTxRecord(wallet, address, tx)); err != nil {
+				log.Printf("eth tx %s: save record: %v", hash, err)
+			}
+		}
+		if err := s.NonceRepo.Save(ctx, acct, owner); err != nil {
+			return err
+		}
+		status, err = s.nonceStatus(ctx, acct)
+		return err
+	})
+	return status, hashes, err
+}
+
+// CancelETHNonceGaps 放弃第一个空洞及之后分配的 nonce，next 回退到空洞处重新分配；
+// 节点里排队的更高 nonce 交易会被之后的新交易替换 (新交易的费用要高于它们)
+func (s *WalletService) CancelETHNonceGaps(ctx context.Context, userID, address string) (*ETHNonceStatus, error) {
+	if _, _, err := s.userETHAddress(ctx, userID, address); err != nil {
+		return nil, err
+	}
+
+	var status *ETHNonceStatus
+	err := s.withNonceLock(ctx, address, func(acct *entity.NonceAccount, owner string) error {
+		if err := s.reconcileNonce(ctx, acct, owner); err != nil {
+			return err
+		}
+		gaps, err := s.nonceStatus(ctx, acct)
+		if err != nil {
+			return err
+		}
+		if len(gaps.Gaps) > 0 {
+			first := gaps.Gaps[0]
+			kept := make([]entity.NonceTx, 0, len(acct.Pending))
+			for _, p := range acct.Pending {
+				if p.Nonce < first {
+					kept = append(kept, p)
+				}
+			}
+			acct.Pending = kept
+			acct.Next = first
+			if err := s.NonceRepo.Save(ctx, acct, owner); err != nil {
+				return err
+			}
+		}
+		status, err = s.nonceStatus(ctx, acct)
+		return err
+	})
+	return status, err
+}
+
+// ReconcileETHNonces 启动时把所有地址的 nonce 和节点对一遍账，发现空洞只打日志
+func (s *WalletService) ReconcileETHNonces(ctx context.Context) {
+	accts, err := s.NonceRepo.ListByChain(ctx, "eth")
+	if err != nil {
+		log.Printf("eth nonce reconcile: list: %v", err)
+		return
+	}
+	for _, a := range accts {
+		err := s.withNonceLock(ctx, a.Address, func(acct *entity.NonceAccount, owner string) error {
+			if err := s.reconcileNonce(ctx, acct, owner); err != nil {
+				return err
+			}
+			status, err := s.nonceStatus(ctx, acct)
+			if err != nil {
+				return err
+			}
+			if len(status.Gaps) > 0 {
+				log.Printf("eth nonce %s: gaps %v (node pending %d, next %d)", acct.Address, status.Gaps, status.NodePending, acct.Next)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("eth nonce reconcile %s: %v", a.Address, err)
+		}
+	}
+}
+
+// userETHAddress 用户的 eth 地址及所属钱包
+func (s *WalletService) userETHAddress(ctx context.Context, userID, address string) (*entity.Wallet, *entity.Address, error) {
+	addr, err := s.AddressRepo.GetByAddrID(ctx, "eth", address)
+	if err != nil {
+		return nil, nil, err
+	}
+	if addr == nil || addr.UserID != userID {
+		return nil, nil, errors.New("address not found or not belongs to user")
+	}
+	wallet, err := s.WalletRepo.GetByID(ctx, addr.WalletID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if wallet == nil {
+		return nil, nil, errors.New("wallet not found")
+	}
+	return wallet, addr, nil
+}
+
+// appendNonceTx 记录 nonce 上的交易，同一个 nonce 只保留最新的一笔
+func appendNonceTx(pending []entity.NonceTx, nonce uint64, hash string) []entity.NonceTx {
+	out := make([]entity.NonceTx, 0, len(pending)+1)
+	for _, p := range pending {
+		if p.Nonce != nonce {
+			out = append(out, p)
+		}
+	}
+	return append(out, entity.NonceTx{Nonce: nonce, TxHash: hash, CreatedAt: time.Now()})
+}
+
+func isNonceTooLow(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+)
+
+// SpeedUpETHTx 用同一个 nonce 加价重发一笔 pending 交易
+func (s *WalletService) SpeedUpETHTx(ctx context.Context, userID, hash string, req *request.ReplaceETHTxReq) (*entity.Transaction, error) {
+	return s.replaceETHTx(ctx, userID, hash, req, false)
+}
+
+// CancelETHTx 用同一个 nonce 发一笔给自己的 0 ETH 交易顶掉 pending 交易
+func (s *WalletService) CancelETHTx(ctx context.Context, userID, hash string, req *request.ReplaceETHTxReq) (*entity.Transaction, error) {
+	return s.replaceETHTx(ctx, userID, hash, req, true)
+}
+
+// replaceETHTx 只能替换还没上链、也没被替换过的交易；要再加价就对最新的替换交易操作
+func (s *WalletService) replaceETHTx(ctx context.Context, userID, hash string, req *request.ReplaceETHTxReq, cancel bool) (*entity.Transaction, error) {
+	record, err := s.GetTransaction(ctx, userID, hash)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != entity.TxPending {
+		return nil, errors.New("only pending transactions can be replaced, status: " + record.Status)
+	}
+	if record.ReplacedByHash != "" {
+		return nil, errors.New("transaction already replaced by " + record.ReplacedByHash)
+	}
+	receipt, err := s.EthChain.Receipt(ctx, record.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if receipt != nil {
+		return nil, errors.New("transaction already mined")
+	}
+
+	raw, err := hex.DecodeString(record.RawTx)
+	if err != nil {
+		return nil, err
+	}
+	var orig types.Transaction
+	if err := orig.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+
+	wallet, addr, err := s.userETHAddress(ctx, userID, record.From)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := s.ethPrivateKey(wallet, addr, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// 持有 nonce 锁，避免同一笔交易被并发替换；next 不变，只更新该 nonce 上的交易
+	var replacement *entity.Transaction
+	err = s.withNonceLock(ctx, record.From, func(acct *entity.NonceAccount, owner string) error {
+		tx, err := s.EthChain.ReplaceETHTx(ctx, priv, &orig, cancel, req.BumpPercent)
+		if err != nil {
+			return err
+		}
+		replacement = newETHTxRecord(wallet, record.From, tx)
+		replacement.ReplacesHash = record.Hash
+		replacement.Cancel = cancel
+		replacement.Escalations = s.escalationLadder(wallet, priv, tx)
+
+		acct.Pending = appendNonceTx(acct.Pending, tx.Nonce(), replacement.Hash)
+		if err := s.NonceRepo.Save(ctx, acct, owner); err != nil {
+			return err
+		}
+		if err := s.TransactionRepo.SetReplacedBy(ctx, "eth", record.Hash, replacement.Hash); err != nil {
+			return err
+		}
+		return s.TransactionRepo.Create(ctx, replacement)
+	})
+	return replacement, err
+}
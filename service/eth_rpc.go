@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+)
+
+// StartETHHealthCheck 后台定时检查 ETH RPC 节点的可用性和区块高度
+func (s *WalletService) StartETHHealthCheck(ctx context.Context, interval time.Duration) {
+	s.EthChain.Clients.StartHealthCheck(ctx, interval)
+}
+
+// ETHEndpoints ETH RPC 节点当前状态
+func (s *WalletService) ETHEndpoints() []chain.ETHEndpointStatus {
+	return s.EthChain.Clients.Status()
+}
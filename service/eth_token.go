@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/config"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// isNativeETH asset 不填或为 ETH 时是原生币
+func isNativeETH(asset string) bool {
+	return asset == "" || strings.EqualFold(asset, "eth")
+}
+
+// RegisterETHTokens 启动时把配置里的 token 登记到当前网络，eth.test_token 从链上读取元数据；失败只打日志
+func (s *WalletService) RegisterETHTokens(ctx context.Context, tokens []config.EthTokenConfig) {
+	chainID, err := s.EthChain.NetworkChainID(ctx)
+	if err != nil {
+		log.Printf("eth tokens: %v", err)
+		return
+	}
+	for _, t := range tokens {
+		address, err := utils.NormalizeETHAddress(t.Address)
+		if err != nil || t.Symbol == "" {
+			log.Printf("eth tokens: skip invalid token %q (%s)", t.Symbol, t.Address)
+			continue
+		}
+		err = s.TokenRepo.Upsert(ctx, &entity.Token{
+			ChainID:   chainID.Int64(),
+			Address:   address,
+			Symbol:    t.Symbol,
+			Name:      t.Name,
+			Decimals:  t.Decimals,
+			Source:    entity.TokenSourceConfig,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Printf("eth tokens: register %s: %v", t.Symbol, err)
+		}
+	}
+
+	if s.EthChain.TestToken != "" {
+		if _, err := s.registerETHToken(ctx, chainID.Int64(), s.EthChain.TestToken, entity.TokenSourceConfig); err != nil {
+			log.Printf("eth tokens: test token %s: %v", s.EthChain.TestToken, err)
+		}
+	}
+}
+
+// ListETHTokens 当前网络登记的 token
+func (s *WalletService) ListETHTokens(ctx context.Context) ([]*entity.Token, error) {
+	chainID, err := s.EthChain.NetworkChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.TokenRepo.ListByChain(ctx, chainID.Int64())
+}
+
+// AddETHToken 按合约地址登记 token，已登记的直接返回
+func (s *WalletService) AddETHToken(ctx context.Context, req *request.AddTokenReq) (*entity.Token, error) {
+	address, err := utils.NormalizeETHAddress(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveETHToken(ctx, address)
+}
+
+// resolveETHToken asset 为合约地址时查登记表，没有就从链上读取元数据自动登记；
+// 为 symbol 时只查登记表，同名的多个合约里优先配置的，否则要求用合约地址
+func (s *WalletService) resolveETHToken(ctx context.Context, asset string) (*entity.Token, error) {
+	chainID, err := s.EthChain.NetworkChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(asset, "0x") || strings.HasPrefix(asset, "0X") {
+		address, err := utils.NormalizeETHAddress(asset)
+		if err != nil {
+			return nil, err
+		}
+		token, err := s.TokenRepo.GetByAddress(ctx, chainID.Int64(), address)
+		if err != nil || token != nil {
+			return token, err
+		}
+		return s.registerETHToken(ctx, chainID.Int64(), address, entity.TokenSourceDiscovered)
+	}
+
+	tokens, err := s.TokenRepo.ListBySymbol(ctx, chainID.Int64(), asset)
+	if err != nil {
+		return nil, err
+	}
+	var configured []*entity.Token
+	for _, t := range tokens {
+		if t.Source == entity.TokenSourceConfig {
+			configured = append(configured, t)
+		}
+	}
+	switch {
+	case len(tokens) == 0:
+		return nil, fmt.Errorf("unknown token %s, use the contract address", asset)
+	case len(tokens) == 1:
+		return tokens[0], nil
+	case len(configured) == 1:
+		return configured[0], nil
+	default:
+		return nil, fmt.Errorf("token symbol %s is ambiguous on chain %d, use the contract address", asset, chainID.Int64())
+	}
+}
+
+// registerETHToken 从链上读取 decimals / symbol / name 并登记
+func (s *WalletService) registerETHToken(ctx context.Context, chainID int64, address, source string) (*entity.Token, error) {
+	address, err := utils.NormalizeETHAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := s.EthChain.TokenMetadata(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	token := &entity.Token{
+		ChainID:   chainID,
+		Address:   address,
+		Symbol:    meta.Symbol,
+		Name:      meta.Name,
+		Decimals:  meta.Decimals,
+		Source:    source,
+		CreatedAt: time.Now(),
+	}
+	if err := s.TokenRepo.Upsert(ctx, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// erc20SendParams amount 按 token 的 decimals 换算后构造 transfer(to, amount)，交易发给合约、value 为 0
+func (s *WalletService) erc20SendParams(ctx context.Context, to string, req *request.SendTxReq) (chain.ETHSendParams, error) {
+	if req.Data != "" {
+		return chain.ETHSendParams{}, errors.New("data can not be combined with a token asset")
+	}
+	token, err := s.resolveETHToken(ctx, req.Asset)
+	if err != nil {
+		return chain.ETHSendParams{}, err
+	}
+	amount, err := utils.ParseUnits(req.Amount, token.Decimals)
+	if err != nil {
+		return chain.ETHSendParams{}, err
+	}
+	if amount.Sign() <= 0 {
+		return chain.ETHSendParams{}, errors.New("amount must be positive")
+	}
+	data, err := chain.ERC20TransferData(to, amount)
+	if err != nil {
+		return chain.ETHSendParams{}, err
+	}
+
+	return chain.ETHSendParams{
+		To:       token.Address,
+		Value:    big.NewInt(0),
+		Data:     data,
+		GasLimit: req.GasLimit,
+	}, nil
+}
+
+// erc20Balance balanceOf 按 decimals 格式化
+func (s *WalletService) erc20Balance(ctx context.Context, address, asset string) (string, error) {
+	token, err := s.resolveETHToken(ctx, asset)
+	if err != nil {
+		return "", err
+	}
+	balance, err := s.EthChain.TokenBalance(ctx, token.Address, address)
+	if err != nil {
+		return "", err
+	}
+	return utils.FormatUnits(balance, token.Decimals), nil
+}
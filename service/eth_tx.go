@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// GetTransaction 查询用户发出的 ETH 交易及回执
+func (s *WalletService) GetTransaction(ctx context.Context, userID, hash string) (*entity.Transaction, error) {
+	tx, err := s.TransactionRepo.GetByHash(ctx, "eth", common.HexToHash(hash).Hex())
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil || tx.UserID != userID {
+		return nil, errors.New("transaction not found")
+	}
+	return tx, nil
+}
+
+// BroadcastETHTx 广播一笔已签名交易：broadcast=false 签出来的，或者在别处签好的。
+// 校验 chain id、恢复签名地址后记录到 transactions 再广播；签名地址是用户的托管地址时在 nonce 锁内同步 nonce 管理器
+func (s *WalletService) BroadcastETHTx(ctx context.Context, userID, rawTx string) (*entity.Transaction, error) {
+	raw, err := utils.DecodeHexData(rawTx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw_tx: %w", err)
+	}
+	tx, from, err := s.EthChain.DecodeRawETH(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.TransactionRepo.GetByHash(ctx, "eth", tx.Hash().Hex())
+	if err != nil {
+		return nil, err
+	}
+	if record != nil && record.UserID != userID {
+		return nil, errors.New("transaction belongs to another user")
+	}
+
+	addr, err := s.AddressRepo.GetByAddrID(ctx, "eth", from)
+	if err != nil {
+		return nil, err
+	}
+	if addr == nil {
+		// 外部地址签的交易只记录和跟踪回执，不涉及 nonce 管理
+		return s.broadcastETHRecord(ctx, tx, from, &entity.Wallet{UserID: userID}, record)
+	}
+	if addr.UserID != userID {
+		return nil, fmt.Errorf("sender %s belongs to another user", from)
+	}
+	wallet, err := s.WalletRepo.GetByID(ctx, addr.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, errors.New("wallet not found")
+	}
+
+	err = s.withNonceLock(ctx, from, func(acct *entity.NonceAccount, owner string) error {
+		sent, err := s.broadcastETHRecord(ctx, tx, from, wallet, record)
+		if err != nil {
+			return err
+		}
+		record = sent
+		// 别处签的交易可能用了 nonce 管理器还没分配的 nonce
+		if tx.Nonce() >= acct.Next {
+			acct.Next = tx.Nonce() + 1
+		}
+		acct.Pending = appendNonceTx(acct.Pending, tx.Nonce(), record.Hash)
+		return s.NonceRepo.Save(ctx, acct, owner)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// broadcastETHRecord 广播成功后写记录：新交易直接记为 pending，broadcast=false 签出的记录转成 pending；
+// 已经广播过的交易重发一次也没有副作用
+func (s *WalletService) broadcastETHRecord(ctx context.Context, tx *types.Transaction, from string, wallet *entity.Wallet, record *entity.Transaction) (*entity.Transaction, error) {
+	if err := s.EthChain.SendSignedETH(ctx, tx); err != nil {
+		return nil, err
+	}
+	if record == nil {
+		record = newETHTxRecord(wallet, from, tx)
+		return record, s.TransactionRepo.Create(ctx, record)
+	}
+	if record.Status != entity.TxSigned {
+		return record, nil
+	}
+	now := time.Now()
+	if err := s.TransactionRepo.MarkBroadcast(ctx, "eth", record.Hash, now); err != nil {
+		return nil, err
+	}
+	record.Status = entity.TxPending
+	record.BroadcastAt = now
+	return record, nil
+}
+
+// StartETHTxTracker 后台轮询 pending 交易的回执，上链后继续更新确认数直到 eth.confirmations
+func (s *WalletService) StartETHTxTracker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("eth tx tracker stopped")
+				return
+
+			case <-ticker.C:
+				if err := s.syncETHTxs(ctx); err != nil {
+					log.Printf("eth tx tracker: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *WalletService) syncETHTxs(ctx context.Context) error {
+	txs, err := s.TransactionRepo.ListTracking(ctx, "eth", s.EthChain.Confirmations)
+	if err != nil {
+		return err
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+	tip, err := s.EthChain.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err := s.trackETHTx(ctx, tx, tip); err != nil {
+			log.Printf("eth tx tracker %s: %v", tx.Hash, err)
+		}
+	}
+	return nil
+}
+
+// trackETHTx 更新一笔交易的状态：
+// 有回执时按回执记为 confirmed / reverted；已上链的交易回执消失说明被 reorg，退回 pending；
+// 没有回执、节点也不认识，且 nonce 已被用掉或超过 drop_after 时标记为 dropped (被自己的替换交易顶掉时为 replaced)
+func (s *WalletService) trackETHTx(ctx context.Context, tx *entity.Transaction, tip uint64) error {
+	receipt, err := s.EthChain.Receipt(ctx, tx.Hash)
+	if err != nil {
+		return err
+	}
+	if receipt != nil {
+		applyReceipt(tx, receipt, tip)
+		return s.TransactionRepo.UpdateReceipt(ctx, tx)
+	}
+
+	if tx.Status != entity.TxPending {
+		clearReceipt(tx)
+		return s.TransactionRepo.UpdateReceipt(ctx, tx)
+	}
+
+	known, err := s.EthChain.TransactionKnown(ctx, tx.Hash)
+	if err != nil || known {
+		return err
+	}
+	latest, _, err := s.EthChain.Nonces(ctx, tx.From)
+	if err != nil {
+		return err
+	}
+	expired := s.EthChain.DropAfter > 0 && time.Since(tx.CreatedAt) > s.EthChain.DropAfter
+	if latest > tx.Nonce || expired {
+		tx.Status = entity.TxDropped
+		if tx.ReplacedByHash != "" && latest > tx.Nonce {
+			tx.Status = entity.TxReplaced
+		}
+		return s.TransactionRepo.UpdateReceipt(ctx, tx)
+	}
+	return nil
+}
+
+// newETHTxRecord 已广播交易的记录，费用参数按交易类型填写
+func newETHTxRecord(wallet *entity.Wallet, from string, tx *types.Transaction) *entity.Transaction {
+	raw, _ := tx.MarshalBinary()
+	now := time.Now()
+	record := &entity.Transaction{
+		UserID:      wallet.UserID,
+		WalletID:    wallet.ID,
+		Chain:       "eth",
+		From:        from,
+		Value:       tx.Value().String(),
+		Nonce:       tx.Nonce(),
+		Hash:        tx.Hash().Hex(),
+		RawTx:       hex.EncodeToString(raw),
+		TxType:      tx.Type(),
+		GasLimit:    tx.Gas(),
+		Status:      entity.TxPending,
+		BroadcastAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if tx.To() != nil {
+		record.To = tx.To().Hex()
+	} else {
+		record.ContractAddress = crypto.CreateAddress(common.HexToAddress(from), tx.Nonce()).Hex()
+	}
+	if len(tx.Data()) > 0 {
+		record.Data = hexutil.Encode(tx.Data())
+	}
+	if to, amount, ok := chain.DecodeERC20Transfer(tx.Data()); ok {
+		record.TokenTo = to
+		record.TokenAmount = amount.String()
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		record.MaxFeePerGas = tx.GasFeeCap().String()
+		record.MaxPriorityFeePerGas = tx.GasTipCap().String()
+	} else {
+		record.GasPrice = tx.GasPrice().String()
+	}
+	return record
+}
+
+// applyReceipt 写入回执：区块、gas 用量、实际费用和确认数
+func applyReceipt(tx *entity.Transaction, receipt *types.Receipt, tip uint64) {
+	tx.Status = entity.TxConfirmed
+	if receipt.Status == types.ReceiptStatusFailed {
+		tx.Status = entity.TxReverted
+	}
+	block := receipt.BlockNumber.Uint64()
+	tx.BlockNumber = block
+	tx.BlockHash = receipt.BlockHash.Hex()
+	tx.GasUsed = receipt.GasUsed
+	if receipt.EffectiveGasPrice != nil {
+		tx.EffectiveGasPrice = receipt.EffectiveGasPrice.String()
+		fee := new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+		tx.Fee = fee.String()
+	}
+	tx.Confirmations = 0
+	if tip >= block {
+		tx.Confirmations = tip - block + 1
+	}
+	if tx.ConfirmedAt == nil {
+		now := time.Now()
+		tx.ConfirmedAt = &now
+	}
+}
+
+// clearReceipt 回执所在区块被 reorg 掉，交易回到 pending 等重新打包
+func clearReceipt(tx *entity.Transaction) {
+	tx.Status = entity.TxPending
+	tx.BlockNumber = 0
+	tx.BlockHash = ""
+	tx.GasUsed = 0
+	tx.EffectiveGasPrice = ""
+	tx.Fee = ""
+	tx.Confirmations = 0
+	tx.ConfirmedAt = nil
+}
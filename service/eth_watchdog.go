@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+)
+
+// defaultMaxBumps 费用策略没填 max_bumps 时最多预签的加价次数
+const defaultMaxBumps = 3
+
+// StartETHWatchdog 后台检查还没上链的交易：从节点 mempool 消失的原样重新广播，
+// 超过 eth.stuck_after 还没上链的按钱包费用策略广播下一级预签的加价交易，每一步都记一条事件
+func (s *WalletService) StartETHWatchdog(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("eth watchdog stopped")
+				return
+
+			case <-ticker.C:
+				if err := s.watchETHTxs(ctx); err != nil {
+					log.Printf("eth watchdog: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// ETHTxEvents 交易所在 nonce 上的 watchdog 事件 (包括原交易和替换交易)
+func (s *WalletService) ETHTxEvents(ctx context.Context, userID, hash string) ([]*entity.TxEvent, error) {
+	tx, err := s.GetTransaction(ctx, userID, hash)
+	if err != nil {
+		return nil, err
+	}
+	return s.TxEventRepo.ListByNonce(ctx, tx.Chain, tx.From, tx.Nonce)
+}
+
+// SetETHFeePolicy 设置钱包的自动加价策略。预签的加价交易在发送时生成，已发出的交易不受影响，
+// 但 watchdog 广播前会按新的上限检查，关掉 auto_bump 后不会再加价
+func (s *WalletService) SetETHFeePolicy(ctx context.Context, userID, walletID string, req *request.ETHFeePolicyReq) (*entity.FeePolicy, error) {
+	wallet, err := s.WalletRepo.GetByID(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil || wallet.UserID != userID {
+		return nil, errors.New("wallet not found")
+	}
+
+	policy := &entity.FeePolicy{
+		AutoBump:     req.AutoBump,
+		MaxFeePerGas: req.MaxFeePerGas,
+		BumpPercent:  req.BumpPercent,
+		MaxBumps:     req.MaxBumps,
+	}
+	if policy.AutoBump || policy.MaxFeePerGas != "" {
+		ceiling, ok := new(big.Int).SetString(policy.MaxFeePerGas, 10)
+		if !ok || ceiling.Sign() <= 0 {
+			return nil, errors.New("max_fee_per_gas must be a positive wei amount")
+		}
+	}
+	if policy.MaxBumps < 0 {
+		return nil, errors.New("max_bumps must not be negative")
+	}
+	if err := s.WalletRepo.UpdateFeePolicy(ctx, walletID, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// GetETHFeePolicy 钱包当前的自动加价策略，没有设置时返回 nil
+func (s *WalletService) GetETHFeePolicy(ctx context.Context, userID, walletID string) (*entity.FeePolicy, error) {
+	wallet, err := s.WalletRepo.GetByID(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil || wallet.UserID != userID {
+		return nil, errors.New("wallet not found")
+	}
+	return wallet.FeePolicy, nil
+}
+
+func (s *WalletService) watchETHTxs(ctx context.Context) error {
+	txs, err := s.TransactionRepo.ListWatching(ctx, "eth")
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err := s.watchETHTx(ctx, tx); err != nil {
+			log.Printf("eth watchdog %s: %v", tx.Hash, err)
+		}
+	}
+	return nil
+}
+
+// watchETHTx 已经有回执或 nonce 已被用掉的交易交给 tracker 处理，这里只管还在等打包的
+func (s *WalletService) watchETHTx(ctx context.Context, tx *entity.Transaction) error {
+	receipt, err := s.EthChain.Receipt(ctx, tx.Hash)
+	if err != nil || receipt != nil {
+		return err
+	}
+
+	known, err := s.EthChain.TransactionKnown(ctx, tx.Hash)
+	if err != nil {
+		return err
+	}
+	if !known {
+		latest, _, err := s.EthChain.Nonces(ctx, tx.From)
+		if err != nil {
+			return err
+		}
+		if latest > tx.Nonce {
+			return nil
+		}
+		if err := s.rebroadcastETHTx(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if s.EthChain.StuckAfter <= 0 || time.Since(tx.BroadcastAt) < s.EthChain.StuckAfter {
+		return nil
+	}
+	return s.escalateETHTx(ctx, tx)
+}
+
+// rebroadcastETHTx 原样广播已签名的交易；不更新 broadcast_at，否则反复被挤出 mempool 的交易永远不会加价
+func (s *WalletService) rebroadcastETHTx(ctx context.Context, tx *entity.Transaction) error {
+	raw, err := hex.DecodeString(tx.RawTx)
+	if err != nil {
+		return err
+	}
+	if _, err := s.EthChain.BroadcastRawETH(ctx, raw); err != nil {
+		s.emitTxEvent(ctx, tx, entity.TxEventFailed, "rebroadcast: "+err.Error())
+		return err
+	}
+	s.emitTxEvent(ctx, tx, entity.TxEventRebroadcast, "")
+	return nil
+}
+
+// escalateETHTx 广播下一级预签的加价交易。钱包关闭了自动加价、下一级超过当前的费用上限、
+// 或者阶梯已经用完时只报告一次卡住，等人工 speed-up / cancel。不属于任何钱包的交易 (外部签名后导入广播的) 没有加价策略
+func (s *WalletService) escalateETHTx(ctx context.Context, tx *entity.Transaction) error {
+	if tx.WalletID == "" {
+		return s.reportStuck(ctx, tx, entity.TxEventStuck, fmt.Sprintf("not mined after %s, tx has no wallet to bump from", time.Since(tx.BroadcastAt).Round(time.Second)))
+	}
+	wallet, err := s.WalletRepo.GetByID(ctx, tx.WalletID)
+	if err != nil {
+		return err
+	}
+	if wallet == nil {
+		return errors.New("wallet not found: " + tx.WalletID)
+	}
+
+	policy := wallet.FeePolicy
+	if policy == nil || !policy.AutoBump {
+		return s.reportStuck(ctx, tx, entity.TxEventStuck, fmt.Sprintf("not mined after %s, auto bump disabled", time.Since(tx.BroadcastAt).Round(time.Second)))
+	}
+	if len(tx.Escalations) == 0 {
+		return s.reportStuck(ctx, tx, entity.TxEventCeilingReached, "no pre-signed fee bumps left")
+	}
+	ceiling, ok := new(big.Int).SetString(policy.MaxFeePerGas, 10)
+	if !ok {
+		return fmt.Errorf("invalid max_fee_per_gas in fee policy of wallet %s", wallet.ID)
+	}
+	next := tx.Escalations[0]
+	fee := next.MaxFeePerGas
+	if fee == "" {
+		fee = next.GasPrice
+	}
+	if n, ok := new(big.Int).SetString(fee, 10); !ok || n.Cmp(ceiling) > 0 {
+		return s.reportStuck(ctx, tx, entity.TxEventCeilingReached, "next fee bump "+fee+" wei exceeds ceiling "+policy.MaxFeePerGas)
+	}
+
+	raw, err := hex.DecodeString(next.RawTx)
+	if err != nil {
+		return err
+	}
+
+	// 和手动 speed-up 一样持有 nonce 锁，拿到锁后重新确认这笔交易还没被替换
+	return s.withNonceLock(ctx, tx.From, func(acct *entity.NonceAccount, owner string) error {
+		current, err := s.TransactionRepo.GetByHash(ctx, tx.Chain, tx.Hash)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.Status != entity.TxPending || current.ReplacedByHash != "" {
+			return nil
+		}
+
+		signed, err := s.EthChain.BroadcastRawETH(ctx, raw)
+		if err != nil {
+			s.emitTxEvent(ctx, tx, entity.TxEventFailed, "fee bump: "+err.Error())
+			return err
+		}
+		replacement := newETHTxRecord(wallet, tx.From, signed)
+		replacement.ReplacesHash = tx.Hash
+		replacement.Cancel = tx.Cancel
+		replacement.Escalations = tx.Escalations[1:]
+
+		acct.Pending = appendNonceTx(acct.Pending, signed.Nonce(), replacement.Hash)
+		if err := s.NonceRepo.Save(ctx, acct, owner); err != nil {
+			return err
+		}
+		if err := s.TransactionRepo.SetReplacedBy(ctx, tx.Chain, tx.Hash, replacement.Hash); err != nil {
+			return err
+		}
+		if err := s.TransactionRepo.Create(ctx, replacement); err != nil {
+			return err
+		}
+		s.emitTxEvent(ctx, replacement, entity.TxEventFeeBumped, "replaces "+tx.Hash+", fee "+fee+" wei")
+		return nil
+	})
+}
+
+// reportStuck 同一笔交易只报告一次
+func (s *WalletService) reportStuck(ctx context.Context, tx *entity.Transaction, typ, detail string) error {
+	if tx.StuckReported {
+		return nil
+	}
+	tx.StuckReported = true
+	if err := s.TransactionRepo.UpdateWatchdog(ctx, tx); err != nil {
+		return err
+	}
+	s.emitTxEvent(ctx, tx, typ, detail)
+	return nil
+}
+
+// emitTxEvent 记录事件并打日志，写库失败不影响 watchdog 继续处理
+func (s *WalletService) emitTxEvent(ctx context.Context, tx *entity.Transaction, typ, detail string) {
+	log.Printf("eth watchdog %s (nonce %d): %s %s", tx.Hash, tx.Nonce, typ, detail)
+	ev := &entity.TxEvent{
+		UserID:    tx.UserID,
+		Chain:     tx.Chain,
+		From:      tx.From,
+		Nonce:     tx.Nonce,
+		TxHash:    tx.Hash,
+		Type:      typ,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	}
+	if err := s.TxEventRepo.Create(ctx, ev); err != nil {
+		log.Printf("eth watchdog %s: save event: %v", tx.Hash, err)
+	}
+}
+
+// escalationLadder 钱包开启自动加价时，发送后马上按费用策略预签加价阶梯；失败只打日志，不影响已发出的交易
+func (s *WalletService) escalationLadder(wallet *entity.Wallet, priv *ecdsa.PrivateKey, tx *types.Transaction) []entity.TxEscalation {
+	policy := wallet.FeePolicy
+	if policy == nil || !policy.AutoBump {
+		return nil
+	}
+	ceiling, ok := new(big.Int).SetString(policy.MaxFeePerGas, 10)
+	if !ok {
+		log.Printf("eth watchdog: invalid max_fee_per_gas in fee policy of wallet %s", wallet.ID)
+		return nil
+	}
+	steps := policy.MaxBumps
+	if steps <= 0 {
+		steps = defaultMaxBumps
+	}
+	ladder, err := s.EthChain.EscalateETHTx(priv, tx, ceiling, steps, policy.BumpPercent)
+	if err != nil {
+		log.Printf("eth watchdog %s: pre-sign fee bumps: %v", tx.Hash().Hex(), err)
+		return nil
+	}
+
+	out := make([]entity.TxEscalation, 0, len(ladder))
+	for _, rung := range ladder {
+		raw, _ := rung.MarshalBinary()
+		esc := entity.TxEscalation{Hash: rung.Hash().Hex(), RawTx: hex.EncodeToString(raw)}
+		if rung.Type() == types.DynamicFeeTxType {
+			esc.MaxFeePerGas = rung.GasFeeCap().String()
+			esc.MaxPriorityFeePerGas = rung.GasTipCap().String()
+		} else {
+			esc.GasPrice = rung.GasPrice().String()
+		}
+		out = append(out, esc)
+	}
+	return out
+}
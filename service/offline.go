@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/linlinbupt123-crypto/wallet_service/chain"
+	"github.com/linlinbupt123-crypto/wallet_service/entity"
+	"github.com/linlinbupt123-crypto/wallet_service/offline"
+	"github.com/linlinbupt123-crypto/wallet_service/request"
+	"github.com/linlinbupt123-crypto/wallet_service/utils"
+)
+
+// OfflineImportResult 导入签名结果后的状态：eth 为广播后的交易，btc 为合并签名后的 PSBT，签名够了会直接广播
+type OfflineImportResult struct {
+	Type        string               `json:"type"`
+	Transaction *entity.Transaction  `json:"transaction,omitempty"`
+	PSBT        *entity.BTCPSBT      `json:"psbt,omitempty"`
+	Status      *chain.BTCPSBTStatus `json:"status,omitempty"`
+}
+
+// ExportOfflineWallet 导出钱包密文给离线端，先用 passphrase 解一次，防止导出之后离线端才发现解不开。
+// HD 钱包同时保存 BTC 账户 xpub，之后构造 BTC 离线签名请求不再需要 passphrase
+func (s *WalletService) ExportOfflineWallet(ctx context.Context, userID, walletID, passphrase string) (*offline.WalletExport, error) {
+	wallet, err := s.WalletRepo.GetByID(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil || wallet.UserID != userID {
+		return nil, errors.New("wallet not found")
+	}
+	if _, err := s.HDWalletDomain.ETHPrivateKey(wallet, utils.ETH_DERIVATION_PATH_PREFIX+"0", passphrase); err != nil {
+		return nil, err
+	}
+	if wallet.WalletType == utils.HdWalletType {
+		if err := s.saveBTCAccountXPubs(ctx, wallet, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	return offline.NewWalletExport(wallet)
+}
+
+// saveBTCAccountXPubs 派生账户 0 在各地址类型下的扩展公钥并保存
+func (s *WalletService) saveBTCAccountXPubs(ctx context.Context, wallet *entity.Wallet, passphrase string) error {
+	seed, err := s.HDWalletDomain.DecryptSeed(wallet, passphrase)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for i := range seed {
+			seed[i] = 0
+		}
+	}()
+	xpubs := make(map[string]string, len(btcExportTypes))
+	for _, t := range btcExportTypes {
+		path := fmt.Sprintf("m/%d'/%d'/0'", t.Purpose(), s.BtcChain.CoinType())
+		if xpubs[path], err = s.BtcChain.AccountXPub(seed, path); err != nil {
+			return err
+		}
+	}
+	if err := s.WalletRepo.SetBTCAccountXPubs(ctx, wallet.ID, xpubs); err != nil {
+		return err
+	}
+	wallet.BTCAccountXPubs = xpubs
+	return nil
+}
+
+// CreateOfflineETHRequest 构造未签名的 ETH 交易。nonce 在锁内从 nonce 管理器分配，
+// 签名结果导入之前在 nonce status 里显示为空洞，放弃签名时走 cancel 回收
+func (s *WalletService) CreateOfflineETHRequest(ctx context.Context, userID string, req *request.OfflineETHReq) (*offline.Payload, error) {
+	from, err := utils.NormalizeETHAddress(req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := utils.NormalizeETHAddress(req.To)
+	if err != nil {
+		return nil, err
+	}
+	wallet, addr, err := s.userETHAddress(ctx, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.WalletType != utils.HdWalletType && wallet.WalletType != utils.ImportedWalletType {
+		return nil, fmt.Errorf("%s wallets can not sign offline", wallet.WalletType)
+	}
+	params, err := s.ethSendParams(ctx, to, &request.SendTxReq{
+		Chain:    "eth",
+		From:     from,
+		To:       to,
+		Amount:   req.Amount,
+		Data:     req.Data,
+		GasLimit: req.GasLimit,
+		Asset:    req.Asset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &offline.Payload{
+		Version:   offline.PayloadVersion,
+		Type:      offline.ETHSignRequest,
+		WalletID:  wallet.ID,
+		Chain:     "eth",
+		From:      from,
+		CreatedAt: time.Now(),
+	}
+	if wallet.WalletType == utils.HdWalletType {
+		payload.Path = ethAddressPath(addr)
+	}
+	err = s.withNonceLock(ctx, from, func(acct *entity.NonceAccount, owner string) error {
+		nonce := acct.Next
+		params.Nonce = &nonce
+		tx, chainID, err := s.EthChain.PrepareETH(ctx, from, params)
+		if err != nil {
+			return err
+		}
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		payload.ID = fmt.Sprintf("%s:%d", from, nonce)
+		payload.ChainID = chainID.String()
+		payload.Tx = hexutil.Encode(raw)
+
+		acct.Next = nonce + 1
+		acct.Pending = appendNonceTx(acct.Pending, nonce, "")
+		return s.NonceRepo.Save(ctx, acct, owner)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// CreateOfflineBTCRequest 构造未签名的 PSBT，和 btc/psbt 一样保存记录、冻结 UTXO，id 为 PSBT 记录 id。
+// 输入和找零的公钥从导出离线钱包时保存的账户 xpub 派生，master fingerprint 取自钱包的 XPub，不解密 seed
+func (s *WalletService) CreateOfflineBTCRequest(ctx context.Context, userID string, req *request.OfflineBTCReq) (*offline.Payload, error) {
+	wallet, err := s.WalletRepo.GetByID(ctx, req.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil || wallet.UserID != userID || wallet.WalletType != utils.HdWalletType {
+		return nil, errors.New("hd wallet not found")
+	}
+	if len(wallet.BTCAccountXPubs) == 0 {
+		return nil, errors.New("export the wallet for offline signing first")
+	}
+	fp, err := chain.XPubFingerprint(wallet.XPub)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := chain.ParseFingerprint(fp)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.createBTCPSBT(ctx, wallet, fingerprint, accountXPubKeys(s.BtcChain, wallet), btcSpendParams{
+		coin:       s.BtcChain,
+		to:         req.To,
+		amount:     req.Amount,
+		feeRate:    req.FeeRate,
+		confTarget: req.ConfTarget,
+		strategy:   req.CoinSelection,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &offline.Payload{
+		Version:   offline.PayloadVersion,
+		Type:      offline.BTCSignRequest,
+		ID:        record.ID,
+		WalletID:  record.WalletID,
+		Chain:     "btc",
+		PSBT:      record.PSBT,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// ImportOfflinePayload 导入离线端的签名结果 (JSON 或 UR)：eth 直接广播，btc 合并签名，签名够了就 finalize 广播
+func (s *WalletService) ImportOfflinePayload(ctx context.Context, userID, text string) (*OfflineImportResult, error) {
+	p, err := offline.ParsePayload(text)
+	if err != nil {
+		return nil, err
+	}
+	result := &OfflineImportResult{Type: p.Type}
+	switch p.Type {
+	case offline.ETHSigned:
+		if result.Transaction, err = s.BroadcastETHTx(ctx, userID, p.Tx); err != nil {
+			return nil, err
+		}
+		return result, nil
+
+	case offline.BTCSigned:
+		if result.PSBT, result.Status, err = s.ImportBTCPSBT(ctx, userID, p.ID, []byte(p.PSBT)); err != nil {
+			return nil, err
+		}
+		if !result.Status.Complete {
+			return result, nil
+		}
+		record, err := s.FinalizeBTCPSBT(ctx, userID, p.ID)
+		if record != nil {
+			result.PSBT = record
+		}
+		return result, err
+
+	default:
+		return nil, fmt.Errorf("%s is not a signed payload", p.Type)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -22,34 +21,94 @@ import (
 )
 
 type WalletService struct {
-	HDWalletDomain *domain.HDWallet
-	WalletRepo     *repository.Wallet
-	AddressRepo    *repository.AddressRepo
-	EthChain       *chain.ETHChain
-	UseMainNet     bool
+	HDWalletDomain  *domain.HDWallet
+	WalletRepo      *repository.Wallet
+	AddressRepo     *repository.AddressRepo
+	UTXORepo        *repository.UTXORepo
+	BTCTxRepo       *repository.BTCTxRepo
+	BTCPSBTRepo     *repository.BTCPSBTRepo
+	NonceRepo       *repository.NonceRepo
+	TransactionRepo *repository.TransactionRepo
+	TxEventRepo     *repository.TxEventRepo
+	TokenRepo       *repository.TokenRepo
+	NFTRepo         *repository.NFTRepo
+	EthChain        *chain.ETHChain
+	BtcChain        *chain.BTCChain
+	UTXOChains      map[chain.ChainType]*chain.BTCChain // btc / ltc / doge，btc 与 BtcChain 是同一个
+	UseMainNet      bool
+	IPFSGateway     string // 读取 ipfs:// NFT 元数据的网关
 }
 
 func NewWalletService(
 	hdSvc *domain.HDWallet,
 	walletRepo *repository.Wallet,
 	addressRepo *repository.AddressRepo,
+	utxoRepo *repository.UTXORepo,
+	btcTxRepo *repository.BTCTxRepo,
+	btcPSBTRepo *repository.BTCPSBTRepo,
+	nonceRepo *repository.NonceRepo,
+	transactionRepo *repository.TransactionRepo,
+	txEventRepo *repository.TxEventRepo,
+	tokenRepo *repository.TokenRepo,
+	nftRepo *repository.NFTRepo,
 	EthConfig config.EthConfig,
-) *WalletService {
-	return &WalletService{
-		HDWalletDomain: hdSvc,
-		WalletRepo:     walletRepo,
-		AddressRepo:    addressRepo,
-		EthChain:       chain.NewETHChain(EthConfig),
+	BtcConfig config.BtcConfig,
+	LtcConfig config.BtcConfig,
+	DogeConfig config.BtcConfig,
+) (*WalletService, error) {
+	btcChain, err := chain.NewUTXOChain(chain.BTC, BtcConfig)
+	if err != nil {
+		return nil, err
+	}
+	ltcChain, err := chain.NewUTXOChain(chain.LTC, LtcConfig)
+	if err != nil {
+		return nil, err
+	}
+	dogeChain, err := chain.NewUTXOChain(chain.DOGE, DogeConfig)
+	if err != nil {
+		return nil, err
+	}
+	ipfsGateway := EthConfig.IPFSGateway
+	if ipfsGateway == "" {
+		ipfsGateway = "https://ipfs.io/ipfs/"
 	}
+	return &WalletService{
+		HDWalletDomain:  hdSvc,
+		WalletRepo:      walletRepo,
+		AddressRepo:     addressRepo,
+		UTXORepo:        utxoRepo,
+		BTCTxRepo:       btcTxRepo,
+		BTCPSBTRepo:     btcPSBTRepo,
+		NonceRepo:       nonceRepo,
+		TransactionRepo: transactionRepo,
+		TxEventRepo:     txEventRepo,
+		TokenRepo:       tokenRepo,
+		NFTRepo:         nftRepo,
+		EthChain:        chain.NewETHChain(EthConfig),
+		BtcChain:        btcChain,
+		UTXOChains: map[chain.ChainType]*chain.BTCChain{
+			chain.BTC:  btcChain,
+			chain.LTC:  ltcChain,
+			chain.DOGE: dogeChain,
+		},
+		IPFSGateway: ipfsGateway,
+	}, nil
 }
 
 // CreateWalletAndAddresses 创建 HD 钱包 + 主地址
-func (s *WalletService) CreateWalletAndAddresses(ctx context.Context, userID, passphrase string) (*entity.Wallet, map[string]string, error) {
+// btcAddressType 为空时使用配置里的默认 BTC 地址类型
+func (s *WalletService) CreateWalletAndAddresses(ctx context.Context, userID, passphrase, btcAddressType string) (*entity.Wallet, map[string]string, error) {
+	addrType, err := chain.ParseBTCAddressType(btcAddressType, s.BtcChain.AddressType)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// 创建 HD 钱包对象
 	wallet, err := s.HDWalletDomain.CreateWallet(ctx, userID, passphrase)
 	if err != nil {
 		return nil, nil, err
 	}
+	wallet.BTCAddressType = string(addrType)
 
 	// 存入数据库
 	walletID, err := s.WalletRepo.Create(ctx, wallet)
@@ -81,11 +140,31 @@ func (s *WalletService) CreateWalletAndAddresses(ctx context.Context, userID, pa
 		return nil, nil, err
 	}
 
+	// BTC
+	btcPath := generatePath(addrType.Purpose(), s.BtcChain.CoinType(), 0, 0, 0)
+	btcAddr, err := s.BtcChain.DeriveTypedAddress(seed, btcPath, addrType)
+	if err != nil {
+		return nil, nil, walletErr.WrapWithCode(walletErr.DeriveErr, "DeriveTypedAddress", err)
+	}
+	addresses["btc"] = btcAddr
+	if err := s.AddressRepo.Create(ctx, &entity.Address{
+		UserID:      userID,
+		WalletID:    walletID,
+		Chain:       "btc",
+		Address:     btcAddr,
+		Index:       0,
+		AddressType: string(addrType),
+		CreatedAt:   time.Now(),
+	}); err != nil {
+		return nil, nil, err
+	}
+
 	return wallet, addresses, nil
 }
 
 // DeriveNewAddress 为用户在某条链派生下一个地址
-func (s *WalletService) DeriveNewAddress(ctx context.Context, userID, walletID, chainName, passphrase string) (string, error) {
+// addressType 只对 btc / ltc / doge 生效，为空时使用钱包在该链的默认地址类型
+func (s *WalletService) DeriveNewAddress(ctx context.Context, walletID, userID, passphrase, chainName, addressType string) (string, error) {
 	// 1. find wallet
 	wallet, err := s.WalletRepo.GetByID(ctx, walletID)
 	if err != nil {
@@ -101,42 +180,53 @@ func (s *WalletService) DeriveNewAddress(ctx context.Context, userID, walletID,
 		return "", err
 	}
 
-	// 3. 找该链目前最大的 index
-	maxIndex, err := s.AddressRepo.GetMaxIndex(ctx, wallet.ID, chainName)
+	// 3. 确定 UTXO 链的地址类型（BTC 钱包没有记录时按 legacy 处理）
+	coin := s.UTXOChains[chain.ChainType(chainName)]
+	var btcType chain.BTCAddressType
+	if coin != nil {
+		btcType, err = coin.ParseAddressType(addressType, walletAddressType(coin, wallet))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// 4. 找该链目前最大的 index
+	var maxIndex int
+	if coin != nil {
+		maxIndex, err = s.AddressRepo.GetMaxIndexByType(ctx, wallet.ID, chainName, string(btcType), 0)
+	} else {
+		maxIndex, err = s.AddressRepo.GetMaxIndex(ctx, wallet.ID, chainName)
+	}
 	if err != nil {
 		return "", err
 	}
 	nextIndex := maxIndex + 1 // 如果没有记录，GetMaxIndex 会返回 -1，则 nextIndex=0
 
-	// 4. 生成 BIP44 path
-	var path string
-	switch chainName {
-	case "btc":
-		path = generatePath(44, 0, 0, 0, nextIndex)
-	case "eth":
-		path = generatePath(44, 60, 0, 0, nextIndex)
-	default:
-		return "", errors.New("unsupported chain")
-	}
-
-	// 5. 派生地址
+	// 5. 生成 BIP44/49/84/86 path 并派生地址
 	var addr string
-	switch chainName {
-	case "eth":
+	switch {
+	case coin != nil:
+		path := generatePath(btcType.Purpose(), coin.CoinType(), 0, 0, nextIndex)
+		addr, err = coin.DeriveTypedAddress(seed, path, btcType)
+	case chainName == "eth":
+		path := generatePath(44, 60, 0, 0, nextIndex)
 		_, addr, err = s.HDWalletDomain.DeriveETHKeyPair(seed, path)
+	default:
+		return "", errors.New("unsupported chain")
 	}
 	if err != nil {
-		return "", err
+		return "", walletErr.WrapWithCode(walletErr.DeriveErr, "DeriveNewAddress", err)
 	}
 
 	// 6. 存数据库
 	err = s.AddressRepo.Create(ctx, &entity.Address{
-		UserID:    userID,
-		WalletID:  wallet.ID,
-		Chain:     chainName,
-		Address:   addr,
-		Index:     uint32(nextIndex),
-		CreatedAt: time.Now(),
+		UserID:      userID,
+		WalletID:    wallet.ID,
+		Chain:       chainName,
+		Address:     addr,
+		Index:       uint32(nextIndex),
+		AddressType: string(btcType),
+		CreatedAt:   time.Now(),
 	})
 	if err != nil {
 		return "", err
@@ -145,52 +235,90 @@ func (s *WalletService) DeriveNewAddress(ctx context.Context, userID, walletID,
 	return addr, nil
 }
 
+// walletBTCAddressType 钱包的默认 BTC 地址类型，老钱包没有该字段时按 legacy 处理
+func walletBTCAddressType(wallet *entity.Wallet) chain.BTCAddressType {
+	if wallet.BTCAddressType == "" {
+		return chain.P2PKH
+	}
+	return chain.BTCAddressType(wallet.BTCAddressType)
+}
+
+// walletAddressType 钱包在 coin 链上的默认地址类型：btc 跟随钱包设置，ltc / doge 用配置的默认值
+func walletAddressType(coin *chain.BTCChain, wallet *entity.Wallet) chain.BTCAddressType {
+	if coin.Name == chain.BTC {
+		return walletBTCAddressType(wallet)
+	}
+	return coin.AddressType
+}
+
 // BIP44 path helper: m / purpose' / coin_type' / account' / change / address_index
 func generatePath(purpose, coinType, account, change, index int) string {
 	// 简单 string 拼装，具体你也可以用专门 BIP32 库
 	return fmt.Sprintf("m/%d'/%d'/%d'/%d/%d", purpose, coinType, account, change, index)
 }
 
-// SendTransaction 发起交易（fromAddress 对应你管理的地址）
-func (s *WalletService) SendTransaction(ctx context.Context, req *request.SendTxReq) (string, error) {
-	fromAddr, err := utils.NormalizeETHAddress(req.From)
-	if err != nil {
-		return "", err
-	}
-	toAddr, err := utils.NormalizeETHAddress(req.To)
-	if err != nil {
-		return "", err
+// SendTransaction 发起交易（fromAddress 对应你管理的地址）。
+// eth 的 broadcast=false 只签名不广播，额外返回已签名交易的 RLP hex
+func (s *WalletService) SendTransaction(ctx context.Context, req *request.SendTxReq) (string, string, error) {
+	fromAddr, toAddr := req.From, req.To
+	if req.Chain == "eth" {
+		var err error
+		if fromAddr, err = utils.NormalizeETHAddress(req.From); err != nil {
+			return "", "", err
+		}
+		if toAddr, err = utils.NormalizeETHAddress(req.To); err != nil {
+			return "", "", err
+		}
 	}
 	// 1. address → walletID
-	addr, err := s.AddressRepo.GetByAddrID(ctx, fromAddr)
+	addr, err := s.AddressRepo.GetByAddrID(ctx, req.Chain, fromAddr)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	if addr == nil {
-		return "", fmt.Errorf("address: %s not found or not belongs to user", fromAddr)
-	}
-	if addr.Chain != req.Chain {
-		return "", errors.New("address not found or not belongs to user")
+		return "", "", fmt.Errorf("address: %s not found or not belongs to user", fromAddr)
 	}
 	index := int32(addr.Index)
 	if index < 0 {
-		return "", errors.New("address not found or not belongs to user")
+		return "", "", errors.New("address not found or not belongs to user")
 	}
 	wallet, err := s.WalletRepo.GetByID(ctx, addr.WalletID)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	if wallet == nil {
-		return "", errors.New("wallet not found")
+		return "", "", errors.New("wallet not found")
 	}
 	// 根据 index 重新 derive 出对应私钥/地址，让 chain 层去签名 & 广播
+	if req.Chain != "eth" && req.Asset != "" {
+		return "", "", errors.New("asset is only supported on eth")
+	}
+	if req.Chain != "eth" && signOnly(req) {
+		return "", "", errors.New("broadcast=false is only supported on eth")
+	}
+	if req.Nonce != nil && !signOnly(req) {
+		return "", "", errors.New("nonce is only supported with broadcast=false")
+	}
 	switch req.Chain {
-	case "btc":
-		return "", nil
+	case "btc", "ltc", "doge":
+		// UTXO 链从钱包在该链的所有地址选币，from 只用来确定钱包
+		hash, err := s.sendBTC(ctx, s.UTXOChains[chain.ChainType(req.Chain)], wallet, toAddr, req)
+		return hash, "", err
 	case "eth":
-		return s.sendTransactionByAddress(ctx, wallet, addr, toAddr, req.Amount, req.Passphrase)
+		hash, err := s.sendTransactionByAddress(ctx, wallet, addr, toAddr, req)
+		if err != nil || !signOnly(req) {
+			return hash, "", err
+		}
+		record, err := s.TransactionRepo.GetByHash(ctx, "eth", hash)
+		if err != nil {
+			return "", "", err
+		}
+		if record == nil {
+			return "", "", errors.New("signed transaction not recorded")
+		}
+		return hash, "0x" + record.RawTx, nil
 	default:
-		return "", errors.New("unsupported chain")
+		return "", "", errors.New("unsupported chain")
 	}
 }
 
@@ -199,57 +327,80 @@ func (s *WalletService) sendTransactionByAddress(
 	wallet *entity.Wallet,
 	addr *entity.Address,
 	toAddress string,
-	amount string,
-	passphrase string,
+	req *request.SendTxReq,
 ) (string, error) {
-	var privKey *ecdsa.PrivateKey
-	switch wallet.WalletType {
-	case "hd":
-		seed, err := s.HDWalletDomain.DecryptSeed(wallet, passphrase)
-		if err != nil {
-			return "", err
-		}
-		// HD 派生 index 就是 Address 表里的 Index
-		path := generatePath(44, 60, 0, 0, int(addr.Index))
-		privKey, _, err = s.HDWalletDomain.DeriveETHKeyPair(seed, path)
-		if err != nil {
-			return "", walletErr.WrapWithCode(walletErr.DeriveErr, "DeriveETHKeyPair", err)
-		}
-	case "imported":
-
-		key, err := utils.DeriveAESKey(passphrase, wallet.SaltHex)
-		if err != nil {
-			return "", err
-		}
-
-		privKeyBytes, err := utils.DecryptAES(wallet.CipherKey, key)
-		if err != nil {
-			return "", err
-		}
-
-		privKeyHex := strings.TrimPrefix(string(privKeyBytes), "0x")
-
-		privKey, err = crypto.HexToECDSA(privKeyHex)
-		if err != nil {
-			return "", err
-		}
-	default:
-		return "", errors.New("unsupported wallet type")
+	params, err := s.ethSendParams(ctx, toAddress, req)
+	if err != nil {
+		return "", err
 	}
-	amountWei, err := utils.ETHToWei(amount)
+	privKey, err := s.ethPrivateKey(wallet, addr, req.Passphrase)
 	if err != nil {
 		return "", err
 	}
+	params.SignOnly = signOnly(req)
+	params.Nonce = req.Nonce
+	return s.sendETH(ctx, wallet, privKey, params)
+}
+
+// ethSendParams 交易内容：原生 ETH 按 amount 和 data，token 转账编码成 transfer calldata
+func (s *WalletService) ethSendParams(ctx context.Context, toAddress string, req *request.SendTxReq) (chain.ETHSendParams, error) {
+	if !isNativeETH(req.Asset) {
+		return s.erc20SendParams(ctx, toAddress, req)
+	}
+	amountWei, err := utils.ETHToWei(req.Amount)
+	if err != nil {
+		return chain.ETHSendParams{}, err
+	}
+	var data []byte
+	if req.Data != "" {
+		if data, err = utils.DecodeHexData(req.Data); err != nil {
+			return chain.ETHSendParams{}, fmt.Errorf("invalid calldata: %w", err)
+		}
+	}
+	return chain.ETHSendParams{
+		To:       toAddress,
+		Value:    amountWei,
+		Data:     data,
+		GasLimit: req.GasLimit,
+	}, nil
+}
+
+// signOnly broadcast 不填时默认广播
+func signOnly(req *request.SendTxReq) bool {
+	return req.Broadcast != nil && !*req.Broadcast
+}
 
-	return s.EthChain.SendETH(ctx, privKey, toAddress, amountWei)
+// ethPrivateKey 解密出地址对应的私钥：HD 钱包按 Address 表里的 index 派生，导入钱包直接解密
+func (s *WalletService) ethPrivateKey(wallet *entity.Wallet, addr *entity.Address, passphrase string) (*ecdsa.PrivateKey, error) {
+	// HD 派生 index 就是 Address 表里的 Index
+	return s.HDWalletDomain.ETHPrivateKey(wallet, ethAddressPath(addr), passphrase)
+}
+
+// ethAddressPath HD 钱包里 eth 地址的派生路径
+func ethAddressPath(addr *entity.Address) string {
+	return generatePath(44, 60, 0, 0, int(addr.Index))
 }
 
 func (s *WalletService) GetBalance(
 	ctx context.Context,
 	userID string,
-	chain string,
+	chainName string,
+	asset string,
+	refresh bool,
 ) (string, error) {
 
+	// UTXO 链余额是用户在该链所有地址上未花费输出之和，refresh 时先从后端同步
+	if chainName != "eth" && asset != "" {
+		return "", errors.New("asset is only supported on eth")
+	}
+	if coin := s.UTXOChains[chain.ChainType(chainName)]; coin != nil {
+		sat, err := s.btcBalance(ctx, coin, userID, refresh)
+		if err != nil {
+			return "", err
+		}
+		return utils.SatoshiToBTC(sat), nil
+	}
+
 	// 1. 找用户地址（这里简单：取 index = 0 的主地址）
 	addrs, err := s.AddressRepo.GetByUserID(ctx, userID)
 	if err != nil {
@@ -258,7 +409,7 @@ func (s *WalletService) GetBalance(
 
 	var address string
 	for _, a := range addrs {
-		if a.Chain == chain && a.Index == 0 {
+		if a.Chain == chainName && a.Index == 0 {
 			address = a.Address
 			break
 		}
@@ -268,8 +419,11 @@ func (s *WalletService) GetBalance(
 	}
 
 	// 2. 查链上余额
-	switch chain {
+	switch chainName {
 	case "eth":
+		if !isNativeETH(asset) {
+			return s.erc20Balance(ctx, address, asset)
+		}
 		balanceWei, err := s.EthChain.GetBalance(ctx, address)
 		if err != nil {
 			return "", err
@@ -24,4 +24,6 @@ const (
 const (
 	HdWalletType       = "hd"
 	ImportedWalletType = "imported"
+	MultisigWalletType = "multisig"
+	TimelockWalletType = "timelock"
 )
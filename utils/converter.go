@@ -6,9 +6,14 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"math/big"
+	"strconv"
+	"strings"
 
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"golang.org/x/crypto/scrypt"
 )
 
@@ -29,6 +34,64 @@ func ETHToWei(eth string) (*big.Int, error) {
 	return wei, nil
 }
 
+// ParseUnits 十进制金额字符串按 decimals 换成最小单位 (ERC-20 token 金额)，
+// 不经过浮点，小数位超过 decimals 时报错而不是截断
+func ParseUnits(amount string, decimals uint8) (*big.Int, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(amount), ".")
+	if whole == "" && frac == "" {
+		return nil, errors.New("invalid amount")
+	}
+	if len(frac) > int(decimals) {
+		return nil, fmt.Errorf("amount %s has more than %d decimals", amount, decimals)
+	}
+	digits := whole + frac + strings.Repeat("0", int(decimals)-len(frac))
+	if strings.ContainsAny(digits, "+-") {
+		return nil, errors.New("invalid amount")
+	}
+	v, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, errors.New("invalid amount")
+	}
+	return v, nil
+}
+
+// FormatUnits 最小单位按 decimals 转成十进制字符串，保留全部小数位
+func FormatUnits(v *big.Int, decimals uint8) string {
+	if decimals == 0 {
+		return v.String()
+	}
+	s := new(big.Int).Abs(v).String()
+	if len(s) <= int(decimals) {
+		s = strings.Repeat("0", int(decimals)-len(s)+1) + s
+	}
+	point := len(s) - int(decimals)
+	out := s[:point] + "." + s[point:]
+	if v.Sign() < 0 {
+		out = "-" + out
+	}
+	return out
+}
+
+// SatoshiToBTC satoshi -> BTC 字符串，8 位小数
+func SatoshiToBTC(sat int64) string {
+	return strconv.FormatFloat(btcutil.Amount(sat).ToBTC(), 'f', 8, 64)
+}
+
+// BTCToSatoshi BTC 字符串 -> satoshi，按 8 位小数精确换算，不经过浮点
+func BTCToSatoshi(btc string) (int64, error) {
+	v, err := ParseUnits(btc, 8)
+	if err != nil {
+		return 0, err
+	}
+	if !v.IsInt64() {
+		return 0, errors.New("amount out of range")
+	}
+	if v.Sign() <= 0 {
+		return 0, errors.New("amount must be positive")
+	}
+	return v.Int64(), nil
+}
+
 // DeriveAESKey 通过 passphrase + salt 派生 AES key
 func DeriveAESKey(passphrase, saltHex string) ([]byte, error) {
 	salt, err := hex.DecodeString(saltHex)
@@ -102,3 +165,11 @@ func NormalizeETHAddress(addr string) (string, error) {
 	}
 	return common.HexToAddress(addr).Hex(), nil
 }
+
+// DecodeHexData 解码 calldata 等 hex 数据，0x 前缀可有可无
+func DecodeHexData(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		s = "0x" + s
+	}
+	return hexutil.Decode(s)
+}